@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"fmt"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/spf13/cobra"
+)
+
+func NewBottomCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bottom",
+		Short: "Check out the first branch of the current stack",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateNavigation(context)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runBottom(cmd, context)
+		},
+	}
+
+	return cmd
+}
+
+func runBottom(cmd *cobra.Command, context *Context) error {
+	branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	if err != nil {
+		return err
+	}
+	current, _ := currentTrackedBranch(context, branchMap)
+	start := current
+
+	// Descend the stack until the parent is the root of the tree.
+	bottom := branchMap.FindBranch(current)
+	for parent := branchMap.FindParent(current); !isBelowStack(parent); parent = branchMap.FindParent(current) {
+		bottom = parent
+		current = gitutil.BranchName(parent)
+	}
+
+	if gitutil.BranchName(bottom) == start {
+		fmt.Fprintln(cmd.OutOrStdout(), "Already at the bottom of the stack.")
+		return nil
+	}
+	return checkoutTrackedBranch(cmd, context, bottom)
+}
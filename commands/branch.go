@@ -1,21 +1,34 @@
 package commands
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/acamadeo/git-tree/common"
 	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/operations"
 	"github.com/acamadeo/git-tree/store"
 	git "github.com/libgit2/git2go/v34"
 	"github.com/spf13/cobra"
 )
 
+type branchOptions struct {
+	insert bool
+	split  bool
+}
+
 func NewBranchCommand() *cobra.Command {
+	var opts branchOptions
+
 	cmd := &cobra.Command{
 		Use:   "branch",
 		Short: "Add a new branch at current commit",
-		Args:  cobra.ExactArgs(1),
+		Long: "Add a new branch at current commit. If HEAD is detached in the middle " +
+			"of a tracked branch, the branch can be split in two at HEAD instead.",
+		Args: cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			context, err := CreateContext()
 			if err != nil {
@@ -30,34 +43,77 @@ func NewBranchCommand() *cobra.Command {
 				return err
 			}
 
-			return runBranch(context, args)
+			operation := "branch " + args[0]
+			if opts.insert {
+				operation = "branch --insert " + args[0]
+			}
+
+			branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+			if err != nil {
+				return err
+			}
+			if !common.OnTipCommit(context.Repo, branchMap) {
+				toSplit := common.ContainingBranch(context.Repo, branchMap, headCommit(context.Repo).Id())
+				if !opts.split && !confirmSplit(cmd, toSplit, args[0]) {
+					return fmt.Errorf("HEAD is in the middle of branch %q. Run `git-tree branch --split %s` to split it.", toSplit, args[0])
+				}
+
+				if err := operations.BeginOplogEntry(context.Repo, "branch --split "+args[0]); err != nil {
+					return err
+				}
+				err = runBranchSplit(context, args, toSplit)
+				operations.FinishOplogEntry(context.Repo, err)
+				return err
+			}
+
+			if err := operations.BeginOplogEntry(context.Repo, operation); err != nil {
+				return err
+			}
+			err = runBranch(context, args, &opts)
+			operations.FinishOplogEntry(context.Repo, err)
+			return err
 		},
 	}
 
+	flags := cmd.Flags()
+
+	flags.BoolVarP(&opts.insert, "insert", "i", false, "Insert the new branch between the current branch and its children")
+	flags.BoolVarP(&opts.split, "split", "s", false, "Split the branch containing HEAD at HEAD without asking")
+
 	return cmd
 }
 
 // Add a new branch pointing to the current commit and checkout that branch.
-func runBranch(context *Context, args []string) error {
+//
+// If `opts.insert` is set, the children of the current branch become children
+// of the new branch.
+func runBranch(context *Context, args []string, opts *branchOptions) error {
 	// Create the new branch.
+	branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	if err != nil {
+		return err
+	}
+
 	newBranchName := args[0]
 	newBranch, err := context.Repo.CreateBranch(newBranchName, headCommit(context.Repo), false)
 	if err != nil {
 		return fmt.Errorf("Could not create branch: %s.", err.Error())
 	}
 
-	branchMap := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
-
 	// Add the new branch as a child of the head branch in the branch map.
-	headRef, _ := context.Repo.Head()
-	headName := gitutil.BranchName(headRef.Branch())
-
-	headBranch := branchMap.FindBranch(headName)
-	branchMap.Children[headBranch] = append(branchMap.Children[headBranch], newBranch)
+	headBranch := branchMap.FindBranch(headTrackedBranch(context.Repo, branchMap))
+	if opts.insert {
+		branchMap.Children[newBranch] = branchMap.Children[headBranch]
+		branchMap.Children[headBranch] = models.BranchList{newBranch}
+	} else {
+		branchMap.Children[headBranch] = append(branchMap.Children[headBranch], newBranch)
+	}
 
 	// Rewrite the branch map file to disk.
 	branchFile := store.BranchMapPath(context.Repo.Path())
-	store.WriteBranchMap(branchMap, branchFile)
+	if err := store.WriteBranchMap(branchMap, branchFile); err != nil {
+		return fmt.Errorf("Could not write branch map: %s.", err.Error())
+	}
 
 	// Checkout the new branch.
 	if err := context.Repo.SetHead("refs/heads/" + newBranchName); err != nil {
@@ -67,6 +123,75 @@ func runBranch(context *Context, args []string) error {
 	return nil
 }
 
+// Add a new branch pointing to the current commit, in the middle of tracked
+// branch `toSplit`, and checkout that branch.
+//
+// The new branch gets the commits of `toSplit` up to HEAD, so it is inserted
+// between `toSplit` and its parent.
+func runBranchSplit(context *Context, args []string, toSplit string) error {
+	newBranchName := args[0]
+	if err := splitBranch(context.Repo, toSplit, newBranchName, headCommit(context.Repo)); err != nil {
+		return err
+	}
+
+	if err := context.Repo.SetHead("refs/heads/" + newBranchName); err != nil {
+		return fmt.Errorf("Could not checkout new branch: %s.", err.Error())
+	}
+	return nil
+}
+
+// Split tracked branch `toSplit` at `commit`, one of its own commits: branch
+// `newBranchName` is created at `commit` and inserted between `toSplit` and its
+// parent.
+func splitBranch(repo *git.Repository, toSplit string, newBranchName string, commit *git.Commit) error {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return err
+	}
+
+	newBranch, err := repo.CreateBranch(newBranchName, commit, false)
+	if err != nil {
+		return fmt.Errorf("Could not create branch: %s.", err.Error())
+	}
+	branchMap.InsertParent(toSplit, newBranch)
+
+	branchFile := store.BranchMapPath(repo.Path())
+	if err := store.WriteBranchMap(branchMap, branchFile); err != nil {
+		return fmt.Errorf("Could not write branch map: %s.", err.Error())
+	}
+	return nil
+}
+
+// Ask whether to split branch `toSplit` at HEAD, creating branch `newName`.
+func confirmSplit(cmd *cobra.Command, toSplit string, newName string) bool {
+	fmt.Fprintf(cmd.OutOrStdout(), "HEAD is in the middle of branch %q. Split it at HEAD into %q? [y/N] ", toSplit, newName)
+
+	line, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+
+// Returns the name of the tracked branch at HEAD. If HEAD is detached at a
+// commit several tracked branches point to, the branch furthest from the root
+// is returned.
+func headTrackedBranch(repo *git.Repository, branchMap *models.BranchMap) string {
+	if headBranch := gitutil.HeadBranch(repo); headBranch != nil {
+		return gitutil.BranchName(headBranch)
+	}
+
+	headRef, _ := repo.Head()
+	found := ""
+	for _, name := range branchMap.ListBranchNames() {
+		branch, err := repo.LookupBranch(name, git.BranchLocal)
+		if err == nil && branch.Target().Equal(headRef.Target()) {
+			if found == "" || branchMap.IsBranchAncestor(found, name) {
+				found = name
+			}
+		}
+	}
+	return found
+}
+
 func headCommit(repo *git.Repository) *git.Commit {
 	headRef, _ := repo.Head()
 	return gitutil.CommitByReference(repo, headRef)
@@ -81,9 +206,13 @@ func validateBranchArgs(context *Context, args []string) error {
 		return fmt.Errorf("Branch %q already exists in the git repository.", args[0])
 	}
 
-	// Check if you are on a tip commit.
-	branchMap := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
-	if !common.OnTipCommit(context.Repo, branchMap) {
+	// Check if you are on a tip commit, or in the middle of a tracked branch
+	// that can be split.
+	branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	if err != nil {
+		return err
+	}
+	if !common.OnTipCommit(context.Repo, branchMap) && common.ContainingBranch(context.Repo, branchMap, headCommit(context.Repo).Id()) == "" {
 		headCommit, _ := context.Repo.Head()
 		return fmt.Errorf("HEAD commit %q is not pointed to by any tracked branches.", gitutil.ReferenceShortHash(headCommit))
 	}
@@ -132,15 +132,115 @@ func (suite *BranchTestSuite) TestBranch_UpdatesBranchMapFile() {
 
 	gotString := suite.repo.ReadFile(".git/tree/branches")
 	wantString :=
-		`git-tree-root
-git-tree-root master
-master treecko
-treecko grovyle`
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["treecko"]},
+    {"branch": "treecko", "children": ["grovyle"]}
+  ]
+}`
 
 	assert.Equal(suite.T(), gotString, wantString,
 		"Got branch map file: %v, but want file: %v", gotString, wantString)
 }
 
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+func (suite *BranchTestSuite) TestBranch_InsertMovesChildrenUnderNewBranch() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("treecko")
+	NewInitCommand().Execute()
+
+	cmd := NewBranchCommand()
+	cmd.SetArgs([]string{"--insert", "mudkip"})
+	cmd.Execute()
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["treecko"]},
+    {"branch": "treecko", "children": ["mudkip"]},
+    {"branch": "mudkip", "children": ["grovyle"]}
+  ]
+}`
+
+	assert.Equal(suite.T(), gotString, wantString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+
+	head, _ := suite.repo.Repo.Head()
+	newBranch := suite.repo.LookupBranch("mudkip")
+
+	assert.Zero(suite.T(), head.Cmp(newBranch.Reference),
+		"Expected HEAD to be at branch %q, but it is not", "mudkip")
+}
+
+// Branches:
+//
+//	master ─── mud ─── kip
+//	                    └── mudkip
+func (suite *BranchTestSuite) TestBranch_SplitsBranchAtDetachedHead() {
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("mud", "mud", "mud")
+	suite.repo.WriteAndCommitFile("kip", "kip", "kip")
+	NewInitCommand().Execute()
+	suite.repo.SwitchCommit("mud")
+
+	cmd := NewBranchCommand()
+	cmd.SetArgs([]string{"--split", "mud"})
+	gotError := cmd.Execute()
+
+	assert.Nil(suite.T(), gotError)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("mud", "mudkip"),
+		"Expected branch %q to be an ancestor of %q, but it is not", "mud", "mudkip")
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["mud"]},
+    {"branch": "mud", "children": ["mudkip"]}
+  ]
+}`
+	assert.Equal(suite.T(), gotString, wantString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+
+	head, _ := suite.repo.Repo.Head()
+	assert.True(suite.T(), head.IsBranch())
+	assert.Equal(suite.T(), "refs/heads/mud", head.Name())
+}
+
+// Branches:
+//
+//	master ─── mud ─── kip
+//	                    └── mudkip
+func (suite *BranchTestSuite) TestBranch_DeclinedSplitLeavesBranchAlone() {
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("mud", "mud", "mud")
+	suite.repo.WriteAndCommitFile("kip", "kip", "kip")
+	NewInitCommand().Execute()
+	suite.repo.SwitchCommit("mud")
+
+	cmd := NewBranchCommand()
+	cmd.SetArgs([]string{"mud"})
+	cmd.SetIn(strings.NewReader("n\n"))
+	cmd.SetOut(&strings.Builder{})
+	gotError := cmd.Execute()
+
+	assert.ErrorContains(suite.T(), gotError, "HEAD is in the middle of branch \"mudkip\"")
+	assert.Nil(suite.T(), suite.repo.LookupBranch("mud"))
+}
+
 func containsAll(s string, substr ...string) bool {
 	for _, sub := range substr {
 		if !strings.Contains(s, sub) {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/acamadeo/git-tree/store"
 	git "github.com/libgit2/git2go/v34"
 )
 
@@ -18,6 +19,12 @@ func CreateContext() (*Context, error) {
 		return nil, fmt.Errorf("Current directory %q is not a git repository.", cwd)
 	}
 
+	// Upgrade the files under `.git/tree` written by older versions of
+	// git-tree, and refuse to touch files written by newer versions.
+	if err := store.MigrateFormat(repo.Path()); err != nil {
+		return nil, err
+	}
+
 	return &Context{
 		Repo: repo,
 	}, nil
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ContextTestSuite struct {
+	suite.Suite
+	repo    testutil.TestRepository
+	testDir string
+}
+
+func (suite *ContextTestSuite) SetupTest() {
+	suite.testDir, _ = os.Getwd()
+	suite.repo = testutil.CreateTestRepo()
+	os.Chdir(suite.repo.Repo.Workdir())
+
+	suite.repo.BranchWithCommit("treecko")
+	NewInitCommand().Execute()
+}
+
+func (suite *ContextTestSuite) TearDownTest() {
+	os.Chdir(suite.testDir)
+	suite.repo.Free()
+}
+
+func (suite *ContextTestSuite) TestCreateContext_MigratesLineBasedFiles() {
+	// Files written before the format was versioned.
+	suite.repo.WriteFile(".git/tree/branches", `git-tree-root
+git-tree-root master
+master treecko`)
+	suite.repo.WriteFile(".git/tree/obsmap", `action amend
+cf59c4bf9d3036b68242d6e9db30c0d7654326b6 3316a58b9dd84c7b1864a3eb4d398ca643ac23c7 post-rewrite.amend`)
+
+	_, err := CreateContext()
+
+	assert.Nil(suite.T(), err)
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString := `{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["treecko"]}
+  ]
+}`
+	assert.Equal(suite.T(), wantString, gotString)
+
+	gotString = suite.repo.ReadFile(".git/tree/obsmap")
+	wantString = `{
+  "version": 2,
+  "actions": [
+    {
+      "type": "amend",
+      "entries": [
+        {"commit": "cf59c4bf9d3036b68242d6e9db30c0d7654326b6", "obsoleter": "3316a58b9dd84c7b1864a3eb4d398ca643ac23c7", "hook": "post-rewrite.amend"}
+      ]
+    }
+  ]
+}`
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+func (suite *ContextTestSuite) TestCreateContext_RefusesNewerFormat() {
+	newerFile := `{"version": 3, "branches": {}}`
+	suite.repo.WriteFile(".git/tree/branches", newerFile)
+
+	_, err := CreateContext()
+
+	var newerFormat *store.NewerFormatError
+	assert.True(suite.T(), errors.As(err, &newerFormat))
+	assert.Equal(suite.T(), 3, newerFormat.Version)
+	assert.Equal(suite.T(), newerFile, suite.repo.ReadFile(".git/tree/branches"))
+}
+
+func (suite *ContextTestSuite) TestCreateContext_DoesNotLockCurrentFormat() {
+	// Another git-tree process holds the lock.
+	lock, err := store.AcquireLock(suite.repo.Repo.Path())
+	assert.Nil(suite.T(), err)
+	defer lock.Release()
+
+	_, err = CreateContext()
+
+	assert.Nil(suite.T(), err)
+}
+
+func TestContextTestSuite(t *testing.T) {
+	suite.Run(t, new(ContextTestSuite))
+}
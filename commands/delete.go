@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/acamadeo/git-tree/common"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
+	"github.com/spf13/cobra"
+)
+
+type deleteOptions struct {
+	keepCommits bool
+	toContinue  bool
+	toAbort     bool
+}
+
+func NewDeleteCommand() *cobra.Command {
+	var opts deleteOptions
+
+	cmd := &cobra.Command{
+		Use:   "delete <branch>",
+		Short: "Delete a branch and restack its children onto its parent",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateDeleteArgs(context, args, &opts)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runDelete(context, args, &opts)
+		},
+	}
+
+	flags := cmd.Flags()
+
+	flags.BoolVar(&opts.keepCommits, "keep-commits", false, "Keep the branch's commits in its children instead of dropping them")
+	flags.BoolVar(&opts.toContinue, "continue", false, "Continue an in-progress git-tree delete")
+	flags.BoolVar(&opts.toAbort, "abort", false, "Abort an in-progress git-tree delete")
+
+	return cmd
+}
+
+func validateDeleteArgs(context *Context, args []string, opts *deleteOptions) error {
+	if !common.GitTreeInited(context.Repo.Path()) {
+		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+	}
+
+	if opts.toAbort && opts.toContinue {
+		return errors.New("Command does not take both --continue and --abort.")
+	}
+	if opts.toAbort || opts.toContinue {
+		if len(args) > 0 || opts.keepCommits {
+			return errors.New("Command does not take a branch or --keep-commits.")
+		}
+		if !utils.FileExists(store.DeletingPath(context.Repo.Path())) {
+			return errors.New("There is no git-tree delete in progress.")
+		}
+		return nil
+	}
+
+	if len(args) != 1 {
+		return errors.New("Command should be followed by the branch to delete.")
+	}
+	return nil
+}
+
+// Deletes a branch, moving its children onto its parent.
+func runDelete(context *Context, args []string, opts *deleteOptions) error {
+	var result operations.RebaseTreeResult
+	if opts.toAbort {
+		result = operations.DeleteAbort(context.Repo)
+		operations.DiscardOplogEntry(context.Repo)
+	} else if opts.toContinue {
+		result = operations.DeleteContinue(context.Repo)
+		operations.FinishOplogEntry(context.Repo, result.Error)
+	} else {
+		operation := "delete " + args[0]
+		if opts.keepCommits {
+			operation = "delete --keep-commits " + args[0]
+		}
+
+		if err := operations.BeginOplogEntry(context.Repo, operation); err != nil {
+			return err
+		}
+		result = operations.Delete(context.Repo, args[0], opts.keepCommits)
+		operations.FinishOplogEntry(context.Repo, result.Error)
+	}
+
+	if result.Type == operations.RebaseTreeMergeConflict {
+		return errors.New("merge conflict encountered")
+	} else if result.Type == operations.RebaseTreeUnstagedChanges {
+		return errors.New("resolved files must be staged")
+	}
+	return result.Error
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"os"
+	"testing"
+
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DeleteTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+	// Directory the test is running in. In setUp(), we `cd` into `repo`'s
+	// working directory. In tearDown(), we return to `testDir`.
+	testDir string
+}
+
+func (suite *DeleteTestSuite) SetupTest() {
+	suite.testDir, _ = os.Getwd()
+	suite.repo = testutil.CreateTestRepo()
+	os.Chdir(suite.repo.Repo.Workdir())
+}
+
+func (suite *DeleteTestSuite) TearDownTest() {
+	os.Chdir(suite.testDir)
+	suite.repo.Free()
+}
+
+func (suite *DeleteTestSuite) TestDelete_ContinueAndAbortTogether() {
+	suite.repo.BranchWithCommit("treecko")
+	NewInitCommand().Execute()
+
+	cmd := NewDeleteCommand()
+	cmd.SetArgs([]string{"--continue", "--abort"})
+	gotError := cmd.Execute()
+
+	wantError := "Command does not take both --continue and --abort."
+	assert.EqualError(suite.T(), gotError, wantError)
+}
+
+func TestDeleteTestSuite(t *testing.T) {
+	suite.Run(t, new(DeleteTestSuite))
+}
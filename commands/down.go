@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/acamadeo/git-tree/store"
+	"github.com/spf13/cobra"
+)
+
+func NewDownCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "down",
+		Short: "Check out the parent of the current branch",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateNavigation(context)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runDown(cmd, context)
+		},
+	}
+
+	return cmd
+}
+
+func runDown(cmd *cobra.Command, context *Context) error {
+	branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	if err != nil {
+		return err
+	}
+	current, _ := currentTrackedBranch(context, branchMap)
+
+	parent := branchMap.FindParent(current)
+	if isBelowStack(parent) {
+		return errors.New("Already at the bottom of the stack.")
+	}
+	return checkoutTrackedBranch(cmd, context, parent)
+}
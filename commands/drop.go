@@ -34,5 +34,13 @@ func runDrop(context *Context) error {
 		return nil
 	}
 
-	return operations.Drop(context.Repo)
+	if err := operations.Drop(context.Repo); err != nil {
+		return err
+	}
+
+	if dir := operations.SharedHooksDir(context.Repo); dir != "" {
+		fmt.Printf("The git-tree hooks in %s were kept, since other repositories may use them. "+
+			"Run `git-tree hooks uninstall` to remove them.\n", dir)
+	}
+	return nil
 }
@@ -9,11 +9,21 @@ import (
 	"github.com/acamadeo/git-tree/models"
 	"github.com/acamadeo/git-tree/operations"
 	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
 	git "github.com/libgit2/git2go/v34"
 	"github.com/spf13/cobra"
 )
 
+type evolveOptions struct {
+	toContinue bool
+	toAbort    bool
+	dryRun     bool
+	inMemory   bool
+}
+
 func NewEvolveCommand() *cobra.Command {
+	var opts evolveOptions
+
 	cmd := &cobra.Command{
 		Use:   "evolve",
 		Short: "Reconcile troubled commits in your repository",
@@ -24,7 +34,7 @@ func NewEvolveCommand() *cobra.Command {
 				return err
 			}
 
-			return validateEvolve(context)
+			return validateEvolve(context, &opts)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			context, err := CreateContext()
@@ -32,37 +42,116 @@ func NewEvolveCommand() *cobra.Command {
 				return err
 			}
 
-			return runEvolve(context)
+			return runEvolve(cmd, context, &opts)
 		},
 	}
 
+	flags := cmd.Flags()
+
+	flags.BoolVar(&opts.toContinue, "continue", false, "Continue an in-progress git-tree evolve")
+	flags.BoolVar(&opts.toAbort, "abort", false, "Abort an in-progress git-tree evolve")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Print the steps evolve would perform without rewriting anything")
+	flags.BoolVar(&opts.inMemory, "in-memory", false, "Rebase commits in memory and only update the working tree at the end")
+
 	return cmd
 }
 
-func validateEvolve(context *Context) error {
+func validateEvolve(context *Context, opts *evolveOptions) error {
 	if !common.GitTreeInited(context.Repo.Path()) {
 		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
 	}
+
+	if opts.toAbort && opts.toContinue {
+		return errors.New("Command does not take both --continue and --abort.")
+	}
+	if opts.dryRun && (opts.toAbort || opts.toContinue) {
+		return errors.New("Command does not take --dry-run with --continue or --abort.")
+	}
+	if opts.inMemory && (opts.toAbort || opts.toContinue) {
+		return errors.New("Command does not take --in-memory with --continue or --abort.")
+	}
+
+	if opts.toAbort || opts.toContinue {
+		if !utils.FileExists(store.EvolvingPath(context.Repo.Path())) {
+			return errors.New("There is no git-tree evolve in progress.")
+		}
+	}
 	return nil
 }
 
-func runEvolve(context *Context) error {
-	obsmap := store.ReadObsolescenceMap(context.Repo, store.ObsoleteMapPath(context.Repo.Path()))
+func runEvolve(cmd *cobra.Command, context *Context, opts *evolveOptions) error {
+	if opts.dryRun {
+		plan, err := operations.EvolveDryRun(context.Repo)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), plan.String())
+		return nil
+	}
+
+	var result operations.EvolveResult
+	if opts.toAbort {
+		result = operations.EvolveAbort(context.Repo)
+		operations.DiscardOplogEntry(context.Repo)
+	} else if opts.toContinue {
+		result = operations.EvolveContinue(context.Repo)
+		operations.FinishOplogEntry(context.Repo, result.Error)
+	} else {
+		operation := "evolve"
+		if opts.inMemory {
+			operation += " --in-memory"
+		}
+		if err := operations.BeginOplogEntry(context.Repo, operation); err != nil {
+			return err
+		}
+
+		// Pick up rewrites that bypassed the git-hooks. They are recorded in
+		// the same operation log entry, so undoing the evolve forgets them too.
+		if _, err := operations.ObsoleteScan(context.Repo); err != nil {
+			operations.FinishOplogEntry(context.Repo, err)
+			return err
+		}
+		troubled, err := hasTroubledCommits(context)
+		if err != nil {
+			operations.FinishOplogEntry(context.Repo, err)
+			return err
+		}
+		if !troubled {
+			operations.FinishOplogEntry(context.Repo, nil)
+			fmt.Println("No troubled commits in repository.")
+			return nil
+		}
+
+		result = operations.EvolveWithOptions(context.Repo, operations.EvolveOptions{InMemory: opts.inMemory})
+		operations.FinishOplogEntry(context.Repo, result.Error)
+	}
+
+	if result.Type == operations.EvolveMergeConflict {
+		return errors.New("merge conflict encountered")
+	} else if result.Type == operations.EvolveUnstagedChanges {
+		return errors.New("resolved files must be staged")
+	}
+	return result.Error
+}
+
+// Returns true if any commit in the tracked branches has been obsoleted.
+func hasTroubledCommits(context *Context) (bool, error) {
+	obsmap, err := store.ReadObsolescenceMap(context.Repo, store.ObsoleteMapPath(context.Repo.Path()))
+	if err != nil {
+		return false, err
+	}
 
-	branchMap := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	if err != nil {
+		return false, err
+	}
 	branches := gitutil.LookupBranches(context.Repo, branchMap.ListBranchNames()...)
 	root := gitutil.MergeBaseOctopus_Branches(context.Repo, branches...)
 	commits := gitutil.LocalCommitsFromBranches_RootOid(context.Repo, root, branches...)
 
-	// If there are no obsolete commits in the repository, notify the user that
-	// running `git-tree evolve` is a no-op.
-	if !anyObsoleteCommits(obsmap, commits) {
-		fmt.Println("No troubled commits in repository.")
-		return nil
-	}
-
-	repoTree := gitutil.CreateRepoTree(context.Repo, root, branches...)
-	return operations.Evolve(repoTree)
+	// If there are no obsolete commits in the repository, running
+	// `git-tree evolve` is a no-op.
+	return anyObsoleteCommits(obsmap, commits), nil
 }
 
 // Returns true if any obsolete commits are found among the `localCommits`.
@@ -74,6 +163,9 @@ func anyObsoleteCommits(obsmap *models.ObsolescenceMap, localCommits []*git.Comm
 
 	for _, action := range obsmap.Actions {
 		for _, entry := range action.Entries {
+			if entry.Commit == nil {
+				continue
+			}
 			if _, ok := localCommitOids[*entry.Commit.Id()]; ok {
 				return true
 			}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/acamadeo/git-tree/common"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/spf13/cobra"
+)
+
+func NewGCCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Prune obsolescences that no longer affect any tracked commit",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if !common.GitTreeInited(context.Repo.Path()) {
+				return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if err := operations.BeginOplogEntry(context.Repo, "gc"); err != nil {
+				return err
+			}
+			result, err := operations.GC(context.Repo)
+			operations.FinishOplogEntry(context.Repo, err)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %d obsolescence actions (%d entries).\n",
+				result.RemovedActions, result.RemovedEntries)
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -16,6 +16,27 @@ var DropCmd = NewDropCommand()
 var BranchCmd = NewBranchCommand()
 var RebaseCmd = NewRebaseCommand()
 var EvolveCmd = NewEvolveCommand()
+var LogCmd = NewLogCommand()
+var SyncCmd = NewSyncCommand()
+var TrackCmd = NewTrackCommand()
+var UntrackCmd = NewUntrackCommand()
+var DeleteCmd = NewDeleteCommand()
+var RenameCmd = NewRenameCommand()
+var GCCmd = NewGCCommand()
+var ObslogCmd = NewObslogCommand()
+var InterdiffCmd = NewInterdiffCommand()
+var HooksCmd = NewHooksCommand()
+
+// Operation log commands.
+var UndoCmd = NewUndoCommand()
+var RedoCmd = NewRedoCommand()
+var OplogCmd = NewOplogCommand()
+
+// Tree navigation commands.
+var UpCmd = NewUpCommand()
+var DownCmd = NewDownCommand()
+var TopCmd = NewTopCommand()
+var BottomCmd = NewBottomCommand()
 
 // Triggered using git-hooks (https://www.git-scm.com/docs/githooks).
 var ObsoleteCmd = NewObsoleteCommand()
@@ -26,7 +47,10 @@ var hiddenCommands = map[string]*cobra.Command{
 
 func init() {
 	// Add all the commands.
-	RootCmd.AddCommand(InitCmd, DropCmd, BranchCmd, RebaseCmd, EvolveCmd)
+	RootCmd.AddCommand(InitCmd, DropCmd, BranchCmd, RebaseCmd, EvolveCmd, LogCmd, ObslogCmd, InterdiffCmd, SyncCmd)
+	RootCmd.AddCommand(TrackCmd, UntrackCmd, DeleteCmd, RenameCmd, GCCmd, HooksCmd)
+	RootCmd.AddCommand(UpCmd, DownCmd, TopCmd, BottomCmd)
+	RootCmd.AddCommand(UndoCmd, RedoCmd, OplogCmd)
 }
 
 // Returns the status code for the program.
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/acamadeo/git-tree/common"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/spf13/cobra"
+)
+
+func NewHooksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage the git-hooks that record obsolete commits",
+	}
+
+	cmd.AddCommand(newHooksInstallCommand(), newHooksUninstallCommand(), newHooksStatusCommand())
+	return cmd
+}
+
+func newHooksInstallCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the git-hooks into the hooks directory",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if !common.GitTreeInited(context.Repo.Path()) {
+				return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if err := operations.InstallHooks(context.Repo); err != nil {
+				return err
+			}
+			return runHooksStatus(cmd, context)
+		},
+	}
+
+	return cmd
+}
+
+func newHooksUninstallCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the git-hooks, restoring the original hooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return operations.UninstallHooks(context.Repo)
+		},
+	}
+
+	return cmd
+}
+
+func newHooksStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report missing or broken git-hooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runHooksStatus(cmd, context)
+		},
+	}
+
+	return cmd
+}
+
+var hookStateNames = map[operations.HookState]string{
+	operations.HookInstalled: "installed",
+	operations.HookMissing:   "missing",
+	operations.HookOutdated:  "outdated",
+	operations.HookBroken:    "broken",
+}
+
+// Prints the status of each hook. Fails if any hook is not installed, so the
+// status can be checked by scripts.
+func runHooksStatus(cmd *cobra.Command, context *Context) error {
+	dir, err := operations.HooksDir(context.Repo)
+	if err != nil {
+		return err
+	}
+	statuses, err := operations.HooksStatus(context.Repo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Hooks directory: %s\n", dir)
+	allInstalled := true
+	for _, status := range statuses {
+		line := fmt.Sprintf("  %-13s %s", status.Hook, hookStateNames[status.State])
+		if status.Problem != "" {
+			line += ": " + status.Problem
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), line)
+		allInstalled = allInstalled && status.State == operations.HookInstalled
+	}
+
+	if !allInstalled {
+		return errors.New("Some git-hooks are not installed. Run `git-tree hooks install` to install them.")
+	}
+	return nil
+}
@@ -5,14 +5,13 @@ import (
 	"fmt"
 
 	"github.com/acamadeo/git-tree/common"
+	gitutil "github.com/acamadeo/git-tree/git"
 	"github.com/acamadeo/git-tree/operations"
 	git "github.com/libgit2/git2go/v34"
 	"github.com/spf13/cobra"
 )
 
 // TODO: Handle special cases like:
-//   - Detached HEAD
-//   - HEAD is not at the tip of a git branch
 //   - Some of the branches split at a commit instead of a branch.
 //      * An invariant of git-tree is that branches only split at other
 //        branches.
@@ -53,7 +52,17 @@ func NewInitCommand() *cobra.Command {
 }
 
 func runInit(context *Context, opts *initOptions) error {
-	return operations.Init(context.Repo, branchesFromNames(context, opts.branches)...)
+	operation := "init"
+	for _, branch := range opts.branches {
+		operation += " -b " + branch
+	}
+
+	if err := operations.BeginOplogEntry(context.Repo, operation); err != nil {
+		return err
+	}
+	err := operations.Init(context.Repo, branchesFromNames(context, opts.branches)...)
+	operations.FinishOplogEntry(context.Repo, err)
+	return err
 }
 
 func validateInitArgs(context *Context, opts *initOptions) error {
@@ -76,14 +85,15 @@ func validateInitArgs(context *Context, opts *initOptions) error {
 	return nil
 }
 
+// Without arguments, every local branch is tracked, so HEAD may be detached or
+// in the middle of a branch.
 func validateInitArgless(context *Context) error {
-	head, err := context.Repo.Head()
-	if err != nil {
+	if _, err := context.Repo.Head(); err != nil {
 		return fmt.Errorf("Cannot find HEAD reference.")
 	}
 
-	if !head.IsBranch() {
-		return fmt.Errorf("HEAD is not a branch.")
+	if len(gitutil.AllLocalBranches(context.Repo)) == 0 {
+		return errors.New("There are no branches to track.")
 	}
 
 	return nil
@@ -52,6 +52,17 @@ func (suite *InitTestSuite) TestInit_BranchDoesNotExist_RepoBranchless() {
 		"Command got error %v, but want error %v", gotError, wantError)
 }
 
+func (suite *InitTestSuite) TestInit_DetachedHead() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.WriteAndCommitFile("grovyle", "grovyle", "grovyle")
+	suite.repo.SwitchCommit("treecko")
+
+	gotError := NewInitCommand().Execute()
+
+	assert.Nil(suite.T(), gotError)
+	assert.True(suite.T(), suite.repo.FileExists(".git/tree/branches"))
+}
+
 func TestInitTestSuite(t *testing.T) {
 	suite.Run(t, new(InitTestSuite))
 }
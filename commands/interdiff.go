@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/acamadeo/git-tree/common"
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/spf13/cobra"
+)
+
+func NewInterdiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "interdiff [<branch>]",
+		Short: "Show how each commit of a branch changed since its previous version",
+		Args:  cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if !common.GitTreeInited(context.Repo.Path()) {
+				return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runInterdiff(cmd, context, args)
+		},
+	}
+
+	return cmd
+}
+
+func runInterdiff(cmd *cobra.Command, context *Context, args []string) error {
+	branchName := ""
+	if len(args) > 0 {
+		branchName = args[0]
+	} else if headRef, err := context.Repo.Head(); err == nil && headRef.IsBranch() {
+		branchName = gitutil.BranchName(headRef.Branch())
+	} else {
+		return errors.New("HEAD is not on a branch. Specify the branch to compare.")
+	}
+
+	output, err := operations.Interdiff(context.Repo, branchName)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), output)
+	return nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/acamadeo/git-tree/common"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/spf13/cobra"
+)
+
+func NewLogCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "log",
+		Short: "Show the tree of tracked branches and their commits",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateLog(context)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runLog(cmd, context)
+		},
+	}
+
+	return cmd
+}
+
+func validateLog(context *Context) error {
+	if !common.GitTreeInited(context.Repo.Path()) {
+		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+	}
+	return nil
+}
+
+func runLog(cmd *cobra.Command, context *Context) error {
+	output, err := operations.Log(context.Repo)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), output)
+	return nil
+}
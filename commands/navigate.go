@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/acamadeo/git-tree/common"
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+	"github.com/spf13/cobra"
+)
+
+// Helpers shared by the tree navigation commands (`up`, `down`, `top` and
+// `bottom`).
+
+func validateNavigation(context *Context) error {
+	if !common.GitTreeInited(context.Repo.Path()) {
+		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+	}
+
+	if err := validateNoUncommittedChanges(context); err != nil {
+		return err
+	}
+
+	branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	if err != nil {
+		return err
+	}
+	if _, err := currentTrackedBranch(context, branchMap); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the name of the tracked branch that HEAD points to.
+func currentTrackedBranch(context *Context, branchMap *models.BranchMap) (string, error) {
+	headRef, err := context.Repo.Head()
+	if err != nil {
+		return "", errors.New("Cannot find HEAD reference.")
+	}
+	if !headRef.IsBranch() {
+		return "", errors.New("HEAD is not a branch.")
+	}
+
+	headName := gitutil.BranchName(headRef.Branch())
+	if headName == store.GitTreeRootBranch || branchMap.FindBranch(headName) == nil {
+		return "", fmt.Errorf("Branch %q is not tracked by git-tree.", headName)
+	}
+	return headName, nil
+}
+
+// Returns true if `branch` is the root of the tree (i.e., it sits below every
+// stack) or is missing.
+func isBelowStack(branch *git.Branch) bool {
+	return branch == nil || gitutil.BranchName(branch) == store.GitTreeRootBranch
+}
+
+// Pick one of `branches`. If there are several branches, `index` (1-based)
+// selects one of them. If `index` is 0, the user is prompted to pick one.
+func chooseBranch(cmd *cobra.Command, parentName string, branches models.BranchList, index int) (*git.Branch, error) {
+	if index > 0 {
+		if index > len(branches) {
+			return nil, fmt.Errorf("Branch %q has %d children, but index %d was requested.", parentName, len(branches), index)
+		}
+		return branches[index-1], nil
+	}
+
+	if len(branches) == 1 {
+		return branches[0], nil
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Branch %q has multiple children:\n", parentName)
+	for i, branch := range branches {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, gitutil.BranchName(branch))
+	}
+	fmt.Fprint(out, "Pick a branch: ")
+
+	line, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(branches) {
+		return nil, fmt.Errorf("Invalid choice %q.", strings.TrimSpace(line))
+	}
+	return branches[choice-1], nil
+}
+
+// Returns an error if the working tree has uncommitted changes, or if its
+// status cannot be read.
+func validateNoUncommittedChanges(context *Context) error {
+	dirty, err := gitutil.HasUncommittedChanges(context.Repo)
+	if err != nil {
+		return fmt.Errorf("Could not read the status of the working tree: %s.", err.Error())
+	}
+	if dirty {
+		return errors.New("Cannot switch branches with uncommitted changes. Commit or stash them first.")
+	}
+	return nil
+}
+
+// Check out `branch`, refusing to overwrite any local changes.
+func checkoutTrackedBranch(cmd *cobra.Command, context *Context, branch *git.Branch) error {
+	if err := validateNoUncommittedChanges(context); err != nil {
+		return err
+	}
+
+	name := gitutil.BranchName(branch)
+	if err := gitutil.CheckoutBranchByName(context.Repo, name); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Switched to branch %q\n", name)
+	return nil
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type NavigateTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+	// Directory the test is running in. In setUp(), we `cd` into `repo`'s
+	// working directory. In tearDown(), we return to `testDir`.
+	testDir string
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle ─┬─ mudkip
+//	                                └─ sceptile
+func (suite *NavigateTestSuite) SetupTest() {
+	repo := testutil.CreateTestRepo()
+	os.Chdir(repo.Repo.Workdir())
+
+	repo.BranchWithCommit("treecko")
+	repo.BranchWithCommit("grovyle")
+	repo.BranchWithCommit("sceptile")
+	repo.SwitchBranch("grovyle")
+	repo.BranchWithCommit("mudkip")
+	executeWithArgs(NewInitCommand())
+
+	suite.repo = repo
+}
+
+func (suite *NavigateTestSuite) TearDownTest() {
+	os.Chdir(suite.testDir)
+	suite.repo.Free()
+}
+
+func (suite *NavigateTestSuite) assertHeadAt(branchName string) {
+	headName := gitutil.BranchName(gitutil.HeadBranch(suite.repo.Repo))
+	assert.Equal(suite.T(), branchName, headName,
+		"Expected HEAD to be at branch %q, but it is at %q", branchName, headName)
+}
+
+func (suite *NavigateTestSuite) TestUp_SingleChild() {
+	suite.repo.SwitchBranch("treecko")
+
+	executeWithArgs(NewUpCommand())
+
+	suite.assertHeadAt("grovyle")
+}
+
+func (suite *NavigateTestSuite) TestUp_MultipleChildren_Index() {
+	suite.repo.SwitchBranch("grovyle")
+
+	cmd := NewUpCommand()
+	executeWithArgs(cmd, "2")
+
+	suite.assertHeadAt("sceptile")
+}
+
+func (suite *NavigateTestSuite) TestUp_MultipleChildren_Prompt() {
+	suite.repo.SwitchBranch("grovyle")
+
+	cmd := NewUpCommand()
+	cmd.SetIn(strings.NewReader("1\n"))
+	cmd.SetOut(new(strings.Builder))
+	executeWithArgs(cmd)
+
+	suite.assertHeadAt("mudkip")
+}
+
+func (suite *NavigateTestSuite) TestUp_NoChildren() {
+	suite.repo.SwitchBranch("sceptile")
+
+	gotError := executeWithArgs(NewUpCommand())
+
+	assert.EqualError(suite.T(), gotError, "Branch \"sceptile\" has no children.")
+}
+
+func (suite *NavigateTestSuite) TestDown_MovesToParent() {
+	suite.repo.SwitchBranch("mudkip")
+
+	executeWithArgs(NewDownCommand())
+
+	suite.assertHeadAt("grovyle")
+}
+
+func (suite *NavigateTestSuite) TestDown_AlreadyAtBottom() {
+	suite.repo.SwitchBranch("master")
+
+	gotError := executeWithArgs(NewDownCommand())
+
+	assert.EqualError(suite.T(), gotError, "Already at the bottom of the stack.")
+}
+
+func (suite *NavigateTestSuite) TestDown_RefusesToDiscardChanges() {
+	suite.repo.SwitchBranch("mudkip")
+	suite.repo.WriteFile("mudkip", "swampert")
+
+	gotError := executeWithArgs(NewDownCommand())
+
+	assert.EqualError(suite.T(), gotError, "Cannot switch branches with uncommitted changes. Commit or stash them first.")
+	suite.assertHeadAt("mudkip")
+}
+
+func (suite *NavigateTestSuite) TestTop_FollowsChosenChild() {
+	suite.repo.SwitchBranch("treecko")
+
+	cmd := NewTopCommand()
+	cmd.SetIn(strings.NewReader("2\n"))
+	cmd.SetOut(new(strings.Builder))
+	executeWithArgs(cmd)
+
+	suite.assertHeadAt("sceptile")
+}
+
+func (suite *NavigateTestSuite) TestBottom_MovesToFirstBranchAboveRoot() {
+	suite.repo.SwitchBranch("sceptile")
+
+	executeWithArgs(NewBottomCommand())
+
+	suite.assertHeadAt("master")
+}
+
+// Execute `cmd` with exactly the given arguments (rather than the arguments of
+// the test binary).
+func executeWithArgs(cmd *cobra.Command, args ...string) error {
+	cmd.SetArgs(append([]string{}, args...))
+	return cmd.Execute()
+}
+
+func TestNavigateTestSuite(t *testing.T) {
+	suite.Run(t, new(NavigateTestSuite))
+}
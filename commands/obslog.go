@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/acamadeo/git-tree/common"
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/spf13/cobra"
+)
+
+type obslogOptions struct {
+	patch bool
+}
+
+func NewObslogCommand() *cobra.Command {
+	var opts obslogOptions
+
+	cmd := &cobra.Command{
+		Use:   "obslog [<commit|branch>]",
+		Short: "Show how a commit was rewritten over time",
+		Args:  cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if !common.GitTreeInited(context.Repo.Path()) {
+				return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			revision := "HEAD"
+			if len(args) > 0 {
+				revision = args[0]
+			}
+			return runObslog(cmd, context, revision, &opts)
+		},
+	}
+
+	flags := cmd.Flags()
+
+	flags.BoolVarP(&opts.patch, "patch", "p", false, "Show the diff between successive versions")
+
+	return cmd
+}
+
+func runObslog(cmd *cobra.Command, context *Context, revision string, opts *obslogOptions) error {
+	commit, err := gitutil.CommitByRevision(context.Repo, revision)
+	if err != nil {
+		return fmt.Errorf("Could not find commit %q.", revision)
+	}
+
+	output, err := operations.RenderObslog(context.Repo, commit, opts.patch)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), output)
+	return nil
+}
@@ -1,9 +1,11 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/acamadeo/git-tree/common"
 	"github.com/acamadeo/git-tree/operations"
 	"github.com/spf13/cobra"
 )
@@ -22,7 +24,7 @@ func NewObsoleteCommand() *cobra.Command {
 				return err
 			}
 
-			return runObsolete(context, args)
+			return runObsolete(cmd, context, args)
 		},
 	}
 
@@ -40,7 +42,7 @@ func validateObsoleteArgs(args []string) error {
 		}
 	case "pre-rebase":
 	case "pre-commit":
-	case "post-commit":
+	case "post-commit", "scan":
 		if len(args) != 2 {
 			return fmt.Errorf("accepts 2 arg(s), received %d", len(args))
 		}
@@ -48,7 +50,7 @@ func validateObsoleteArgs(args []string) error {
 	return nil
 }
 
-func runObsolete(context *Context, args []string) error {
+func runObsolete(cmd *cobra.Command, context *Context, args []string) error {
 	command := args[1]
 
 	switch command {
@@ -62,7 +64,24 @@ func runObsolete(context *Context, args []string) error {
 		return operations.ObsoletePreCommit(context.Repo)
 	case "post-commit":
 		return operations.ObsoletePostCommit(context.Repo)
+	case "scan":
+		return runObsoleteScan(cmd, context)
 	default:
 		return fmt.Errorf("Obsolescence not supported for operation %q.", command)
 	}
 }
+
+// Records the rewrites the git-hooks missed, found in the reflogs of the
+// tracked branches.
+func runObsoleteScan(cmd *cobra.Command, context *Context) error {
+	if !common.GitTreeInited(context.Repo.Path()) {
+		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+	}
+
+	added, err := operations.ObsoleteScan(context.Repo)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Recorded %d obsolete commits from the reflogs.\n", added)
+	return nil
+}
@@ -71,6 +71,30 @@ func (suite *ObsoleteTestSuite) TestObsolete_PostCommit_ValidArgs() {
 		"Command got error %v, but want error %v", gotError, wantError)
 }
 
+func (suite *ObsoleteTestSuite) TestObsolete_PostCommit_InvalidArgs() {
+	suite.repo.BranchWithCommit("treecko")
+
+	cmd := NewObsoleteCommand()
+	cmd.SetArgs([]string{"obsolete", "post-commit", "extra"})
+	gotError := cmd.Execute()
+
+	wantError := "accepts 2 arg(s), received 3"
+	assert.EqualError(suite.T(), gotError, wantError)
+}
+
+func (suite *ObsoleteTestSuite) TestObsolete_Scan_ValidArgs() {
+	suite.repo.BranchWithCommit("treecko")
+	NewInitCommand().Execute()
+
+	cmd := NewObsoleteCommand()
+	cmd.SetArgs([]string{"obsolete", "scan"})
+	gotError := cmd.Execute()
+
+	var wantError error = nil
+	assert.Equal(suite.T(), gotError, wantError,
+		"Command got error %v, but want error %v", gotError, wantError)
+}
+
 func TestObsoleteTestSuite(t *testing.T) {
 	suite.Run(t, new(ObsoleteTestSuite))
 }
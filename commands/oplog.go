@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/spf13/cobra"
+)
+
+func NewOplogCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "oplog",
+		Short: "List the git-tree operations that can be undone",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			runOplog(cmd, context)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// Lists the entries of the operation log, newest first. The last applied
+// entry is marked with `@` and entries that were undone are labelled.
+func runOplog(cmd *cobra.Command, context *Context) {
+	entries, position := operations.ReadOplog(context.Repo)
+	if len(entries) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No operations recorded.")
+		return
+	}
+
+	for index := len(entries); index > 0; index-- {
+		marker := " "
+		if index == position {
+			marker = "@"
+		}
+
+		line := fmt.Sprintf("%s %d %s", marker, index, entries[index-1].Operation)
+		if index > position {
+			line += " (undone)"
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), line)
+	}
+}
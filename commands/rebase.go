@@ -5,21 +5,31 @@ import (
 	"fmt"
 
 	"github.com/acamadeo/git-tree/common"
+	gitutil "github.com/acamadeo/git-tree/git"
 	"github.com/acamadeo/git-tree/operations"
+	"github.com/acamadeo/git-tree/store"
 	git "github.com/libgit2/git2go/v34"
 	"github.com/spf13/cobra"
 )
 
 type rebaseArgs struct {
 	source *git.Branch
-	dest   *git.Branch
+	// Set if the source was given as a commit in the middle of `source`: the
+	// commit below which `source` is split before being rebased.
+	splitAt *git.Commit
+	dest    *git.Branch
+	// Set if the destination was given as a revision other than a branch.
+	destCommit *git.Commit
 }
 
 type rebaseOptions struct {
-	sourceName string
-	destName   string
-	toContinue bool
-	toAbort    bool
+	sourceName   string
+	destName     string
+	toContinue   bool
+	toAbort      bool
+	rebaseMerges bool
+	dryRun       bool
+	inMemory     bool
 }
 
 func NewRebaseCommand() *cobra.Command {
@@ -28,6 +38,10 @@ func NewRebaseCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "rebase",
 		Short: "Rebase one branch onto another branch",
+		Long: "Rebase one branch and its descendants onto another branch, or onto any " +
+			"revision. The source can also be a commit in the middle of a tracked branch: " +
+			"the branch is then split below that commit, and only the commits from it " +
+			"onwards are rebased.",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			context, err := CreateContext()
 			if err != nil {
@@ -42,16 +56,19 @@ func NewRebaseCommand() *cobra.Command {
 				return err
 			}
 
-			return runRebase(context, &opts)
+			return runRebase(cmd, context, &opts)
 		},
 	}
 
 	flags := cmd.Flags()
 
-	flags.StringVarP(&opts.sourceName, "source", "s", "", "Source branch to rebase")
-	flags.StringVarP(&opts.destName, "dest", "d", "", "Branch to rebase onto")
+	flags.StringVarP(&opts.sourceName, "source", "s", "", "Source branch, or commit within a tracked branch, to rebase")
+	flags.StringVarP(&opts.destName, "dest", "d", "", "Branch or revision to rebase onto")
 	flags.BoolVar(&opts.toContinue, "continue", false, "Continue an in-progress git-tree rebase")
 	flags.BoolVar(&opts.toAbort, "abort", false, "Abort an in-progress git-tree rebase")
+	flags.BoolVar(&opts.rebaseMerges, "rebase-merges", false, "Recreate merge commits instead of flattening them")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Print the branches rebase would move and the conflicts it would hit without rewriting anything")
+	flags.BoolVar(&opts.inMemory, "in-memory", false, "Rebase branches in memory and only update the working tree at the end")
 
 	return cmd
 }
@@ -70,9 +87,21 @@ func validateRebaseArgs(context *Context, opts *rebaseOptions) error {
 }
 
 func validateAbortOrContinue(opts *rebaseOptions) error {
+	if opts.toAbort && opts.toContinue {
+		return errors.New("Command does not take both --continue and --abort.")
+	}
 	if opts.sourceName != "" || opts.destName != "" {
 		return errors.New("Command does not take --source or --dest arguments.")
 	}
+	if opts.rebaseMerges {
+		return errors.New("Command does not take --rebase-merges with --continue or --abort.")
+	}
+	if opts.dryRun {
+		return errors.New("Command does not take --dry-run with --continue or --abort.")
+	}
+	if opts.inMemory {
+		return errors.New("Command does not take --in-memory with --continue or --abort.")
+	}
 	return nil
 }
 
@@ -81,27 +110,81 @@ func validateRegularRebase(repo *git.Repository, opts *rebaseOptions) error {
 		return errors.New("Command should be followed by valid `-s <source-branch> -d <dest-branch>`.")
 	}
 
-	rebaseArgs := parseRebaseArgs(repo, opts)
-	if rebaseArgs.source == nil {
-		return fmt.Errorf("Could not find source branch %q.", opts.sourceName)
+	rebaseArgs, err := parseRebaseArgs(repo, opts)
+	if err != nil {
+		return err
 	}
-	if rebaseArgs.dest == nil {
-		return fmt.Errorf("Could not find dest branch %q.", opts.destName)
+	if rebaseArgs.splitAt != nil && opts.dryRun {
+		return errors.New("Command does not take --dry-run with --source given as a commit inside a branch.")
 	}
-	return nil
+	if opts.dryRun {
+		return nil
+	}
+	return validateRebaseTree(repo, rebaseArgs, opts)
 }
 
-// Rebases a branch and all its descendants onto another branch.
-func runRebase(context *Context, opts *rebaseOptions) error {
-	rebaseArgs := parseRebaseArgs(context.Repo, opts)
+// Returns an error if the rebase would be refused. This runs before the source
+// branch is split, so that a refused rebase leaves the tree untouched.
+func validateRebaseTree(repo *git.Repository, rebaseArgs rebaseArgs, opts *rebaseOptions) error {
+	treeOpts := rebaseTreeOptions(opts.rebaseMerges)
+	treeOpts.InMemory = opts.inMemory
 
+	if rebaseArgs.destCommit != nil {
+		return operations.ValidateRebaseTreeOntoCommit(repo, rebaseArgs.source, rebaseArgs.destCommit, treeOpts)
+	}
+	if rebaseArgs.splitAt != nil {
+		// Splitting the source gives it a new parent, so the destination may be
+		// its current parent. Only check the commit the destination points to.
+		destCommit := gitutil.CommitByOid(repo, *rebaseArgs.dest.Target())
+		return operations.ValidateRebaseTreeOntoCommit(repo, rebaseArgs.source, destCommit, treeOpts)
+	}
+	return operations.ValidateRebaseTree(repo, rebaseArgs.source, rebaseArgs.dest, treeOpts)
+}
+
+// Rebases a branch and all its descendants onto another branch.
+func runRebase(cmd *cobra.Command, context *Context, opts *rebaseOptions) error {
 	var result operations.RebaseTreeResult
 	if opts.toAbort {
 		result = operations.RebaseTreeAbort(context.Repo)
+		operations.DiscardOplogEntry(context.Repo)
 	} else if opts.toContinue {
 		result = operations.RebaseTreeContinue(context.Repo)
+		operations.FinishOplogEntry(context.Repo, result.Error)
 	} else {
-		result = operations.RebaseTree(context.Repo, rebaseArgs.source, rebaseArgs.dest)
+		rebaseArgs, err := parseRebaseArgs(context.Repo, opts)
+		if err != nil {
+			return err
+		}
+		if opts.dryRun {
+			return runRebaseDryRun(cmd, context, opts, rebaseArgs)
+		}
+
+		operation := fmt.Sprintf("rebase -s %s -d %s", opts.sourceName, opts.destName)
+		if opts.rebaseMerges {
+			operation += " --rebase-merges"
+		}
+		if opts.inMemory {
+			operation += " --in-memory"
+		}
+
+		if err := operations.BeginOplogEntry(context.Repo, operation); err != nil {
+			return err
+		}
+		if rebaseArgs.splitAt != nil {
+			if err := splitSourceBranch(cmd, context.Repo, rebaseArgs); err != nil {
+				operations.FinishOplogEntry(context.Repo, err)
+				return err
+			}
+		}
+
+		treeOpts := rebaseTreeOptions(opts.rebaseMerges)
+		treeOpts.InMemory = opts.inMemory
+		if rebaseArgs.destCommit != nil {
+			result = operations.RebaseTreeOntoCommit(context.Repo, rebaseArgs.source, rebaseArgs.destCommit, treeOpts)
+		} else {
+			result = operations.RebaseTreeWithOptions(context.Repo, rebaseArgs.source, rebaseArgs.dest, treeOpts)
+		}
+		operations.FinishOplogEntry(context.Repo, result.Error)
 	}
 
 	if result.Type == operations.RebaseTreeMergeConflict {
@@ -112,8 +195,80 @@ func runRebase(context *Context, opts *rebaseOptions) error {
 	return result.Error
 }
 
-func parseRebaseArgs(repo *git.Repository, opts *rebaseOptions) rebaseArgs {
-	sourceBranch, _ := repo.LookupBranch(opts.sourceName, git.BranchLocal)
-	destBranch, _ := repo.LookupBranch(opts.destName, git.BranchLocal)
-	return rebaseArgs{source: sourceBranch, dest: destBranch}
+// Prints the branches the rebase would move and the conflicts it would hit.
+func runRebaseDryRun(cmd *cobra.Command, context *Context, opts *rebaseOptions, rebaseArgs rebaseArgs) error {
+	var plan *operations.RebaseTreePlan
+	var err error
+	if rebaseArgs.destCommit != nil {
+		plan, err = operations.RebaseTreeOntoCommitDryRun(context.Repo, rebaseArgs.source, rebaseArgs.destCommit, rebaseTreeOptions(opts.rebaseMerges))
+	} else {
+		plan, err = operations.RebaseTreeDryRun(context.Repo, rebaseArgs.source, rebaseArgs.dest, rebaseTreeOptions(opts.rebaseMerges))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), plan.String())
+	return nil
+}
+
+func rebaseTreeOptions(rebaseMerges bool) operations.RebaseTreeOptions {
+	if rebaseMerges {
+		return operations.RebaseTreeOptions{MergeMode: gitutil.RecreateMerges}
+	}
+	return operations.RebaseTreeOptions{MergeMode: gitutil.FlattenMerges}
+}
+
+// Split the source branch below the commit it was given as, so that only the
+// commits from that commit onwards are rebased. The commits below it stay on a
+// new branch.
+func splitSourceBranch(cmd *cobra.Command, repo *git.Repository, rebaseArgs rebaseArgs) error {
+	sourceName := gitutil.BranchName(rebaseArgs.source)
+	newBranchName := gitutil.UniqueBranchName(repo, sourceName+"-base")
+	if err := splitBranch(repo, sourceName, newBranchName, rebaseArgs.splitAt); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Split branch %q: its commits up to %s stay on new branch %q.\n",
+		sourceName, gitutil.CommitShortHash(rebaseArgs.splitAt), newBranchName)
+	return nil
+}
+
+// Resolve the `--source` and `--dest` arguments.
+//
+// The source is a branch, or a commit among the own commits of a tracked
+// branch (i.e. those that are not in its parent). The destination is a branch,
+// or any revision that resolves to a commit.
+func parseRebaseArgs(repo *git.Repository, opts *rebaseOptions) (rebaseArgs, error) {
+	args := rebaseArgs{}
+
+	if branch, err := repo.LookupBranch(opts.sourceName, git.BranchLocal); err == nil {
+		args.source = branch
+	} else if commit, err := gitutil.CommitByRevision(repo, opts.sourceName); err == nil {
+		branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+		if err != nil {
+			return args, err
+		}
+		branchName := common.ContainingBranch(repo, branchMap, commit.Id())
+		if branchName == "" {
+			return args, fmt.Errorf("Commit %q is not on a tracked branch.", opts.sourceName)
+		}
+		args.source, _ = repo.LookupBranch(branchName, git.BranchLocal)
+
+		// Rebasing from the first commit of the branch moves the whole branch.
+		parent := branchMap.FindParent(branchName)
+		if commit.ParentCount() > 0 && !commit.ParentId(0).Equal(parent.Target()) {
+			args.splitAt = commit.Parent(0)
+		}
+	} else {
+		return args, fmt.Errorf("Could not find source branch %q.", opts.sourceName)
+	}
+
+	if branch, err := repo.LookupBranch(opts.destName, git.BranchLocal); err == nil {
+		args.dest = branch
+	} else if commit, err := gitutil.CommitByRevision(repo, opts.destName); err == nil {
+		args.destCommit = commit
+	} else {
+		return args, fmt.Errorf("Could not find dest branch %q.", opts.destName)
+	}
+	return args, nil
 }
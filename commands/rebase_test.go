@@ -36,6 +36,18 @@ func (suite *RebaseTestSuite) TestRebase_ErrorIfGitTreeNotInitialized() {
 	assert.EqualError(suite.T(), gotError, wantError)
 }
 
+func (suite *RebaseTestSuite) TestRebase_ContinueAndAbortTogether() {
+	suite.repo.BranchWithCommit("treecko")
+	NewInitCommand().Execute()
+
+	cmd := NewRebaseCommand()
+	cmd.SetArgs([]string{"--continue", "--abort"})
+	gotError := cmd.Execute()
+
+	wantError := "Command does not take both --continue and --abort."
+	assert.EqualError(suite.T(), gotError, wantError)
+}
+
 // Initial:
 //
 //	master ─── mew ─┬─ treecko
@@ -80,6 +92,107 @@ func (suite *RebaseTestSuite) TestRebase_DestBranchDoesNotExist() {
 	assert.EqualError(suite.T(), gotError, wantError)
 }
 
+// Initial:
+//
+//	master ─┬─ mud ─── kip    (mudkip)
+//	        └─ treecko
+//
+// Result:
+//
+//	master ─┬─ mud                      (mudkip-base)
+//	        └─ treecko ─── kip          (mudkip)
+func (suite *RebaseTestSuite) TestRebase_SourceCommitInsideBranchSplitsBranch() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.SwitchBranch("master")
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("mud", "mud", "mud")
+	suite.repo.WriteAndCommitFile("kip", "kip", "kip")
+	NewInitCommand().Execute()
+
+	cmd := NewRebaseCommand()
+	cmd.SetArgs([]string{"-s", "mudkip~0", "-d", "treecko"})
+	gotError := cmd.Execute()
+
+	assert.Nil(suite.T(), gotError)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "mudkip"))
+	assert.False(suite.T(), suite.repo.IsBranchAncestor("mudkip-base", "mudkip"))
+	assert.False(suite.T(), suite.repo.IsBranchAncestor("treecko", "mudkip-base"))
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["mudkip-base", "treecko"]},
+    {"branch": "treecko", "children": ["mudkip"]}
+  ]
+}`
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+// Initial:
+//
+//	master ─── mud ─── kip    (mudkip)
+//
+// Result:
+//
+//	master ─┬─ mud            (mudkip-base)
+//	        └─ kip            (mudkip)
+func (suite *RebaseTestSuite) TestRebase_SourceCommitInsideBranchOntoParent() {
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("mud", "mud", "mud")
+	suite.repo.WriteAndCommitFile("kip", "kip", "kip")
+	NewInitCommand().Execute()
+
+	cmd := NewRebaseCommand()
+	cmd.SetArgs([]string{"-s", "mudkip~0", "-d", "master"})
+	gotError := cmd.Execute()
+
+	assert.Nil(suite.T(), gotError)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("master", "mudkip"))
+	assert.False(suite.T(), suite.repo.IsBranchAncestor("mudkip-base", "mudkip"))
+}
+
+// Initial:
+//
+//	master ─── mud ─── kip    (mudkip)
+func (suite *RebaseTestSuite) TestRebase_RefusedRebaseDoesNotSplitSource() {
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("mud", "mud", "mud")
+	suite.repo.WriteAndCommitFile("kip", "kip", "kip")
+	NewInitCommand().Execute()
+	branchMap := suite.repo.ReadFile(".git/tree/branches")
+
+	cmd := NewRebaseCommand()
+	cmd.SetArgs([]string{"-s", "mudkip~0", "-d", "mudkip~1"})
+	gotError := cmd.Execute()
+
+	assert.EqualError(suite.T(), gotError, "Source cannot be an ancestor of destination")
+	assert.Nil(suite.T(), suite.repo.LookupBranch("mudkip-base"))
+	assert.Equal(suite.T(), branchMap, suite.repo.ReadFile(".git/tree/branches"))
+}
+
+// Initial:
+//
+//	master ─── mew ─┬─ treecko
+//	                └─ mudkip
+func (suite *RebaseTestSuite) TestRebase_SourceCommitNotOnTrackedBranch() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.BranchWithCommit("mudkip")
+	NewInitCommand().Execute()
+
+	cmd := NewRebaseCommand()
+	cmd.SetArgs([]string{"-s", "master~0", "-d", "treecko"})
+	gotError := cmd.Execute()
+
+	wantError := "Commit \"master~0\" is not on a tracked branch."
+	assert.EqualError(suite.T(), gotError, wantError)
+}
+
 func TestRebaseTestSuite(t *testing.T) {
 	suite.Run(t, new(RebaseTestSuite))
 }
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/spf13/cobra"
+)
+
+func NewRedoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "redo",
+		Short: "Redo the last undone git-tree operation",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			entry, err := operations.Redo(context.Repo)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Redid `git-tree %s`.\n", entry.Operation)
+			return nil
+		},
+	}
+
+	return cmd
+}
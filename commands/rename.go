@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/acamadeo/git-tree/common"
+	"github.com/acamadeo/git-tree/operations"
+	git "github.com/libgit2/git2go/v34"
+	"github.com/spf13/cobra"
+)
+
+func NewRenameCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename <old> <new>",
+		Short: "Rename a tracked branch",
+		Args:  cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateRenameArgs(context, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if err := operations.BeginOplogEntry(context.Repo, "rename "+strings.Join(args, " ")); err != nil {
+				return err
+			}
+			err = operations.Rename(context.Repo, args[0], args[1])
+			operations.FinishOplogEntry(context.Repo, err)
+			return err
+		},
+	}
+
+	return cmd
+}
+
+func validateRenameArgs(context *Context, args []string) error {
+	if !common.GitTreeInited(context.Repo.Path()) {
+		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+	}
+
+	if branch, _ := context.Repo.LookupBranch(args[1], git.BranchLocal); branch != nil {
+		return fmt.Errorf("Branch %q already exists in the git repository.", args[1])
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/acamadeo/git-tree/common"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
+	"github.com/spf13/cobra"
+)
+
+type syncOptions struct {
+	toContinue   bool
+	toAbort      bool
+	rebaseMerges bool
+}
+
+func NewSyncCommand() *cobra.Command {
+	var opts syncOptions
+
+	cmd := &cobra.Command{
+		Use:   "sync <trunk>",
+		Short: "Rebase the tree onto the latest tip of the trunk",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateSyncArgs(context, args, &opts)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runSync(context, args, &opts)
+		},
+	}
+
+	flags := cmd.Flags()
+
+	flags.BoolVar(&opts.toContinue, "continue", false, "Continue an in-progress git-tree sync")
+	flags.BoolVar(&opts.toAbort, "abort", false, "Abort an in-progress git-tree sync")
+	flags.BoolVar(&opts.rebaseMerges, "rebase-merges", false, "Recreate merge commits instead of flattening them")
+
+	return cmd
+}
+
+func validateSyncArgs(context *Context, args []string, opts *syncOptions) error {
+	if !common.GitTreeInited(context.Repo.Path()) {
+		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+	}
+
+	if opts.toAbort && opts.toContinue {
+		return errors.New("Command does not take both --continue and --abort.")
+	}
+	if opts.toAbort || opts.toContinue {
+		if len(args) > 0 {
+			return errors.New("Command does not take a trunk argument.")
+		}
+		if opts.rebaseMerges {
+			return errors.New("Command does not take --rebase-merges with --continue or --abort.")
+		}
+		if !utils.FileExists(store.SyncingPath(context.Repo.Path())) {
+			return errors.New("There is no git-tree sync in progress.")
+		}
+		return nil
+	}
+
+	if len(args) != 1 {
+		return errors.New("Command should be followed by the trunk to sync onto.")
+	}
+	return nil
+}
+
+// Rebases every stack in the tree onto the tip of the trunk.
+func runSync(context *Context, args []string, opts *syncOptions) error {
+	var result operations.RebaseTreeResult
+	if opts.toAbort {
+		result = operations.SyncAbort(context.Repo)
+		operations.DiscardOplogEntry(context.Repo)
+	} else if opts.toContinue {
+		result = operations.SyncContinue(context.Repo)
+		operations.FinishOplogEntry(context.Repo, result.Error)
+	} else {
+		operation := "sync " + args[0]
+		if opts.rebaseMerges {
+			operation += " --rebase-merges"
+		}
+
+		if err := operations.BeginOplogEntry(context.Repo, operation); err != nil {
+			return err
+		}
+		result = operations.SyncWithOptions(context.Repo, args[0], rebaseTreeOptions(opts.rebaseMerges))
+		operations.FinishOplogEntry(context.Repo, result.Error)
+	}
+
+	if result.Type == operations.RebaseTreeMergeConflict {
+		return errors.New("merge conflict encountered")
+	} else if result.Type == operations.RebaseTreeUnstagedChanges {
+		return errors.New("resolved files must be staged")
+	}
+	return result.Error
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/spf13/cobra"
+)
+
+func NewTopCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Check out the leaf branch of the current stack",
+		Long: "Check out the leaf branch of the current stack. If the stack forks, " +
+			"choose which child to follow when prompted.",
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateNavigation(context)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runTop(cmd, context)
+		},
+	}
+
+	return cmd
+}
+
+func runTop(cmd *cobra.Command, context *Context) error {
+	branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	if err != nil {
+		return err
+	}
+	current, _ := currentTrackedBranch(context, branchMap)
+	start := current
+
+	// Climb the stack until we reach a branch without children.
+	top := branchMap.FindBranch(current)
+	for children := branchMap.FindChildren(current); len(children) > 0; children = branchMap.FindChildren(current) {
+		child, err := chooseBranch(cmd, current, children, 0)
+		if err != nil {
+			return err
+		}
+		top = child
+		current = gitutil.BranchName(child)
+	}
+
+	if gitutil.BranchName(top) == start {
+		fmt.Fprintln(cmd.OutOrStdout(), "Already at the top of the stack.")
+		return nil
+	}
+	return checkoutTrackedBranch(cmd, context, top)
+}
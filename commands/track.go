@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/acamadeo/git-tree/common"
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+	"github.com/spf13/cobra"
+)
+
+func NewTrackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "track <branch>...",
+		Short: "Start tracking branches with git-tree",
+		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateTrack(context, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			oldRoot := rootTarget(context)
+			if err := operations.BeginOplogEntry(context.Repo, "track "+strings.Join(args, " ")); err != nil {
+				return err
+			}
+			err = operations.Track(context.Repo, branchesFromNames(context, args)...)
+			operations.FinishOplogEntry(context.Repo, err)
+			if err != nil {
+				return err
+			}
+
+			// Tracking a branch that does not descend from the root moves the
+			// root back, which changes the commits of the bottom branches.
+			if newRoot := rootTarget(context); !newRoot.Equal(&oldRoot) {
+				fmt.Fprintf(cmd.OutOrStdout(), "Moved %s from %s back to %s, the merge-base of the tracked branches.\n",
+					store.GitTreeRootBranch, gitutil.OidShortHash(oldRoot), gitutil.OidShortHash(newRoot))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// Returns the commit the root branch of the tree points to.
+func rootTarget(context *Context) git.Oid {
+	root, _ := context.Repo.LookupBranch(store.GitTreeRootBranch, git.BranchLocal)
+	return *root.Target()
+}
+
+func validateTrack(context *Context, branchNames []string) error {
+	if !common.GitTreeInited(context.Repo.Path()) {
+		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+	}
+
+	for _, branch := range branchNames {
+		if _, err := context.Repo.LookupBranch(branch, git.BranchLocal); err != nil {
+			return fmt.Errorf("Branch %q does not exist in the git repository.", branch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TrackTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+	// Directory the test is running in. In setUp(), we `cd` into `repo`'s
+	// working directory. In tearDown(), we return to `testDir`.
+	testDir string
+}
+
+func (suite *TrackTestSuite) SetupTest() {
+	suite.testDir, _ = os.Getwd()
+	suite.repo = testutil.CreateTestRepo()
+	os.Chdir(suite.repo.Repo.Workdir())
+}
+
+func (suite *TrackTestSuite) TearDownTest() {
+	os.Chdir(suite.testDir)
+	suite.repo.Free()
+}
+
+// Branches:
+//
+//	master ─── mew ─── treecko
+func (suite *TrackTestSuite) TestTrack_ReportsMovedRoot() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	initCmd := NewInitCommand()
+	initCmd.SetArgs([]string{"-b", "treecko"})
+	initCmd.Execute()
+	oldRoot := gitutil.OidShortHash(*suite.repo.LookupBranch("git-tree-root").Target())
+
+	cmd := NewTrackCommand()
+	cmd.SetArgs([]string{"master"})
+	output := &strings.Builder{}
+	cmd.SetOut(output)
+	gotError := cmd.Execute()
+
+	newRoot := gitutil.OidShortHash(*suite.repo.LookupBranch("git-tree-root").Target())
+	assert.Nil(suite.T(), gotError)
+	assert.NotEqual(suite.T(), oldRoot, newRoot)
+	assert.Equal(suite.T(),
+		fmt.Sprintf("Moved git-tree-root from %s back to %s, the merge-base of the tracked branches.\n", oldRoot, newRoot),
+		output.String())
+}
+
+// Branches:
+//
+//	master ─── mew ─── treecko
+func (suite *TrackTestSuite) TestTrack_RootNotMoved() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	initCmd := NewInitCommand()
+	initCmd.SetArgs([]string{"-b", "mew"})
+	initCmd.Execute()
+
+	cmd := NewTrackCommand()
+	cmd.SetArgs([]string{"treecko", "treecko"})
+	output := &strings.Builder{}
+	cmd.SetOut(output)
+	gotError := cmd.Execute()
+
+	assert.Nil(suite.T(), gotError)
+	assert.Empty(suite.T(), output.String())
+}
+
+func TestTrackTestSuite(t *testing.T) {
+	suite.Run(t, new(TrackTestSuite))
+}
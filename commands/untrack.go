@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/acamadeo/git-tree/common"
+	"github.com/acamadeo/git-tree/operations"
+	"github.com/spf13/cobra"
+)
+
+func NewUntrackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "untrack <branch>...",
+		Short: "Stop tracking branches with git-tree",
+		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return validateUntrack(context)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if err := operations.BeginOplogEntry(context.Repo, "untrack "+strings.Join(args, " ")); err != nil {
+				return err
+			}
+			err = operations.Untrack(context.Repo, args...)
+			operations.FinishOplogEntry(context.Repo, err)
+			return err
+		},
+	}
+
+	return cmd
+}
+
+func validateUntrack(context *Context) error {
+	if !common.GitTreeInited(context.Repo.Path()) {
+		return errors.New("git-tree is not initialized. Run `git-tree init` to initialize.")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/acamadeo/git-tree/store"
+	"github.com/spf13/cobra"
+)
+
+func NewUpCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "up [index]",
+		Short: "Check out a child of the current branch",
+		Long: "Check out a child of the current branch. If the branch has several " +
+			"children, pick one by its (1-based) index or choose it when prompted.",
+		Args: cobra.MaximumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			if err := validateUpArgs(args); err != nil {
+				return err
+			}
+			return validateNavigation(context)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			context, err := CreateContext()
+			if err != nil {
+				return err
+			}
+
+			return runUp(cmd, context, args)
+		},
+	}
+
+	return cmd
+}
+
+func validateUpArgs(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if index, err := strconv.Atoi(args[0]); err != nil || index < 1 {
+		return fmt.Errorf("Index %q must be a positive number.", args[0])
+	}
+	return nil
+}
+
+func runUp(cmd *cobra.Command, context *Context, args []string) error {
+	index := 0
+	if len(args) == 1 {
+		index, _ = strconv.Atoi(args[0])
+	}
+
+	branchMap, err := store.ReadBranchMap(context.Repo, store.BranchMapPath(context.Repo.Path()))
+	if err != nil {
+		return err
+	}
+	current, _ := currentTrackedBranch(context, branchMap)
+
+	children := branchMap.FindChildren(current)
+	if len(children) == 0 {
+		return fmt.Errorf("Branch %q has no children.", current)
+	}
+
+	child, err := chooseBranch(cmd, current, children, index)
+	if err != nil {
+		return err
+	}
+	return checkoutTrackedBranch(cmd, context, child)
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	gitutil "github.com/acamadeo/git-tree/git"
 	"github.com/acamadeo/git-tree/models"
 	"github.com/acamadeo/git-tree/store"
 	"github.com/acamadeo/git-tree/utils"
@@ -30,3 +31,21 @@ func OnTipCommit(repo *git.Repository, branchMap *models.BranchMap) bool {
 	}
 	return false
 }
+
+// Returns the name of the tracked branch whose own commits (i.e. those that are
+// not in its parent) include commit `oid`, or "" if there is none.
+func ContainingBranch(repo *git.Repository, branchMap *models.BranchMap, oid *git.Oid) string {
+	for _, name := range branchMap.ListBranchNames() {
+		parent := branchMap.FindParent(name)
+		branch, err := repo.LookupBranch(name, git.BranchLocal)
+		if parent == nil || err != nil {
+			continue
+		}
+		for _, commit := range gitutil.CommitsBetween(repo, parent.Target(), branch.Target()) {
+			if commit.Id().Equal(oid) {
+				return name
+			}
+		}
+	}
+	return ""
+}
@@ -89,3 +89,7 @@ func TestE2eEvolveDescendants(t *testing.T) {
 func TestE2eEvolveCombinations(t *testing.T) {
 	testscript.Run(t, testscriptParams("evolve/combinations"))
 }
+
+func TestE2eEvolveConflicts(t *testing.T) {
+	testscript.Run(t, testscriptParams("evolve/conflicts"))
+}
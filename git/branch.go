@@ -19,7 +19,11 @@ func AllLocalBranches(repo *git.Repository) []*git.Branch {
 	return output
 }
 
+// Returns the name of `branch`, or "" if `branch` is nil.
 func BranchName(branch *git.Branch) string {
+	if branch == nil {
+		return ""
+	}
 	name, _ := branch.Name()
 	return name
 }
@@ -71,8 +75,12 @@ func nameWithNumber(name string, number int) string {
 	return fmt.Sprintf("%s-%d", name, number)
 }
 
+// Returns the branch HEAD points to, or nil if HEAD is detached or unborn.
 func HeadBranch(repo *git.Repository) *git.Branch {
-	headRef, _ := repo.Head()
+	headRef, err := repo.Head()
+	if err != nil || !headRef.IsBranch() {
+		return nil
+	}
 	return headRef.Branch()
 }
 
@@ -98,3 +98,18 @@ func commitOids(commits []*git.Commit) []*git.Oid {
 	}
 	return unique
 }
+
+// Returns the commit that revision `spec` (e.g. a branch, tag, or hash)
+// resolves to.
+func CommitByRevision(repo *git.Repository, spec string) (*git.Commit, error) {
+	object, err := repo.RevparseSingle(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	peeled, err := object.Peel(git.ObjectCommit)
+	if err != nil {
+		return nil, err
+	}
+	return peeled.AsCommit()
+}
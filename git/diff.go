@@ -0,0 +1,92 @@
+package gitutil
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+
+	git "github.com/libgit2/git2go/v34"
+)
+
+// Returns the patch that turns the tree of commit `from` into the tree of
+// commit `to`.
+func DiffCommitTrees(repo *git.Repository, from *git.Commit, to *git.Commit) (string, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return "", err
+	}
+	defer fromTree.Free()
+
+	toTree, err := to.Tree()
+	if err != nil {
+		return "", err
+	}
+	defer toTree.Free()
+
+	return DiffTrees(repo, fromTree, toTree)
+}
+
+// Returns the patch that turns tree `from` into tree `to`.
+func DiffTrees(repo *git.Repository, from *git.Tree, to *git.Tree) (string, error) {
+	diff, err := repo.DiffTreeToTree(from, to, nil)
+	if err != nil {
+		return "", err
+	}
+	defer diff.Free()
+
+	patch, err := diff.ToBuf(git.DiffFormatPatch)
+	if err != nil {
+		return "", err
+	}
+	return string(patch), nil
+}
+
+// Returns an identifier of the changes `commit` makes to its first parent, like
+// `git patch-id`: two commits that make the same changes (e.g. a commit and
+// its rebased or cherry-picked copy) have the same patch ID.
+//
+// Whitespace and line numbers are ignored. Returns false for merge commits and
+// commits without changes.
+func PatchId(repo *git.Repository, commit *git.Commit) (string, bool) {
+	if commit.ParentCount() > 1 {
+		return "", false
+	}
+
+	var parentTree *git.Tree
+	if commit.ParentCount() == 1 {
+		tree, err := commit.Parent(0).Tree()
+		if err != nil {
+			return "", false
+		}
+		defer tree.Free()
+		parentTree = tree
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", false
+	}
+	defer tree.Free()
+
+	diff, err := repo.DiffTreeToTree(parentTree, tree, nil)
+	if err != nil {
+		return "", false
+	}
+	defer diff.Free()
+
+	patch, err := diff.ToBuf(git.DiffFormatPatch)
+	if err != nil || len(patch) == 0 {
+		return "", false
+	}
+
+	hash := sha1.New()
+	for _, line := range strings.Split(string(patch), "\n") {
+		// Skip the lines that depend on the parent rather than on the change.
+		if strings.HasPrefix(line, "index ") || strings.HasPrefix(line, "@@") {
+			continue
+		}
+		hash.Write([]byte(strings.Join(strings.Fields(line), "")))
+		hash.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), true
+}
@@ -0,0 +1,318 @@
+package gitutil
+
+import (
+	"fmt"
+
+	git "github.com/libgit2/git2go/v34"
+)
+
+// How merge commits are handled when rebasing.
+type MergeMode int
+
+const (
+	// Merge commits are dropped and the commits they merged in are replayed
+	// one after another (like `git rebase`).
+	FlattenMerges MergeMode = iota
+	// Merge commits are recreated on top of the rebased commits (like
+	// `git rebase --rebase-merges`).
+	RecreateMerges
+)
+
+// Returns true if any commit reachable from `tip` but not from `base` is a
+// merge commit.
+func HasMergeCommits(repo *git.Repository, base *git.Oid, tip *git.Oid) bool {
+	for _, commit := range CommitsBetween(repo, base, tip) {
+		if commit.ParentCount() > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the Oid's of every parent of `commit`, in order.
+func ParentIds(commit *git.Commit) []git.Oid {
+	parents := []git.Oid{}
+	for i := uint(0); i < commit.ParentCount(); i++ {
+		parents = append(parents, *commit.ParentId(i))
+	}
+	return parents
+}
+
+// Create a copy of `commit` whose parents are `parents`, returning the new
+// commit. The commit is replayed in memory; the working tree is not touched.
+//
+// The changes a commit introduces are taken relative to its first parent, so
+// a merge commit keeps any conflict resolutions it contained. If the other
+// parents of a merge commit changed as well, the merge is performed again.
+func RecreateCommit(repo *git.Repository, commit *git.Commit, parents []git.Oid) (git.Oid, error) {
+	oid, conflicted, err := recreateCommit(repo, commit, parents)
+	if conflicted {
+		return git.Oid{}, fmt.Errorf("Merge conflict while recreating commit %s", CommitShortHash(commit))
+	}
+	return oid, err
+}
+
+// Same as RecreateCommit(), but returns whether the commit could not be
+// recreated because of a merge conflict.
+func recreateCommit(repo *git.Repository, commit *git.Commit, parents []git.Oid) (git.Oid, bool, error) {
+	if oidsEqual(ParentIds(commit), parents) {
+		return *commit.Id(), false, nil
+	}
+
+	parentCommits := []*git.Commit{}
+	for _, parent := range parents {
+		parentCommits = append(parentCommits, CommitByOid(repo, parent))
+	}
+
+	tree, conflicted, err := recreateTree(repo, commit, parentCommits)
+	if conflicted || err != nil {
+		return git.Oid{}, conflicted, err
+	}
+	defer tree.Free()
+
+	oid, err := repo.CreateCommit("", commit.Author(), commit.Committer(), commit.Message(), tree, parentCommits...)
+	if err != nil {
+		return git.Oid{}, false, fmt.Errorf("Could not create commit: %s", err)
+	}
+	return *oid, false, nil
+}
+
+// Returns the tree that RecreateCommit() would give the copy of `commit` whose
+// parents are `parents`, without creating the commit.
+func RecreateTree(repo *git.Repository, commit *git.Commit, parents []git.Oid) (*git.Tree, error) {
+	if oidsEqual(ParentIds(commit), parents) {
+		return commit.Tree()
+	}
+
+	parentCommits := []*git.Commit{}
+	for _, parent := range parents {
+		parentCommits = append(parentCommits, CommitByOid(repo, parent))
+	}
+
+	tree, conflicted, err := recreateTree(repo, commit, parentCommits)
+	if conflicted {
+		return nil, fmt.Errorf("Merge conflict while recreating commit %s", CommitShortHash(commit))
+	}
+	return tree, err
+}
+
+// Returns the tree of the copy of `commit` whose parents are `parents`, or
+// whether it could not be recreated because of a merge conflict.
+func recreateTree(repo *git.Repository, commit *git.Commit, parents []*git.Commit) (*git.Tree, bool, error) {
+	index, err := recreatedIndex(repo, commit, parents)
+	if err != nil {
+		return nil, false, err
+	}
+	defer index.Free()
+
+	if index.HasConflicts() {
+		return nil, true, nil
+	}
+
+	treeOid, err := index.WriteTreeTo(repo)
+	if err != nil {
+		return nil, false, fmt.Errorf("Could not write tree: %s", err)
+	}
+	tree, err := repo.LookupTree(treeOid)
+	if err != nil {
+		return nil, false, err
+	}
+	return tree, false, nil
+}
+
+func recreatedIndex(repo *git.Repository, commit *git.Commit, parents []*git.Commit) (*git.Index, error) {
+	mergeOpts := git.MergeOptions{TreeFlags: git.MergeTreeFindRenames}
+
+	// Only the first parent changed: apply the commit's changes onto it.
+	if oidsEqual(ParentIds(commit)[1:], commitIds(parents[1:])) {
+		opts := git.CherrypickOptions{MergeOptions: mergeOpts}
+		if commit.ParentCount() > 1 {
+			opts.Mainline = 1
+		}
+		return repo.CherrypickCommit(commit, parents[0], opts)
+	}
+
+	if len(parents) != 2 {
+		return nil, fmt.Errorf("Cannot recreate octopus merge commit %s", CommitShortHash(commit))
+	}
+	return repo.MergeCommits(parents[0], parents[1], &mergeOpts)
+}
+
+// Rebase commits in branch `toMove` that aren't in branch `upstream` onto
+// branch `onto`, recreating any merge commits among them.
+//
+// Commits whose parent is `upstream` are moved onto `onto`. Commits merged in
+// from elsewhere keep their original parents. The commits are replayed in
+// memory, so a merge conflict fails the rebase with RebaseError without moving
+// `toMove`. It is up to the caller to undo any other branch it rebased.
+func RebaseRecreatingMerges(repo *git.Repository, upstream, onto *git.Branch, toMove **git.Branch) RebaseResult {
+	newTip, result := RebaseInMemory(repo, *upstream.Target(), *onto.Target(), *(*toMove).Target(), RecreateMerges)
+	if result.Type == RebaseMergeConflict {
+		// There is no rebase in progress to resolve the conflict in, so it
+		// cannot be continued.
+		return RebaseResult{Type: RebaseError, Error: result.Error}
+	} else if result.Type != RebaseSuccess {
+		return result
+	}
+
+	// Leave HEAD on the rebased branch, as a regular rebase would. HEAD is
+	// detached first so the working tree is checked out relative to it.
+	if head, err := repo.Head(); err == nil {
+		repo.SetHeadDetached(head.Target())
+	}
+	msg := fmt.Sprintf("[git-tree] rebase %s recreating merges", BranchName(*toMove))
+	newRef, err := (*toMove).SetTarget(&newTip, msg)
+	if err != nil {
+		return RebaseResult{Type: RebaseError, Error: err}
+	}
+	*toMove = newRef.Branch()
+
+	if err := CheckoutBranch(repo, *toMove); err != nil {
+		return RebaseResult{Type: RebaseError, Error: err}
+	}
+	return RebaseResult{Type: RebaseSuccess}
+}
+
+// Rebase the commits reachable from `tip` but not from `upstream` onto commit
+// `onto`, returning the rebased tip. Merge commits are handled as described by
+// `mode`.
+//
+// Commits are created from merged index trees in memory. No branch is moved
+// and neither HEAD nor the working tree is touched, so a merge conflict stops
+// the rebase with RebaseMergeConflict without anything to clean up.
+func RebaseInMemory(repo *git.Repository, upstream, onto, tip git.Oid, mode MergeMode) (git.Oid, RebaseResult) {
+	rewritten := map[git.Oid]git.Oid{upstream: onto}
+	newTip := onto
+
+	for _, commit := range CommitsBetween(repo, &upstream, &tip) {
+		var parents []git.Oid
+		if mode == FlattenMerges {
+			// Like libgit2, drop merge commits and replay the other commits one
+			// after another.
+			if commit.ParentCount() > 1 {
+				continue
+			}
+			parents = []git.Oid{newTip}
+		} else {
+			// Commits merged in from elsewhere keep their original parents.
+			parents = ParentIds(commit)
+			for i, parent := range parents {
+				if newParent, ok := rewritten[parent]; ok {
+					parents[i] = newParent
+				}
+			}
+		}
+
+		newOid, conflicted, err := recreateCommit(repo, commit, parents)
+		if err != nil {
+			return onto, RebaseResult{Type: RebaseError, Error: err}
+		}
+		if conflicted {
+			err := fmt.Errorf("Merge conflict while recreating commit %s", CommitShortHash(commit))
+			return onto, RebaseResult{Type: RebaseMergeConflict, Error: err}
+		}
+		rewritten[*commit.Id()] = newOid
+		newTip = newOid
+	}
+
+	if mode == RecreateMerges {
+		// The tip is left where it is if it has nothing to rebase.
+		newTip = tip
+		if rebasedTip, ok := rewritten[tip]; ok {
+			newTip = rebasedTip
+		}
+	}
+	return newTip, RebaseResult{Type: RebaseSuccess}
+}
+
+// Apply the changes `commit` introduces relative to its first parent onto the
+// tree `onto`, returning the resulting tree and the paths that conflict. The
+// commit is replayed in memory; no commit or branch is created and the working
+// tree is not touched.
+//
+// Conflicting paths take the version of `commit`, so that further commits can
+// be replayed on top of the result.
+func CherrypickOntoTree(repo *git.Repository, commit *git.Commit, onto *git.Tree) (*git.Tree, []string, error) {
+	var ancestor *git.Tree
+	if commit.ParentCount() > 0 {
+		ancestor, _ = commit.Parent(0).Tree()
+	}
+	theirs, err := commit.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mergeOpts := git.MergeOptions{TreeFlags: git.MergeTreeFindRenames}
+	index, err := repo.MergeTrees(ancestor, onto, theirs, &mergeOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not replay commit %s: %s", CommitShortHash(commit), err)
+	}
+	defer index.Free()
+
+	conflicts := []git.IndexConflict{}
+	if index.HasConflicts() {
+		iterator, err := index.ConflictIterator()
+		if err != nil {
+			return nil, nil, err
+		}
+		for {
+			conflict, err := iterator.Next()
+			if err != nil {
+				break
+			}
+			conflicts = append(conflicts, conflict)
+		}
+		iterator.Free()
+	}
+
+	paths := []string{}
+	for _, conflict := range conflicts {
+		path := conflictPath(conflict)
+		paths = append(paths, path)
+		index.RemoveConflict(path)
+		if conflict.Their != nil {
+			index.Add(conflict.Their)
+		}
+	}
+
+	treeOid, err := index.WriteTreeTo(repo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not write tree: %s", err)
+	}
+	tree, err := repo.LookupTree(treeOid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree, paths, nil
+}
+
+// Returns the path of a conflicted file. Any side of the conflict may be
+// missing, e.g. if the file was deleted on one side.
+func conflictPath(conflict git.IndexConflict) string {
+	for _, entry := range []*git.IndexEntry{conflict.Our, conflict.Their, conflict.Ancestor} {
+		if entry != nil {
+			return entry.Path
+		}
+	}
+	return ""
+}
+
+func commitIds(commits []*git.Commit) []git.Oid {
+	oids := []git.Oid{}
+	for _, commit := range commits {
+		oids = append(oids, *commit.Id())
+	}
+	return oids
+}
+
+func oidsEqual(a []git.Oid, b []git.Oid) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(&b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package gitutil
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	git "github.com/libgit2/git2go/v34"
+)
+
+// An entry of a reflog: the reference moved from `Old` to `New` because of
+// the operation described by `Message` (e.g. `commit (amend): ...`).
+type ReflogEntry struct {
+	Old     git.Oid
+	New     git.Oid
+	Message string
+}
+
+// Returns the entries of the reflog of local branch `branchName`, from oldest
+// to newest. Returns no entries if the branch has no reflog.
+//
+// git2go does not expose the reflog, so the file under `.git/logs` is read
+// directly. Branch reflogs are shared by all worktrees, so they live in the
+// common git directory. Each line has the form:
+//
+//	<old> <new> <name> <<email>> <timestamp> <timezone>\t<message>
+func BranchReflog(repo *git.Repository, branchName string) []ReflogEntry {
+	file, err := os.Open(filepath.Join(CommonDir(repo.Path()), "logs", "refs", "heads", branchName))
+	if err != nil {
+		return []ReflogEntry{}
+	}
+	defer file.Close()
+
+	entries := []ReflogEntry{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		header, message, _ := strings.Cut(scanner.Text(), "\t")
+		fields := strings.Fields(header)
+		if len(fields) < 2 {
+			continue
+		}
+
+		oldOid, err := git.NewOid(fields[0])
+		if err != nil {
+			continue
+		}
+		newOid, err := git.NewOid(fields[1])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, ReflogEntry{Old: *oldOid, New: *newOid, Message: message})
+	}
+	return entries
+}
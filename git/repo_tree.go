@@ -21,14 +21,12 @@ import (
 //   - Sort the children of each commit by Oid value.
 //   - Sort the names of the branches associated with a particular commit.
 //
-// TODO: Add support for merge commits.
-//   - NOTE: libgit2 rebase might not work if the commit history has merge commits
+// Merge commits are children of each of their parents, so they can be reached
+// from any of them. Parents that were merged in from outside the tree (e.g. a
+// teammate's branch) are not descendants of `Root`.
+//   - NOTE: libgit2 rebase drops merge commits, flattening the history.
 //     https://github.com/libgit2/libgit2/blob/198a1b209a929389c739a8a6abef13e717fdfda9/src/libgit2/rebase.c#L818
-//   - NOTE: We could ask users to avoid using `git merge` to sync with the
-//     upstream repo. Our `git tree sync` should *rebase* (not merge) working
-//     changes onto main HEAD.
-//   - - Down the line, we should get all the operations working even if there are
-//     merge commits.
+//     See `RebaseRecreatingMerges()` to keep them.
 type RepoTree struct {
 	Repo *git.Repository
 	Root git.Oid
@@ -59,6 +57,17 @@ func (r *RepoTree) FindBranches(commit git.Oid) []string {
 	return ret
 }
 
+// Returns true if `commit` is the root of the tree or descends from it.
+//
+// Commits merged in from outside the tree are not descendants of the root.
+func (r *RepoTree) IsDescendantOfRoot(commit git.Oid) bool {
+	if commit.Equal(&r.Root) {
+		return true
+	}
+	isDescendant, _ := r.Repo.DescendantOf(&commit, &r.Root)
+	return isDescendant
+}
+
 func findRepoTreeRoot(repo *git.Repository) git.Oid {
 	revWalk := InitWalkWithAllBranches(repo)
 
@@ -100,18 +109,17 @@ func createCommitChildren(repo *git.Repository, root git.Oid, branches ...*git.B
 	commitChildren := initCommitChildren(LocalCommitsFromBranches_RootOid(repo, &root, branches...))
 
 	// Iterate through the commits, constructing a commit descendancy tree.
+	//
+	// Commits merged in from outside the tree may be ordered after `root`, so
+	// the walk hides the ancestors of `root` instead of stopping at it.
 	revWalk := InitWalkWithAllBranches(repo)
+	revWalk.Hide(&root)
 	revWalk.Iterate(func(commit *git.Commit) bool {
-		// Stop adding commits to RepoTree once we hit `root`.
-		if root == *commit.Id() {
-			return false
-		}
-
-		// Add this commit as a child of its parent.
-		if commit.ParentCount() > 0 {
-			children := commitChildren[*commit.ParentId(0)]
+		// Add this commit as a child of each of its parents.
+		for _, parent := range ParentIds(commit) {
+			children := commitChildren[parent]
 			children = children.Add(*commit.Id())
-			commitChildren[*commit.ParentId(0)] = children
+			commitChildren[parent] = children
 		}
 
 		return true
@@ -0,0 +1,26 @@
+package gitutil
+
+import git "github.com/libgit2/git2go/v34"
+
+// Returns true if the index or working tree contain changes to tracked files.
+//
+// Untracked files are ignored, since checking out another commit leaves them
+// in place.
+//
+// Returns an error if the status cannot be read, so that callers do not mistake
+// an unreadable working tree for a clean one.
+func HasUncommittedChanges(repo *git.Repository) (bool, error) {
+	statusList, err := repo.StatusList(&git.StatusOptions{
+		Show: git.StatusShowIndexAndWorkdir,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer statusList.Free()
+
+	count, err := statusList.EntryCount()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
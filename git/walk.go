@@ -17,3 +17,19 @@ func InitWalkWithBranches(repo *git.Repository, branches ...*git.Branch) *git.Re
 	return revWalk
 
 }
+
+// Returns the commits reachable from `tip` that are not reachable from `base`,
+// ordered from oldest to newest.
+func CommitsBetween(repo *git.Repository, base *git.Oid, tip *git.Oid) []*git.Commit {
+	revWalk, _ := repo.Walk()
+	revWalk.Sorting(git.SortTopological | git.SortReverse)
+	revWalk.Push(tip)
+	revWalk.Hide(base)
+
+	commits := []*git.Commit{}
+	revWalk.Iterate(func(commit *git.Commit) bool {
+		commits = append(commits, commit)
+		return true
+	})
+	return commits
+}
@@ -0,0 +1,143 @@
+package gitutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/acamadeo/git-tree/utils"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// A working tree of the repository other than the current one.
+type Worktree struct {
+	// Root of the working tree.
+	Path string
+	// Name of the branch checked out in the working tree, or "" if its HEAD is
+	// detached.
+	Branch string
+}
+
+// Returns the common git directory of the repository whose git directory is
+// `gitPath`.
+//
+// In a linked worktree (see `git worktree`), `gitPath` is
+// `.git/worktrees/<name>`, which only holds the state of that worktree (e.g.
+// HEAD and the index). The state shared by all worktrees (e.g. the refs and the
+// config) lives in the common git directory, which the `commondir` file points
+// to. Otherwise `gitPath` is the common git directory.
+func CommonDir(gitPath string) string {
+	contents, err := os.ReadFile(filepath.Join(gitPath, "commondir"))
+	if err != nil {
+		return gitPath
+	}
+
+	dir := strings.TrimSpace(string(contents))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitPath, dir)
+	}
+	return filepath.Clean(dir) + string(filepath.Separator)
+}
+
+// Returns the working trees of the repository other than the one of `repo`,
+// including the main working tree unless the repository is bare.
+//
+// git2go does not expose `git worktree list`, so the files under
+// `.git/worktrees` are read directly.
+func OtherWorktrees(repo *git.Repository) []Worktree {
+	commonDir := CommonDir(repo.Path())
+
+	// The main working tree is the parent of the common git directory, unless
+	// the repository is bare.
+	gitDirs := map[string]string{}
+	if filepath.Base(filepath.Clean(commonDir)) == ".git" {
+		gitDirs[commonDir] = filepath.Dir(filepath.Clean(commonDir))
+	}
+
+	// Each linked working tree has a directory under `.git/worktrees`, whose
+	// `gitdir` file points to the `.git` file at the root of the working tree.
+	entries, _ := os.ReadDir(filepath.Join(commonDir, "worktrees"))
+	for _, entry := range entries {
+		gitDir := filepath.Join(commonDir, "worktrees", entry.Name())
+		dotGit := strings.TrimSpace(utils.ReadFile(filepath.Join(gitDir, "gitdir")))
+		if dotGit != "" {
+			gitDirs[gitDir] = filepath.Dir(dotGit)
+		}
+	}
+
+	worktrees := []Worktree{}
+	for gitDir, path := range gitDirs {
+		// Skip the current worktree and worktrees that were deleted without
+		// being pruned.
+		if filepath.Clean(gitDir) == filepath.Clean(repo.Path()) || !utils.DirExists(path) {
+			continue
+		}
+
+		head := strings.TrimSpace(utils.ReadFile(filepath.Join(gitDir, "HEAD")))
+		branch := strings.TrimPrefix(head, "ref: refs/heads/")
+		if branch == head {
+			branch = ""
+		}
+		worktrees = append(worktrees, Worktree{Path: path, Branch: branch})
+	}
+	return worktrees
+}
+
+// Returns the other working trees (see `OtherWorktrees()`) that have any of
+// `branchNames` checked out, keyed by branch name.
+func WorktreesOfBranches(repo *git.Repository, branchNames ...string) map[string]Worktree {
+	wanted := map[string]bool{}
+	for _, name := range branchNames {
+		wanted[name] = true
+	}
+
+	worktrees := map[string]Worktree{}
+	for _, worktree := range OtherWorktrees(repo) {
+		if worktree.Branch != "" && wanted[worktree.Branch] {
+			worktrees[worktree.Branch] = worktree
+		}
+	}
+	return worktrees
+}
+
+// Update the files of the working tree at `path` to match its HEAD, after its
+// branch was moved from another working tree.
+func UpdateWorktree(path string) error {
+	repo, err := git.OpenRepository(path)
+	if err != nil {
+		return err
+	}
+	defer repo.Free()
+
+	return UpdateWorkdir(repo)
+}
+
+// Update the files of the working tree of `repo` to match its HEAD, after the
+// branch HEAD points to was moved without checking it out.
+//
+// Files are only updated if they match the index of the working tree, so local
+// changes are never overwritten.
+func UpdateWorkdir(repo *git.Repository) error {
+	// The index still describes the commit the branch pointed to before it was
+	// moved, so use it as the baseline of the checkout.
+	index, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	defer index.Free()
+
+	treeOid, err := index.WriteTree()
+	if err != nil {
+		return err
+	}
+	baseline, err := repo.LookupTree(treeOid)
+	if err != nil {
+		return err
+	}
+	defer baseline.Free()
+
+	return repo.CheckoutHead(&git.CheckoutOptions{
+		Strategy: git.CheckoutSafe,
+		Baseline: baseline,
+	})
+}
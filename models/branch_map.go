@@ -25,6 +25,7 @@ type BranchMap struct {
 //    they omitted branch <A>'s parent, branch <B>.
 //  - Initially, I think that maybe you should allow the user to call
 //    `git tree init -b <B>` later to add any branches into the tree.
+//     * `git tree track <B>` now does this.
 //  - What would be really nice is if you don't need to pass any branches into
 //    `git tree init`. E.g., if it constructed a tree using all the local
 //    branches. But I haven't thought about feasibility / design implications.
@@ -90,6 +91,64 @@ func addBranchToTree(repo *git.Repository, branchMap *BranchMap, curBranch *git.
 	addChildren(branchMap, curBranch, []*git.Branch{newBranch})
 }
 
+// Add branch `newBranch` to the descendency tree, moving any tracked branches
+// that descend from it under it.
+//
+// It is assumed that `newBranch` descends from the root of the tree.
+func (b *BranchMap) AddBranch(repo *git.Repository, newBranch *git.Branch) {
+	// The root may be tracked under a different pointer than `b.Root`.
+	root := b.FindBranch(gitutil.BranchName(b.Root))
+	if root == nil {
+		root = b.Root
+	}
+	addBranchToTree(repo, b, root, newBranch)
+}
+
+// Remove branch `branchName` from the descendency tree. Its children become
+// children of its parent.
+func (b *BranchMap) RemoveBranch(branchName string) {
+	branch := b.FindBranch(branchName)
+	parent := b.FindParent(branchName)
+	children := b.FindChildren(branchName)
+
+	removeChild(b, parent, branch)
+	delete(b.Children, branch)
+	addChildren(b, parent, children)
+}
+
+// Replace branch `oldName` with `newBranch` (e.g., after the branch was
+// renamed), keeping its place in the descendency tree.
+func (b *BranchMap) ReplaceBranch(oldName string, newBranch *git.Branch) {
+	if oldBranch := b.FindBranch(oldName); oldBranch != nil {
+		b.Children[newBranch] = b.Children[oldBranch]
+		delete(b.Children, oldBranch)
+	}
+
+	for _, children := range b.Children {
+		for i, child := range children {
+			if gitutil.BranchName(child) == oldName {
+				children[i] = newBranch
+			}
+		}
+	}
+
+	if gitutil.BranchName(b.Root) == oldName {
+		b.Root = newBranch
+	}
+}
+
+// Insert `newBranch` between branch `branchName` and its parent (e.g., after
+// the branch was split in two).
+func (b *BranchMap) InsertParent(branchName string, newBranch *git.Branch) {
+	parent := b.FindParent(branchName)
+	for i, child := range b.Children[parent] {
+		if gitutil.BranchName(child) == branchName {
+			b.Children[parent][i] = newBranch
+			b.Children[newBranch] = BranchList{child}
+		}
+	}
+}
+
 // Add `children` to the given branch `branch`.
 func addChildren(branchMap *BranchMap, branch *git.Branch, children []*git.Branch) {
 	// If `branch` does not exist in `branchMap`, add a key for it.
@@ -219,6 +278,15 @@ func (b *BranchMap) FindChildren(branchName string) BranchList {
 	return nil
 }
 
+// Returns the names of branch `branchName` and all its descendants.
+func (b *BranchMap) ListSubtreeBranchNames(branchName string) []string {
+	branchNames := []string{branchName}
+	for _, child := range b.FindChildren(branchName) {
+		branchNames = append(branchNames, b.ListSubtreeBranchNames(gitutil.BranchName(child))...)
+	}
+	return branchNames
+}
+
 func (b *BranchMap) RemoveChildren(parentName string, children []string) {
 	parent := b.FindBranch(parentName)
 
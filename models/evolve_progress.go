@@ -0,0 +1,28 @@
+package models
+
+import git "github.com/libgit2/git2go/v34"
+
+// A single step of the Evolve operation: rebasing `Commit` onto `Onto`.
+type EvolveStep struct {
+	Commit git.Oid
+	Onto   git.Oid
+}
+
+// The progress of an Evolve operation that was interrupted by a merge
+// conflict.
+type EvolveProgress struct {
+	// The branch HEAD pointed to before the operation started, or its commit
+	// if HEAD was detached.
+	HeadBranch string
+	// Map from each step that was already performed to the rebased commit it
+	// produced.
+	Rebased map[EvolveStep]git.Oid
+	// The step that was interrupted by the merge conflict.
+	Pending EvolveStep
+	// The temporary branch the interrupted step is rebasing.
+	PendingBranch string
+	// Temporary branches created by the operation.
+	TempBranches []string
+	// Whether commits are rebased in memory.
+	InMemory bool
+}
@@ -19,6 +19,9 @@ const (
 	PostRewriteAmend
 	PostRewriteRebase
 	PostCommit
+	// Inferred from the reflog of a branch, for rewrites that did not fire the
+	// git-hooks.
+	ReflogScan
 )
 
 // Contains a map of the each obsolete commit and the commit that obsoleted it.
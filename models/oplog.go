@@ -0,0 +1,24 @@
+package models
+
+import git "github.com/libgit2/git2go/v34"
+
+// The state of the repository that git-tree operations modify.
+type RepoSnapshot struct {
+	// Target of each branch, keyed by branch name. Branches that did not exist
+	// map to the zero oid.
+	Branches map[string]git.Oid
+	// The branch HEAD points to, or the commit HEAD points to if detached.
+	Head string
+	// Contents of the git-tree files (e.g. "branches", "obsmap"), keyed by
+	// file name. Files that did not exist are left out.
+	Files map[string]string
+}
+
+// An operation recorded in the operation log.
+type OplogEntry struct {
+	// The command line of the operation (e.g. "rebase -s treecko -d mudkip").
+	Operation string
+	// The state of the repository before and after the operation.
+	Before RepoSnapshot
+	After  RepoSnapshot
+}
@@ -0,0 +1,223 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
+	git "github.com/libgit2/git2go/v34"
+)
+
+type deleteRunner struct {
+	*rebaseTreeRunner
+	// Name of the branch being deleted.
+	branchName string
+	// If true, the children keep the commits of the deleted branch.
+	keepCommits bool
+	// Branch HEAD pointed to before the delete started, or its commit if HEAD
+	// was detached (see `headString()`).
+	headBranch string
+}
+
+// -------------------------------------------------------------------------- \
+// Delete                                                                     |
+// -------------------------------------------------------------------------- /
+
+// Delete branch `branchName` and remove it from the tree.
+//
+// The children of the branch are rebased onto the branch's parent, dropping the
+// commits unique to the deleted branch. If `keepCommits` is true, the children
+// are only reparented and keep those commits.
+//
+// Under the hood, the children are moved using a RebaseTree operation.
+func Delete(repo *git.Repository, branchName string, keepCommits bool) RebaseTreeResult {
+	// Read the branch map file.
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	if err := validateDelete(repo, branchName, keepCommits, branchMap); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	runner := newDeleteRunner(repo, branchMap, branchName, keepCommits, headString(repo))
+	runner.head = runner.headBranch
+	return runner.Execute()
+}
+
+// -------------------------------------------------------------------------- \
+// DeleteContinue                                                             |
+// -------------------------------------------------------------------------- /
+
+func DeleteContinue(repo *git.Repository) RebaseTreeResult {
+	// Try finishing the in-progress rebase.
+	rebaseResult := continueExistingRebase(repo)
+	if rebaseResult.Type != RebaseTreeSuccess {
+		return rebaseResult
+	}
+
+	// Read the branch map file.
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	// Look up the branch being deleted and the original HEAD branch.
+	branchName := utils.ReadFile(store.DeletingPath(repo.Path()))
+	headBranch := utils.ReadFile(store.DeletingHeadPath(repo.Path()))
+
+	runner := newDeleteRunner(repo, branchMap, branchName, false, headBranch)
+
+	// Populate the runner with temporary branches from previous runs.
+	path := store.RebasingTempsPath(repo.Path())
+	runner.tempBranches = store.ReadTemporaryBranches(repo, path)
+
+	return runner.Execute()
+}
+
+// -------------------------------------------------------------------------- \
+// DeleteAbort                                                                |
+// -------------------------------------------------------------------------- /
+
+// Abort a Delete operation in progress.
+func DeleteAbort(repo *git.Repository) RebaseTreeResult {
+	if result := abortExistingRebase(repo); result.Type != RebaseTreeSuccess {
+		return result
+	}
+
+	headBranch := utils.ReadFile(store.DeletingHeadPath(repo.Path()))
+	deleteDeleteStorage(repo)
+	restoreHead(repo, headBranch)
+
+	return RebaseTreeResult{Type: RebaseTreeSuccess}
+}
+
+// validateDelete checks whether the Delete operation is valid, returning an
+// error if it is not.
+func validateDelete(repo *git.Repository, branchName string, keepCommits bool, branchMap *models.BranchMap) error {
+	if err := validateNoOperationInProgress(repo, "delete a branch"); err != nil {
+		return err
+	}
+	if err := validateNoGitRebaseInProgress(repo, "delete a branch"); err != nil {
+		return err
+	}
+
+	if branchName == gitutil.BranchName(branchMap.Root) {
+		return fmt.Errorf("Cannot delete the root branch %q", branchName)
+	}
+	if branchMap.FindParent(branchName) == nil {
+		return fmt.Errorf("Branch %q is not tracked by git-tree", branchName)
+	}
+
+	// Like git, refuse to delete a branch checked out in another worktree.
+	if worktree, ok := gitutil.WorktreesOfBranches(repo, branchName)[branchName]; ok {
+		return fmt.Errorf("Cannot delete branch %q, which is checked out in worktree %s", branchName, worktree.Path)
+	}
+
+	if keepCommits {
+		return nil
+	}
+	movedBranches := []string{}
+	for _, child := range branchMap.FindChildren(branchName) {
+		movedBranches = append(movedBranches, branchMap.ListSubtreeBranchNames(gitutil.BranchName(child))...)
+	}
+	return validateOtherWorktrees(repo, movedBranches)
+}
+
+func newDeleteRunner(repo *git.Repository, branchMap *models.BranchMap, branchName string, keepCommits bool, headBranch string) *deleteRunner {
+	return &deleteRunner{
+		rebaseTreeRunner: newRebaseTreeRunner(repo, nil, nil, branchMap),
+		branchName:       branchName,
+		keepCommits:      keepCommits,
+		headBranch:       headBranch,
+	}
+}
+
+func (r *deleteRunner) Execute() RebaseTreeResult {
+	branch := r.branchMap.FindBranch(r.branchName)
+	parent := r.branchMap.FindParent(r.branchName)
+	if branch == nil || parent == nil {
+		err := fmt.Errorf("Could not find branch %q of the in-progress delete", r.branchName)
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	if !r.keepCommits {
+		// Move each child (and its descendants) onto the parent, leaving behind
+		// the commits of the deleted branch.
+		for _, child := range r.branchMap.FindChildren(r.branchName) {
+			result := r.executeRecurse(branch, parent, child)
+			if result.Type == RebaseTreeMergeConflict {
+				if err := r.handleMergeConflict(); err != nil {
+					return persistError("delete", err)
+				}
+				return result
+			} else if result.Type == RebaseTreeError {
+				// Without a merge conflict to resolve, the delete cannot be
+				// continued or aborted, so undo what was moved so far.
+				if r.head != "" {
+					r.rollback()
+					result.Error = fmt.Errorf("%s. No branch was deleted", result.Error.Error())
+				}
+				return result
+			}
+		}
+	}
+
+	movedBranches := movedBranchNames(r.tempBranches)
+	if result := r.handleSuccess(branch, gitutil.BranchName(parent)); result.Type != RebaseTreeSuccess {
+		return result
+	}
+	if err := updateOtherWorktrees(r.repo, movedBranches); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+	return RebaseTreeResult{Type: RebaseTreeSuccess}
+}
+
+func (r *deleteRunner) handleMergeConflict() error {
+	// Create a file indicating a delete is in progress, containing the branch
+	// being deleted.
+	if err := utils.OverwriteFile(store.DeletingPath(r.repo.Path()), r.branchName); err != nil {
+		return err
+	}
+
+	// Store the original HEAD branch.
+	if err := utils.OverwriteFile(store.DeletingHeadPath(r.repo.Path()), r.headBranch); err != nil {
+		return err
+	}
+
+	return r.persistTempBranches()
+}
+
+func (r *deleteRunner) handleSuccess(branch *git.Branch, parentName string) RebaseTreeResult {
+	headBranch := r.rebasedHead(r.headBranch)
+	deleteTemporaryBranches(r.tempBranches)
+	deleteDeleteStorage(r.repo)
+
+	// Git refuses to delete the checked out branch, so switch to its parent.
+	if headBranch == r.branchName {
+		headBranch = parentName
+	}
+	restoreHead(r.repo, headBranch)
+
+	if err := branch.Delete(); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: fmt.Errorf("Could not delete branch: %s.", err.Error())}
+	}
+
+	r.branchMap.RemoveBranch(r.branchName)
+	if err := store.WriteBranchMap(r.branchMap, store.BranchMapPath(r.repo.Path())); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: fmt.Errorf("Could not write branch map: %s.", err.Error())}
+	}
+
+	return RebaseTreeResult{Type: RebaseTreeSuccess}
+}
+
+func deleteDeleteStorage(repo *git.Repository) {
+	deleteStorage(repo)
+
+	os.Remove(store.DeletingPath(repo.Path()))
+	os.Remove(store.DeletingHeadPath(repo.Path()))
+}
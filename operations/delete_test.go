@@ -0,0 +1,165 @@
+package operations
+
+import (
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/acamadeo/git-tree/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DeleteTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *DeleteTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *DeleteTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+// Initial:
+//
+//	master ─── treecko ─── grovyle ─── sceptile
+//
+// Result:
+//
+//	master ─── treecko ─── sceptile
+func (suite *DeleteTestSuite) TestDelete_DropsCommitsOfDeletedBranch() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.BranchWithCommit("sceptile")
+	Init(suite.repo.Repo)
+
+	gotResult := Delete(suite.repo.Repo, "grovyle", false)
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.Nil(suite.T(), suite.repo.LookupBranch("grovyle"))
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "sceptile"))
+
+	// HEAD is back at `sceptile`, which no longer contains grovyle's commit.
+	assert.True(suite.T(), suite.repo.FileExists("sceptile"))
+	assert.False(suite.T(), suite.repo.FileExists("grovyle"))
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["treecko"]},
+    {"branch": "treecko", "children": ["sceptile"]}
+  ]
+}`
+	assert.Equal(suite.T(), wantString, gotString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+}
+
+// Initial:
+//
+//	master ─── treecko ─── grovyle ─── sceptile
+//
+// Result:
+//
+//	master ─── treecko ─── sceptile (with grovyle's commit)
+func (suite *DeleteTestSuite) TestDelete_KeepCommits() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.BranchWithCommit("sceptile")
+	Init(suite.repo.Repo)
+	sceptileBefore := *suite.repo.LookupBranch("sceptile").Target()
+
+	gotResult := Delete(suite.repo.Repo, "grovyle", true)
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.Nil(suite.T(), suite.repo.LookupBranch("grovyle"))
+	assert.True(suite.T(), suite.repo.LookupBranch("sceptile").Target().Equal(&sceptileBefore))
+	assert.True(suite.T(), suite.repo.FileExists("grovyle"))
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["treecko"]},
+    {"branch": "treecko", "children": ["sceptile"]}
+  ]
+}`
+	assert.Equal(suite.T(), wantString, gotString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+}
+
+// Initial:
+//
+//	master ─── treecko ─── grovyle ─── sceptile
+//
+// Each branch writes conflicting contents to the same file.
+func (suite *DeleteTestSuite) TestDelete_MergeConflict_AbortRestoresBranches() {
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("favorite", "treecko", "treecko")
+	suite.repo.CreateAndSwitchBranch("grovyle")
+	suite.repo.WriteAndCommitFile("favorite", "grovyle", "grovyle")
+	suite.repo.CreateAndSwitchBranch("sceptile")
+	suite.repo.WriteAndCommitFile("favorite", "sceptile", "sceptile")
+	Init(suite.repo.Repo)
+	sceptileBefore := *suite.repo.LookupBranch("sceptile").Target()
+
+	gotResult := Delete(suite.repo.Repo, "grovyle", false)
+
+	assert.Equal(suite.T(), RebaseTreeMergeConflict, gotResult.Type)
+	assert.Equal(suite.T(), "grovyle", suite.repo.ReadFile(".git/tree/deleting"))
+
+	gotResult = DeleteAbort(suite.repo.Repo)
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.NotNil(suite.T(), suite.repo.LookupBranch("grovyle"))
+	assert.True(suite.T(), suite.repo.LookupBranch("sceptile").Target().Equal(&sceptileBefore))
+	assert.False(suite.T(), utils.FileExists(suite.repo.Repo.Path()+"tree/deleting"))
+}
+
+// Initial:
+//
+//	master ─── mew ─── treecko ─┬─ grovyle
+//	                            └─ mudkip
+//
+// mudkip writes back the contents of `favorite` from mew, so it becomes empty
+// once treecko is dropped and fails to rebase.
+func (suite *DeleteTestSuite) TestDelete_ErrorRollsBack() {
+	suite.repo.CreateAndSwitchBranch("mew")
+	suite.repo.WriteAndCommitFile("favorite", "mew", "mew")
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("favorite", "treecko", "treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("favorite", "mew", "mudkip")
+	Init(suite.repo.Repo)
+	grovyleBefore := *suite.repo.LookupBranch("grovyle").Target()
+	mudkipBefore := *suite.repo.LookupBranch("mudkip").Target()
+
+	gotResult := Delete(suite.repo.Repo, "treecko", false)
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.NotNil(suite.T(), suite.repo.LookupBranch("treecko"))
+	assert.True(suite.T(), suite.repo.LookupBranch("grovyle").Target().Equal(&grovyleBefore))
+	assert.True(suite.T(), suite.repo.LookupBranch("mudkip").Target().Equal(&mudkipBefore))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-grovyle"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-mudkip"))
+	assert.False(suite.T(), utils.FileExists(suite.repo.Repo.Path()+"tree/deleting"))
+
+	// HEAD is back where it was.
+	assert.Equal(suite.T(), "mudkip", gitutil.BranchName(gitutil.HeadBranch(suite.repo.Repo)))
+	assert.Equal(suite.T(), "mew", suite.repo.ReadFile("favorite"))
+}
+
+func TestDeleteTestSuite(t *testing.T) {
+	suite.Run(t, new(DeleteTestSuite))
+}
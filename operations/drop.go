@@ -3,9 +3,10 @@ package operations
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
+	gitutil "github.com/acamadeo/git-tree/git"
 	"github.com/acamadeo/git-tree/store"
-	"github.com/acamadeo/git-tree/utils"
 	git "github.com/libgit2/git2go/v34"
 )
 
@@ -13,53 +14,34 @@ import (
 func Drop(repo *git.Repository) error {
 	// Read the branch map file.
 	branchMapPath := store.BranchMapPath(repo.Path())
-	branchMap := store.ReadBranchMap(repo, branchMapPath)
+	branchMap, err := store.ReadBranchMap(repo, branchMapPath)
+	if err != nil {
+		return err
+	}
 
 	// Delete the root branch created by `git-tree init`.
 	if err := branchMap.Root.Delete(); err != nil {
 		return fmt.Errorf("Could not delete root branch: %s.", err.Error())
 	}
 
-	// Delete local git-tree storage (i.e. the branch map and obsolescence map
-	// files).
-	gitTreePath := store.GitTreeSubdirPath(repo.Path())
-	if err := os.RemoveAll(gitTreePath); err != nil {
-		return fmt.Errorf("Could not delete git-tree files: %s.", err.Error())
+	// Delete the hidden refs that keep obsolete commits reachable.
+	if err := store.DeleteObsolescenceRefs(repo); err != nil {
+		return fmt.Errorf("Could not delete obsolescence refs: %s.", err.Error())
 	}
 
-	// Remove `post-commit` and `post-rewrite` git-hooks for git-tree.
-	uninstallGitHooks(repo)
-
-	return nil
-}
-
-func uninstallGitHooks(repo *git.Repository) {
-	// `pre-rebase` hook
-	hookFile := repo.Path() + "hooks/pre-rebase"
-	hookImplFilename := repo.Path() + "hooks/git-tree-pre-rebase.sh"
-	uninstallGitHook(hookFile, hookImplFilename)
-
-	// `post-rewrite` hook
-	hookFile = repo.Path() + "hooks/post-rewrite"
-	hookImplFilename = repo.Path() + "hooks/git-tree-post-rewrite.sh"
-	uninstallGitHook(hookFile, hookImplFilename)
-
-	// `pre-commit` hook
-	hookFile = repo.Path() + "hooks/pre-commit"
-	hookImplFilename = repo.Path() + "hooks/git-tree-pre-commit.sh"
-	uninstallGitHook(hookFile, hookImplFilename)
-
-	// `post-commit` hook
-	hookFile = repo.Path() + "hooks/post-commit"
-	hookImplFilename = repo.Path() + "hooks/git-tree-post-commit.sh"
-	uninstallGitHook(hookFile, hookImplFilename)
-}
-
-func uninstallGitHook(hookFile string, hookImplFilename string) {
-	// Delete `.git/hooks/git-tree-post-{}.sh`.
-	os.Remove(hookImplFilename)
+	// Delete local git-tree storage (i.e. the branch map and obsolescence map
+	// files), along with the files of each linked worktree.
+	gitTreePaths := []string{store.GitTreeSubdirPath(repo.Path())}
+	worktreePaths, _ := filepath.Glob(filepath.Join(gitutil.CommonDir(repo.Path()), "worktrees", "*", store.GitTreeSubdir))
+	gitTreePaths = append(gitTreePaths, worktreePaths...)
+	for _, gitTreePath := range gitTreePaths {
+		if err := os.RemoveAll(gitTreePath); err != nil {
+			return fmt.Errorf("Could not delete git-tree files: %s.", err.Error())
+		}
+	}
 
-	// Delete call to `git-tree-post-{}.sh` in the `post-{}` hook.
-	scriptCall := fmt.Sprintf(`%s "$@"`, hookImplFilename)
-	utils.DeleteLineInFile(hookFile, scriptCall)
+	// Remove the git-hooks installed by git-tree. Hooks in a `core.hooksPath`
+	// directory are kept, since other repositories may share them (see
+	// `SharedHooksDir()`).
+	return uninstallHooksFrom(defaultHooksDir(repo))
 }
@@ -88,6 +88,21 @@ func (suite *DropTestSuite) TestDrop_RemovesGitHookImplementations() {
 		"Expected file %q to not exist, but it does", filename)
 }
 
+func (suite *DropTestSuite) TestDrop_KeepsHooksInCoreHooksPath() {
+	config, _ := suite.repo.Repo.Config()
+	config.SetString("core.hooksPath", "shared-hooks")
+	config.Free()
+	InstallHooks(suite.repo.Repo)
+
+	Drop(suite.repo.Repo)
+
+	hooksDir := suite.repo.Repo.Workdir() + "shared-hooks/"
+	scriptCall := hooksDir + `git-tree-pre-commit.sh "$@"`
+	assert.True(suite.T(), utils.FileContainsLine(hooksDir+"pre-commit", scriptCall),
+		"Expected file %q to contain line %q, but it does not", hooksDir+"pre-commit", scriptCall)
+	assert.True(suite.T(), utils.FileExists(hooksDir+"git-tree-pre-commit.sh"))
+}
+
 func TestDropTestSuite(t *testing.T) {
 	suite.Run(t, new(DropTestSuite))
 }
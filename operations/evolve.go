@@ -1,87 +1,332 @@
 package operations
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
 	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
 	git "github.com/libgit2/git2go/v34"
 )
 
 // NOTE TO SELF: Make sure there are enough comments to describe how the
 // algorithm is working in English!!
 
+type EvolveResultType int
+
+const (
+	EvolveError EvolveResultType = iota
+	EvolveMergeConflict
+	EvolveUnstagedChanges
+	EvolveSuccess
+)
+
+// The result of an Evolve operation.
+type EvolveResult struct {
+	// The type of result that occurred.
+	Type EvolveResultType
+	// The error returned by the operation, if any.
+	Error error
+}
+
+// Options for an Evolve operation.
+type EvolveOptions struct {
+	// If true, commits are rebased in memory and the working tree is only
+	// checked out once every branch was moved. The working tree is still used
+	// to resolve merge conflicts.
+	InMemory bool
+}
+
 type evolveRunner struct {
-	repoTree        *gitutil.RepoTree
-	obsChains       obsolescenceChains
+	repoTree  *gitutil.RepoTree
+	obsChains obsolescenceChains
+	// Branch HEAD pointed to before the evolve started, or its commit if HEAD
+	// was detached (see `headString()`).
 	headBranch      string
 	tempBranchNames []string
+	// Map from each step that was already performed (possibly in a previous
+	// run) to the rebased commit it produced.
+	rebased map[models.EvolveStep]git.Oid
+	// The step that got interrupted by a merge conflict, and the temporary
+	// branch it was rebasing.
+	pending       models.EvolveStep
+	pendingBranch string
+	// Map from each commit that was evolved in this run to its evolved
+	// version. Merge commits are only evolved once all their parents are.
+	evolved map[git.Oid]git.Oid
+	// Tracked branches to move once the operation succeeds. Branches are only
+	// moved at the end so that an interrupted evolve leaves them untouched.
+	branchMoves map[string]git.Oid
+	// The obsolescence chain currently being resolved, if any.
+	currentChain *obsolescenceChain
+	// If true, no branches or commits are created. The steps that would be
+	// performed are recorded in `plan` instead.
+	dryRun bool
+	plan   *EvolvePlan
+	// If true, commits are rebased in memory (see EvolveOptions).
+	inMemory bool
 }
 
+// -------------------------------------------------------------------------- \
+// Evolve                                                                     |
+// -------------------------------------------------------------------------- /
+
 // Reconcile any troubled commits within the repository.
-func Evolve(repoTree *gitutil.RepoTree) error {
-	obsmap := store.ReadObsolescenceMap(repoTree.Repo, store.ObsoleteMapPath(repoTree.Repo.Path()))
-	branchMap := store.ReadBranchMap(repoTree.Repo, store.BranchMapPath(repoTree.Repo.Path()))
-	runner := evolveRunner{
-		repoTree:   repoTree,
-		obsChains:  buildObsolescenceChains(repoTree.Repo, obsmap, branchMap),
-		headBranch: gitutil.BranchName(gitutil.HeadBranch(repoTree.Repo)),
+//
+// If a merge conflict is encountered, the operation stops and can be resumed
+// with EvolveContinue or rolled back with EvolveAbort.
+func Evolve(repo *git.Repository) EvolveResult {
+	return EvolveWithOptions(repo, EvolveOptions{})
+}
+
+// Reconcile any troubled commits within the repository, as configured by
+// `opts`.
+func EvolveWithOptions(repo *git.Repository, opts EvolveOptions) EvolveResult {
+	if err := validateEvolve(repo); err != nil {
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+
+	repoTree, err := evolveRepoTree(repo)
+	if err != nil {
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+	runner, err := newEvolveRunner(repoTree, headString(repo), map[models.EvolveStep]git.Oid{})
+	if err != nil {
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+	runner.inMemory = opts.InMemory
+	return runner.Execute()
+}
+
+// -------------------------------------------------------------------------- \
+// EvolveContinue                                                             |
+// -------------------------------------------------------------------------- /
+
+func EvolveContinue(repo *git.Repository) EvolveResult {
+	// Try finishing the in-progress rebase.
+	rebaseResult := continueExistingRebase(repo)
+	if rebaseResult.Type != RebaseTreeSuccess {
+		return evolveResultFromRebaseTree(rebaseResult)
+	}
+
+	progress := store.ReadEvolveProgress(store.EvolvingPath(repo.Path()))
+
+	// Record the commit produced by the interrupted step.
+	pendingBranch, err := repo.LookupBranch(progress.PendingBranch, git.BranchLocal)
+	if err != nil {
+		err := fmt.Errorf("Could not find branch %q of the in-progress evolve", progress.PendingBranch)
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+	progress.Rebased[progress.Pending] = *pendingBranch.Target()
+
+	// Start over with fresh temporary branches. Steps that were already
+	// performed are not repeated.
+	restoreHead(repo, progress.HeadBranch)
+	deleteTemporaryBranchesByName(repo, progress.TempBranches)
+
+	repoTree, err := evolveRepoTree(repo)
+	if err != nil {
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+	runner, err := newEvolveRunner(repoTree, progress.HeadBranch, progress.Rebased)
+	if err != nil {
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+	runner.inMemory = progress.InMemory
+	return runner.Execute()
+}
+
+// -------------------------------------------------------------------------- \
+// EvolveAbort                                                                |
+// -------------------------------------------------------------------------- /
+
+// Abort an Evolve operation in progress.
+//
+// Tracked branches are only moved once Evolve succeeds, so every branch is
+// left where it was before the operation started.
+func EvolveAbort(repo *git.Repository) EvolveResult {
+	progress := store.ReadEvolveProgress(store.EvolvingPath(repo.Path()))
+
+	if err := abortOpenRebase(repo); err != nil {
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+
+	// Switch back to the original HEAD branch before deleting the temporary
+	// branches, which the aborted rebase may have checked out.
+	restoreHead(repo, progress.HeadBranch)
+	deleteTemporaryBranchesByName(repo, progress.TempBranches)
+	os.Remove(store.EvolvingPath(repo.Path()))
+
+	return EvolveResult{Type: EvolveSuccess}
+}
+
+// validateEvolve checks whether the Evolve operation is valid, returning an
+// error if it is not.
+func validateEvolve(repo *git.Repository) error {
+	if utils.FileExists(store.EvolvingPath(repo.Path())) {
+		return errors.New("Cannot evolve while another evolve is in progress. Abort or continue the existing evolve")
 	}
-	return runner.Execute(gitutil.CommitByOid(repoTree.Repo, repoTree.Root))
+	if err := validateNoGitRebaseInProgress(repo, "evolve"); err != nil {
+		return err
+	}
+	return validateNoOperationInProgress(repo, "evolve")
+}
+
+// Returns a RepoTree of the commits in the branches tracked by git-tree.
+func evolveRepoTree(repo *git.Repository) (*gitutil.RepoTree, error) {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+	branches := gitutil.LookupBranches(repo, branchMap.ListBranchNames()...)
+	root := gitutil.MergeBaseOctopus_Branches(repo, branches...)
+	return gitutil.CreateRepoTree(repo, root, branches...), nil
+}
+
+// Returns an UnsupportedObsolescenceError if the obsolescence map holds an
+// action that cannot be evolved.
+func newEvolveRunner(repoTree *gitutil.RepoTree, headBranch string, rebased map[models.EvolveStep]git.Oid) (*evolveRunner, error) {
+	obsmap, err := store.ReadObsolescenceMap(repoTree.Repo, store.ObsoleteMapPath(repoTree.Repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+	return newEvolveRunnerFromMap(repoTree, obsmap, headBranch, rebased)
+}
+
+// Like newEvolveRunner, but evolves the actions of `obsmap` instead of those
+// of the stored obsolescence map.
+func newEvolveRunnerFromMap(repoTree *gitutil.RepoTree, obsmap *models.ObsolescenceMap, headBranch string, rebased map[models.EvolveStep]git.Oid) (*evolveRunner, error) {
+	branchMap, err := store.ReadBranchMap(repoTree.Repo, store.BranchMapPath(repoTree.Repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+	obsChains, err := buildObsolescenceChains(repoTree.Repo, obsmap, branchMap)
+	if err != nil {
+		return nil, err
+	}
+	return &evolveRunner{
+		repoTree:    repoTree,
+		obsChains:   obsChains,
+		headBranch:  headBranch,
+		rebased:     rebased,
+		evolved:     map[git.Oid]git.Oid{},
+		branchMoves: map[string]git.Oid{},
+	}, nil
 }
 
-func (r *evolveRunner) Execute(root *git.Commit) error {
-	// TODO: We may only need one temp branch. If the tree splits at some point,
-	// we need to be able to point the branch to the current commit. If we can't
-	// do that, we'll need to create more temp branches for every fork in the
-	// tree.
-	branchName := gitutil.UniqueBranchName(r.repoTree.Repo, "git-tree-evolve-head")
-	head, _ := r.repoTree.Repo.CreateBranch(branchName, root.Parent(0), false)
-	r.tempBranchNames = append(r.tempBranchNames, branchName)
-	defer r.cleanup()
+func (r *evolveRunner) Execute() EvolveResult {
+	root := gitutil.CommitByOid(r.repoTree.Repo, r.repoTree.Root)
+
+	result := r.executeRecurse(root, *root.Parent(0).Id())
+	if r.dryRun {
+		return result
+	}
+
+	if result.Type == EvolveMergeConflict {
+		if err := r.handleMergeConflict(); err != nil {
+			err = fmt.Errorf("Could not save the in-progress evolve: %s.", err.Error())
+			return EvolveResult{Type: EvolveError, Error: err}
+		}
+		return result
+	} else if result.Type != EvolveSuccess {
+		// Leave the tracked branches as they were before the operation started.
+		r.cleanup()
+		return result
+	}
+
+	// The evolved branches may be checked out in other worktrees. Check that
+	// they can be updated before moving any branch.
+	movedBranches := []string{}
+	for branchName := range r.branchMoves {
+		movedBranches = append(movedBranches, branchName)
+	}
+	if err := validateOtherWorktrees(r.repoTree.Repo, movedBranches); err != nil {
+		r.cleanup()
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+
+	if err := r.handleSuccess(); err != nil {
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
+	if err := updateOtherWorktrees(r.repoTree.Repo, movedBranches); err != nil {
+		return EvolveResult{Type: EvolveError, Error: err}
+	}
 
-	return r.executeRecurse(root, &head)
+	// The evolved commits are no longer reachable, so their obsolescences can
+	// be forgotten.
+	if _, err := gcObsolescenceMap(r.repoTree.Repo); err != nil {
+		return EvolveResult{Type: EvolveError, Error: fmt.Errorf("Branches were evolved, but the obsolescence map could not be cleaned up: %s", err.Error())}
+	}
+	return result
 }
 
 // Recursive evolve function, which is run on each commit in the `RepoTree`.
-func (r *evolveRunner) executeRecurse(commit *git.Commit, evolveHead **git.Branch) error {
+//
+// `evolveHead` is the commit that `commit` should be rebased onto.
+func (r *evolveRunner) executeRecurse(commit *git.Commit, evolveHead git.Oid) EvolveResult {
 	// Find the obsolescence chain, if any, where this commit got obsoleted.
 	obsChain := r.obsChains.FindChainWithObsoleteCommit(commit)
 
-	evolveOid := (*evolveHead).Target()
-	evolveCommit, _ := r.repoTree.Repo.LookupCommit(evolveOid)
-	fmt.Printf("executeRecurse(%s, %s)\n", gitutil.CommitShortHash(commit), gitutil.CommitShortHash(evolveCommit))
-
-	var obsoletedBranches []*git.Branch
+	var obsoletedBranches []string
 	var oneSidedStart *git.Oid
 	var oneSidedEnd *git.Oid
 	if obsChain != nil {
-		// The current commit is obsolete.
+		// The current commit is obsolete. Descendants of the chain are
+		// troubled because of it.
+		parentChain := r.currentChain
+		r.currentChain = obsChain
+		defer func() { r.currentChain = parentChain }()
 
 		// Resolve any obsolete commits in the obsolescence chain. `evolveHead`
-		// points to the last resolved commit of the chain.
-		r.resolveObsolescences(*obsChain, evolveHead)
+		// becomes the last resolved commit of the chain.
+		head, result := r.resolveObsolescences(*obsChain, evolveHead)
+		if result.Type != EvolveSuccess {
+			return result
+		}
+		evolveHead = head
 		obsoletedBranches = r.findBranchesInObsChain(*obsChain)
 
 		if len(obsChain.obsoleted) == 0 {
 			// This is a one-sided chain. Skip to the end of the obsoleter side.
 			oneSidedStart = obsChain.obsoleter[0].Id()
-			oneSidedEnd = (*evolveHead).Target()
+			oneSidedEnd = &evolveHead
 		} else {
 			// The obsolescence chain is resolved. Skip to final commit in the chain.
 			commit = obsChain.obsoleted[len(obsChain.obsoleted)-1]
+			r.evolved[*commit.Id()] = evolveHead
+		}
+	} else if commit.ParentCount() > 1 {
+		// A merge commit is reached once from each of its parents. It is
+		// recreated once every parent within the tree has been evolved.
+		parents, ready := r.evolvedParents(commit)
+		if !ready {
+			return EvolveResult{Type: EvolveSuccess}
 		}
+
+		head, result := r.recreateMerge(commit, parents)
+		if result.Type != EvolveSuccess {
+			return result
+		}
+		evolveHead = head
+		obsoletedBranches = r.findBranchesAtCommit(commit)
 	} else {
-		// Rebase the current commit onto `evolveHead`. `evolveHead` points to
-		// the last commit that was rebased.
-		// TODO: Handle errors!
-		rebaseCommit(r.repoTree.Repo, commit, evolveHead)
+		// Rebase the current commit onto `evolveHead`. `evolveHead` becomes the
+		// rebased commit.
+		head, result := r.rebaseCommit(commit, evolveHead)
+		if result.Type != EvolveSuccess {
+			return result
+		}
+		evolveHead = head
 		obsoletedBranches = r.findBranchesAtCommit(commit)
 	}
 
 	// Update any branches that pointed to the current commit (or its ultimate successor).
-	for _, branch := range obsoletedBranches {
-		gitutil.MoveBranchTarget(r.repoTree.Repo, &branch, (*evolveHead).Target())
+	for _, branchName := range obsoletedBranches {
+		r.branchMoves[branchName] = evolveHead
 	}
 
 	commitChildren := gitutil.NewCommitSet(r.repoTree.FindChildren(*commit.Id())...)
@@ -89,34 +334,26 @@ func (r *evolveRunner) executeRecurse(commit *git.Commit, evolveHead **git.Branc
 		commitChildren = commitChildren.Remove(*oneSidedStart)
 		commitChildren = commitChildren.AddAll(r.repoTree.FindChildren(*oneSidedEnd)...)
 	}
-	headTarget := (*evolveHead).Target()
 	for _, childOid := range commitChildren {
-		// Abort early if evolve failed for any children.
-		//
-		// TODO: Is this the error behavior we want??
+		// Stop early if evolve failed for any children.
 		child := gitutil.CommitByOid(r.repoTree.Repo, childOid)
-		if err := r.executeRecurse(child, evolveHead); err != nil {
-			return err
+		if result := r.executeRecurse(child, evolveHead); result.Type != EvolveSuccess {
+			return result
 		}
-		// Restore `evolveHead` to current commit before proceeding.
-		gitutil.MoveBranchTarget(r.repoTree.Repo, evolveHead, headTarget)
 	}
-	return nil
+	return EvolveResult{Type: EvolveSuccess}
 }
 
-// Resolve any obsolescences within the given chain.
+// Resolve any obsolescences within the given chain, rebasing the resolved
+// version of the chain onto `evolveHead`.
 //
-// Returns a list of commits in the resolved version of the chain.
-//
-// TODO: Consider whether this function should return an error!
-func (r *evolveRunner) resolveObsolescences(thisChain obsolescenceChain, evolveHead **git.Branch) {
-	evolveOid := (*evolveHead).Target()
-	evolveCommit, _ := r.repoTree.Repo.LookupCommit(evolveOid)
-	fmt.Printf("resolveObsolescences(<chain>, %s), <chain>:\n", gitutil.CommitShortHash(evolveCommit))
-	fmt.Println(thisChain.String())
-
+// Returns the last commit of the resolved version of the chain.
+func (r *evolveRunner) resolveObsolescences(thisChain obsolescenceChain, evolveHead git.Oid) (git.Oid, EvolveResult) {
 	// Start out by rebasing the root of the chain onto `evolveHead`.
-	rebaseCommit(r.repoTree.Repo, thisChain.root, evolveHead)
+	evolveHead, result := r.rebaseCommit(thisChain.root, evolveHead)
+	if result.Type != EvolveSuccess {
+		return evolveHead, result
+	}
 
 	// Go through each commit on the obsoleter side of the chain, checking if it
 	// has been obsoleted itself.
@@ -125,17 +362,24 @@ func (r *evolveRunner) resolveObsolescences(thisChain obsolescenceChain, evolveH
 		if obsChain := r.obsChains.FindChainWithObsoleteCommit(obsoleter); obsChain != nil {
 			// Resolve the obsolescences. Fast-forward past any obsolete commits
 			// in this chain and continue iterating.
-			r.resolveObsolescences(*obsChain, evolveHead)
+			evolveHead, result = r.resolveObsolescences(*obsChain, evolveHead)
+			if result.Type != EvolveSuccess {
+				return evolveHead, result
+			}
 			i = lastObsoleteIdx(i, thisChain, *obsChain)
 			continue
 		}
 
-		// This commit is not obsolete; add it to the head branch.
-		rebaseCommit(r.repoTree.Repo, obsoleter, evolveHead)
+		// This commit is not obsolete; add it on top of the resolved commits.
+		evolveHead, result = r.rebaseCommit(obsoleter, evolveHead)
+		if result.Type != EvolveSuccess {
+			return evolveHead, result
+		}
 	}
+	return evolveHead, EvolveResult{Type: EvolveSuccess}
 }
 
-func (r *evolveRunner) findBranchesInObsChain(thisChain obsolescenceChain) []*git.Branch {
+func (r *evolveRunner) findBranchesInObsChain(thisChain obsolescenceChain) []string {
 	// We assume branch pointers can only be on the final commit in the
 	// obsolescence chain.
 	lastCommit := thisChain.obsoleter[len(thisChain.obsoleter)-1]
@@ -146,24 +390,85 @@ func (r *evolveRunner) findBranchesInObsChain(thisChain obsolescenceChain) []*gi
 	return r.findBranchesAtCommit(lastCommit)
 }
 
-func (r *evolveRunner) findBranchesAtCommit(commit *git.Commit) []*git.Branch {
-	branches := []*git.Branch{}
-	for _, branchName := range r.repoTree.FindBranches(*commit.Id()) {
-		branch, _ := r.repoTree.Repo.LookupBranch(branchName, git.BranchLocal)
-		branches = append(branches, branch)
+func (r *evolveRunner) findBranchesAtCommit(commit *git.Commit) []string {
+	return r.repoTree.FindBranches(*commit.Id())
+}
+
+func (r *evolveRunner) handleMergeConflict() error {
+	progress := &models.EvolveProgress{
+		HeadBranch:    r.headBranch,
+		Rebased:       r.rebased,
+		Pending:       r.pending,
+		PendingBranch: r.pendingBranch,
+		TempBranches:  r.tempBranchNames,
+		InMemory:      r.inMemory,
+	}
+	return store.WriteEvolveProgress(progress, store.EvolvingPath(r.repoTree.Repo.Path()))
+}
+
+func (r *evolveRunner) handleSuccess() error {
+	// Move the tracked branches to their evolved commits.
+	if err := r.moveBranches(); err != nil {
+		r.cleanup()
+		return fmt.Errorf("%s. No branch was evolved", strings.TrimSuffix(err.Error(), "."))
+	}
+
+	// HEAD was never moved while rebasing in memory, so the branch it points
+	// to was moved without checking it out. Check out HEAD once, now that
+	// every branch is in place.
+	var err error
+	if r.inMemory {
+		if err = gitutil.UpdateWorkdir(r.repoTree.Repo); err != nil {
+			err = fmt.Errorf("Branches were evolved, but the working tree could not be updated: %s", err.Error())
+		}
+	}
+
+	// A detached HEAD follows its commit to the evolved version.
+	r.headBranch = followRewrites(r.repoTree.Repo, r.headBranch, r.evolved)
+	r.cleanup()
+	return err
+}
+
+// Point each tracked branch at its evolved commit. If a branch cannot be
+// moved, the branches already moved are put back.
+func (r *evolveRunner) moveBranches() error {
+	repo := r.repoTree.Repo
+	original := map[string]git.Oid{}
+	for branchName, target := range r.branchMoves {
+		branch, err := repo.LookupBranch(branchName, git.BranchLocal)
+		if err != nil {
+			r.restoreBranches(original)
+			return fmt.Errorf("Could not find branch %q: %s.", branchName, err.Error())
+		}
+		oldTarget := *branch.Target()
+		if _, err := branch.SetTarget(&target, "[git-tree] evolve"); err != nil {
+			r.restoreBranches(original)
+			return fmt.Errorf("Could not move branch %q: %s.", branchName, err.Error())
+		}
+		original[branchName] = oldTarget
+	}
+	return nil
+}
+
+// Point each branch in `original` back at its commit.
+func (r *evolveRunner) restoreBranches(original map[string]git.Oid) {
+	for branchName, target := range original {
+		branch, err := r.repoTree.Repo.LookupBranch(branchName, git.BranchLocal)
+		if err != nil {
+			continue
+		}
+		branch.SetTarget(&target, "[git-tree] restore branch after failed evolve")
 	}
-	return branches
 }
 
 func (r *evolveRunner) cleanup() {
 	// Switch back to the original HEAD branch.
-	gitutil.CheckoutBranchByName(r.repoTree.Repo, r.headBranch)
+	restoreHead(r.repoTree.Repo, r.headBranch)
 
 	// Remove temporary branches.
-	for _, branchName := range r.tempBranchNames {
-		branch, _ := r.repoTree.Repo.LookupBranch(branchName, git.BranchLocal)
-		branch.Delete()
-	}
+	deleteTemporaryBranchesByName(r.repoTree.Repo, r.tempBranchNames)
+
+	os.Remove(store.EvolvingPath(r.repoTree.Repo.Path()))
 }
 
 // Return the index of the last commit in `thisChain` that was obsoleted by
@@ -177,41 +482,176 @@ func lastObsoleteIdx(index int, thisChain, obsChain obsolescenceChain) int {
 	return index - 1
 }
 
-// Rebase the sequence of commits from `start` to `end` onto branch `onto`.
+// Returns the evolved version of each parent of merge commit `merge`, and
+// whether the merge is ready to be recreated.
 //
-// NOTE: `start` and `end` are inclusive.
-//
-// NOTE TO SELF: Afterwards, branch `onto` should point to the rebased commit.
+// Parents merged in from outside the tree are kept as they are. The merge is
+// not ready if a parent within the tree has not been evolved yet, or if the
+// merge was already recreated.
+func (r *evolveRunner) evolvedParents(merge *git.Commit) ([]git.Oid, bool) {
+	if _, ok := r.evolved[*merge.Id()]; ok {
+		return nil, false
+	}
 
-// NOTE TO SELF: We definitely need to rebase in case moving the commit over
-// results in merge conflicts!
-//
-// TODO: This should probably return an error...
-func rebaseCommit(repo *git.Repository, commit *git.Commit, onto **git.Branch) {
-	// DEBUG
-	ontoOid := (*onto).Target()
-	ontoCommit, _ := repo.LookupCommit(ontoOid)
-	fmt.Printf("Rebasing commit [%s] onto %s\n",
-		gitutil.CommitShortHash(commit),
-		gitutil.CommitShortHash(ontoCommit))
+	parents := gitutil.ParentIds(merge)
+	for i, parent := range parents {
+		if evolved, ok := r.evolved[parent]; ok {
+			parents[i] = evolved
+		} else if r.repoTree.IsDescendantOfRoot(parent) {
+			return nil, false
+		}
+	}
+	return parents, true
+}
+
+// Recreate merge commit `merge` on top of the evolved `parents`, returning the
+// recreated commit.
+func (r *evolveRunner) recreateMerge(merge *git.Commit, parents []git.Oid) (git.Oid, EvolveResult) {
+	step := models.EvolveStep{Commit: *merge.Id(), Onto: parents[0]}
+
+	// A merge whose parents did not change is left as it is.
+	unchanged := true
+	for i, parent := range gitutil.ParentIds(merge) {
+		unchanged = unchanged && parent.Equal(&parents[i])
+	}
+	if unchanged {
+		r.evolved[*merge.Id()] = *merge.Id()
+		return *merge.Id(), EvolveResult{Type: EvolveSuccess}
+	}
+
+	recreated, ok := r.rebased[step]
+	if !ok {
+		if r.dryRun {
+			recreated = r.plan.addStep(merge, parents[0], r.currentChain)
+		} else {
+			var err error
+			recreated, err = gitutil.RecreateCommit(r.repoTree.Repo, merge, parents)
+			if err != nil {
+				return parents[0], EvolveResult{Type: EvolveError, Error: err}
+			}
+		}
+		r.rebased[step] = recreated
+	}
+
+	r.evolved[*merge.Id()] = recreated
+	return recreated, EvolveResult{Type: EvolveSuccess}
+}
+
+// Rebase `commit` onto commit `onto`, returning the rebased commit.
+func (r *evolveRunner) rebaseCommit(commit *git.Commit, onto git.Oid) (git.Oid, EvolveResult) {
+	rebased, result := r.rebaseCommitOnce(commit, onto)
+	if result.Type == EvolveSuccess {
+		r.evolved[*commit.Id()] = rebased
+	}
+	return rebased, result
+}
+
+// Steps that were already performed (e.g., before the operation got
+// interrupted) are not repeated. In a dry run, the rebase is only recorded in
+// the plan.
+func (r *evolveRunner) rebaseCommitOnce(commit *git.Commit, onto git.Oid) (git.Oid, EvolveResult) {
+	repo := r.repoTree.Repo
+	step := models.EvolveStep{Commit: *commit.Id(), Onto: onto}
+
+	if rebased, ok := r.rebased[step]; ok {
+		return rebased, EvolveResult{Type: EvolveSuccess}
+	}
 
 	startParent := commit.Parent(0)
+	if r.dryRun {
+		// Rebasing a commit onto its own parent recreates the same commit, so
+		// it is left out of the plan.
+		if startParent.Id().Equal(&onto) {
+			return *commit.Id(), EvolveResult{Type: EvolveSuccess}
+		}
+		rebased := r.plan.addStep(commit, onto, r.currentChain)
+		r.rebased[step] = rebased
+		return rebased, EvolveResult{Type: EvolveSuccess}
+	}
+
+	// A commit that conflicts is rebased again below, so that the conflict
+	// shows up in the working tree.
+	if r.inMemory {
+		rebased, result := gitutil.RebaseInMemory(repo, *startParent.Id(), onto, *commit.Id(), gitutil.FlattenMerges)
+		if result.Type == gitutil.RebaseSuccess {
+			r.rebased[step] = rebased
+			return rebased, EvolveResult{Type: EvolveSuccess}
+		} else if result.Type == gitutil.RebaseError {
+			return onto, EvolveResult{Type: EvolveError, Error: fmt.Errorf("Error during rebase: %v", result.Error)}
+		}
+	}
+
+	ontoBranch := gitutil.CreateBranchAtCommit(
+		repo, gitutil.CommitByOid(repo, onto), "git-tree-evolve-head")
 	startParentBranch := gitutil.CreateBranchAtCommit(
 		repo, startParent, "git-tree-evolve-commit-start")
 	endBranch := gitutil.CreateBranchAtCommit(
 		repo, commit, "git-tree-evolve-commit-end")
-	defer func() {
-		gitutil.CheckoutBranch(repo, *onto)
-		startParentBranch.Delete()
-		endBranch.Delete()
-	}()
+	tempBranches := []*git.Branch{ontoBranch, startParentBranch, endBranch}
 
 	// Rebase commit `commit` onto branch `onto`.
-	result := gitutil.Rebase_UpdateOnto(
-		repo, startParentBranch, onto, &endBranch)
+	result := gitutil.Rebase(repo, startParentBranch, ontoBranch, &endBranch)
+
+	if result.Type == gitutil.RebaseMergeConflict {
+		// Keep the temporary branches around. The rebase resumes from them
+		// once the conflict is resolved.
+		r.pending = step
+		r.pendingBranch = gitutil.BranchName(endBranch)
+		for _, branch := range tempBranches {
+			r.tempBranchNames = append(r.tempBranchNames, gitutil.BranchName(branch))
+		}
+		return onto, EvolveResult{Type: EvolveMergeConflict}
+	}
 
-	// TODO: Actually handle the error!
 	if result.Type == gitutil.RebaseError {
-		fmt.Printf("Error during rebase: %v\n", result.Error)
+		abortOpenRebase(repo)
+	}
+
+	// Detach HEAD from the temporary branches so that they can be deleted.
+	rebased := *endBranch.Target()
+	gitutil.CheckoutCommit(repo, gitutil.CommitByOid(repo, rebased))
+	for _, branch := range tempBranches {
+		branch.Delete()
+	}
+
+	if result.Type != gitutil.RebaseSuccess {
+		return onto, EvolveResult{Type: EvolveError, Error: fmt.Errorf("Error during rebase: %v", result.Error)}
+	}
+
+	r.rebased[step] = rebased
+	return rebased, EvolveResult{Type: EvolveSuccess}
+}
+
+func evolveResultFromRebaseTree(result RebaseTreeResult) EvolveResult {
+	switch result.Type {
+	case RebaseTreeMergeConflict:
+		return EvolveResult{Type: EvolveMergeConflict}
+	case RebaseTreeUnstagedChanges:
+		return EvolveResult{Type: EvolveUnstagedChanges}
+	case RebaseTreeSuccess:
+		return EvolveResult{Type: EvolveSuccess}
+	default:
+		return EvolveResult{Type: EvolveError, Error: result.Error}
+	}
+}
+
+// Abort the libgit2 rebase in progress, if any.
+func abortOpenRebase(repo *git.Repository) error {
+	rebase, err := gitutil.OpenRebase(repo)
+	if err != nil {
+		// No rebase is in progress.
+		return nil
+	}
+	if err := rebase.Abort(); err != nil {
+		return fmt.Errorf("Error aborting rebase: %v", err)
+	}
+	return nil
+}
+
+func deleteTemporaryBranchesByName(repo *git.Repository, branchNames []string) {
+	for _, branchName := range branchNames {
+		if branch, err := repo.LookupBranch(branchName, git.BranchLocal); err == nil {
+			branch.Delete()
+		}
 	}
 }
@@ -0,0 +1,168 @@
+package operations
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"sort"
+	"strings"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// The steps an Evolve operation would perform, computed without creating any
+// branches or commits.
+type EvolvePlan struct {
+	repo *git.Repository
+	// Commits that would be rebased, in the order they would be rebased.
+	Steps []EvolvePlanStep
+	// Tracked branches that would be moved, sorted by branch name.
+	Moves []EvolvePlanMove
+	// Map from each planned commit to the commit it is a rebased version of.
+	// Planned commits do not exist yet, so they are identified by placeholder
+	// Oid's.
+	planned map[git.Oid]*git.Commit
+}
+
+// A commit that would be rebased by Evolve.
+type EvolvePlanStep struct {
+	Commit *git.Commit
+	// The commit it would be rebased onto. May be a planned commit.
+	Onto git.Oid
+	// The obsolescence chain that makes the commit troubled, if any.
+	Chain *obsolescenceChain
+}
+
+// A tracked branch that would be moved by Evolve.
+type EvolvePlanMove struct {
+	Branch string
+	From   git.Oid
+	// May be a planned commit.
+	To git.Oid
+}
+
+// -------------------------------------------------------------------------- \
+// EvolveDryRun                                                               |
+// -------------------------------------------------------------------------- /
+
+// Compute the steps that Evolve would perform, without rewriting anything.
+func EvolveDryRun(repo *git.Repository) (*EvolvePlan, error) {
+	if err := validateEvolve(repo); err != nil {
+		return nil, err
+	}
+
+	plan := &EvolvePlan{repo: repo, planned: map[git.Oid]*git.Commit{}}
+
+	repoTree, err := evolveRepoTree(repo)
+	if err != nil {
+		return nil, err
+	}
+	// Evolve records the rewrites that bypassed the git-hooks before evolving,
+	// so plan as if they were recorded, without recording them.
+	obsmap, err := store.ReadObsolescenceMap(repo, store.ObsoleteMapPath(repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+	scanned, _, err := scanReflogs(repo)
+	if err != nil {
+		return nil, err
+	}
+	obsmap.Actions = append(obsmap.Actions, scanned...)
+
+	runner, err := newEvolveRunnerFromMap(repoTree, obsmap, headString(repo), map[models.EvolveStep]git.Oid{})
+	if err != nil {
+		return nil, err
+	}
+	runner.dryRun = true
+	runner.plan = plan
+
+	if result := runner.Execute(); result.Type != EvolveSuccess {
+		return nil, result.Error
+	}
+
+	for branchName, target := range runner.branchMoves {
+		branch, _ := repo.LookupBranch(branchName, git.BranchLocal)
+		if branch == nil || branch.Target().Equal(&target) {
+			continue
+		}
+		plan.Moves = append(plan.Moves, EvolvePlanMove{Branch: branchName, From: *branch.Target(), To: target})
+	}
+	sort.Slice(plan.Moves, func(i, j int) bool {
+		return plan.Moves[i].Branch < plan.Moves[j].Branch
+	})
+
+	return plan, nil
+}
+
+// Record that `commit` would be rebased onto `onto`, returning the placeholder
+// Oid of the planned commit.
+func (p *EvolvePlan) addStep(commit *git.Commit, onto git.Oid, chain *obsolescenceChain) git.Oid {
+	planned := git.Oid(sha1.Sum(append(commit.Id()[:], onto[:]...)))
+	p.planned[planned] = commit
+	p.Steps = append(p.Steps, EvolvePlanStep{Commit: commit, Onto: onto, Chain: chain})
+	return planned
+}
+
+// Render the plan.
+//
+// Planned commits are shown as the hash of the commit they are a rebased
+// version of, followed by a `'`.
+//
+// Example:
+//
+//	rebase 156720b Add torchic.txt
+//	    onto 64f5fcb' Add grovyle.txt
+//	    chain 68f0d35 → 64f5fcb
+//	move branch-2 156720b → 156720b'
+func (p *EvolvePlan) String() string {
+	if len(p.Steps) == 0 && len(p.Moves) == 0 {
+		return "Nothing to evolve."
+	}
+
+	output := []string{}
+	for _, step := range p.Steps {
+		output = append(output, fmt.Sprintf("rebase %s %s", gitutil.CommitShortHash(step.Commit), step.Commit.Summary()))
+		output = append(output, fmt.Sprintf("    onto %s", p.describeCommit(step.Onto)))
+		if step.Chain != nil {
+			output = append(output, fmt.Sprintf("    chain %s", describeChain(step.Chain)))
+		}
+	}
+	for _, move := range p.Moves {
+		output = append(output, fmt.Sprintf("move %s %s → %s", move.Branch, p.shortHash(move.From), p.shortHash(move.To)))
+	}
+	return strings.Join(output, "\n")
+}
+
+func (p *EvolvePlan) shortHash(oid git.Oid) string {
+	if original, ok := p.planned[oid]; ok {
+		return gitutil.CommitShortHash(original) + "'"
+	}
+	return gitutil.OidShortHash(oid)
+}
+
+func (p *EvolvePlan) describeCommit(oid git.Oid) string {
+	commit, ok := p.planned[oid]
+	if !ok {
+		commit = gitutil.CommitByOid(p.repo, oid)
+	}
+	return fmt.Sprintf("%s %s", p.shortHash(oid), commit.Summary())
+}
+
+// Describe the obsolescence chain as `<obsoleted commits> → <obsoleter commits>`.
+func describeChain(chain *obsolescenceChain) string {
+	return fmt.Sprintf("%s → %s", shortHashes(chain.obsoleted), shortHashes(chain.obsoleter))
+}
+
+func shortHashes(commits []*git.Commit) string {
+	if len(commits) == 0 {
+		return "<nil>"
+	}
+
+	hashes := []string{}
+	for _, commit := range commits {
+		hashes = append(hashes, gitutil.CommitShortHash(commit))
+	}
+	return strings.Join(hashes, " ")
+}
@@ -0,0 +1,117 @@
+package operations
+
+import (
+	"fmt"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EvolvePlanTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *EvolvePlanTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *EvolvePlanTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+func (suite *EvolvePlanTestSuite) shortHash(branchName string) string {
+	return gitutil.OidShortHash(*suite.repo.LookupBranch(branchName).Target())
+}
+
+// Branches:
+//
+//	master ─── treecko
+func (suite *EvolvePlanTestSuite) TestEvolveDryRun_NothingToEvolve() {
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	plan, err := EvolveDryRun(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Nothing to evolve.", plan.String())
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko]
+func (suite *EvolvePlanTestSuite) TestEvolveDryRun_DoesNotRewriteAnything() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	oldTreecko := suite.shortHash("treecko")
+	amendHead(&suite.repo, "treecko amended")
+
+	grovyle := suite.shortHash("grovyle")
+	branchesBefore := gitutil.AllLocalBranches(suite.repo.Repo)
+
+	plan, err := EvolveDryRun(suite.repo.Repo)
+
+	wantString := fmt.Sprintf(
+		`rebase %s grovyle
+    onto %s treecko amended
+    chain %s → %s
+move grovyle %s → %s'`,
+		grovyle, suite.shortHash("treecko"), oldTreecko, suite.shortHash("treecko"), grovyle, grovyle)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, plan.String())
+
+	// No branches were created or moved.
+	assert.Equal(suite.T(), len(branchesBefore), len(gitutil.AllLocalBranches(suite.repo.Repo)))
+	assert.Equal(suite.T(), grovyle, suite.shortHash("grovyle"))
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko] without the git-hooks
+func (suite *EvolvePlanTestSuite) TestEvolveDryRun_IncludesUnrecordedRewrites() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	oldTreecko := suite.shortHash("treecko")
+	suite.repo.AmendCommit("treecko amended")
+
+	grovyle := suite.shortHash("grovyle")
+	obsmapFile := store.ObsoleteMapPath(suite.repo.Repo.Path())
+	obsmapBefore, _ := store.ReadObsolescenceMap(suite.repo.Repo, obsmapFile)
+
+	plan, err := EvolveDryRun(suite.repo.Repo)
+
+	wantString := fmt.Sprintf(
+		`rebase %s grovyle
+    onto %s treecko amended
+    chain %s → %s
+move grovyle %s → %s'`,
+		grovyle, suite.shortHash("treecko"), oldTreecko, suite.shortHash("treecko"), grovyle, grovyle)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, plan.String())
+
+	// The rewrite was not recorded.
+	obsmapAfter, _ := store.ReadObsolescenceMap(suite.repo.Repo, obsmapFile)
+	assert.Equal(suite.T(), len(obsmapBefore.Actions), len(obsmapAfter.Actions))
+}
+
+func TestEvolvePlanTestSuite(t *testing.T) {
+	suite.Run(t, new(EvolvePlanTestSuite))
+}
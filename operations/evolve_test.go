@@ -0,0 +1,334 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EvolveTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *EvolveTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *EvolveTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko]
+//   - Detach HEAD at [grovyle]
+func (suite *EvolveTestSuite) TestEvolve_DetachedHeadFollowsEvolvedCommit() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	amendHead(&suite.repo, "treecko amended")
+	suite.repo.SwitchBranch("grovyle")
+	suite.repo.Repo.SetHeadDetached(suite.repo.LookupBranch("grovyle").Target())
+
+	result := Evolve(suite.repo.Repo)
+
+	head, _ := suite.repo.Repo.Head()
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.False(suite.T(), head.IsBranch())
+	assert.Equal(suite.T(), *suite.repo.LookupBranch("grovyle").Target(), *head.Target())
+}
+
+// Branches:
+//
+//	master ─── treecko ─┬─ grovyle
+//	                    └─ mudkip
+//
+// Action:
+//   - Amend [treecko]
+func (suite *EvolveTestSuite) TestEvolve_RebasesSiblingsOntoSameCommit() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	amendHead(&suite.repo, "treecko amended")
+
+	result := Evolve(suite.repo.Repo)
+
+	treecko := *suite.repo.LookupBranch("treecko").Target()
+	grovyle := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("grovyle").Target())
+	mudkip := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("mudkip").Target())
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.Equal(suite.T(), treecko, *grovyle.ParentId(0))
+	assert.Equal(suite.T(), treecko, *mudkip.ParentId(0))
+}
+
+// Branches:
+//
+//	master ─┬─ treecko ─── grovyle
+//	        └─ mudkip
+//
+// Action:
+//   - Amend [treecko]
+func (suite *EvolveTestSuite) TestEvolve_KeepsUntroubledCommits() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+	mudkip := *suite.repo.LookupBranch("mudkip").Target()
+
+	suite.repo.SwitchBranch("treecko")
+	amendHead(&suite.repo, "treecko amended")
+
+	result := Evolve(suite.repo.Repo)
+
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "grovyle"))
+	assert.Equal(suite.T(), mudkip, *suite.repo.LookupBranch("mudkip").Target())
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko]
+//   - Switch to [grovyle]
+func (suite *EvolveTestSuite) TestEvolve_InMemory() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.WriteFile("treecko", "treecko amended")
+	suite.repo.StageFiles()
+	amendHead(&suite.repo, "treecko amended")
+	suite.repo.SwitchBranch("grovyle")
+
+	result := EvolveWithOptions(suite.repo.Repo, EvolveOptions{InMemory: true})
+
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "grovyle"))
+	assert.Equal(suite.T(), "grovyle", gitutil.BranchName(gitutil.HeadBranch(suite.repo.Repo)))
+	assert.Equal(suite.T(), "treecko amended", suite.repo.ReadFile("treecko"))
+	dirty, err := gitutil.HasUncommittedChanges(suite.repo.Repo)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), dirty)
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko]
+//   - Detach HEAD at the obsolete [treecko]
+func (suite *EvolveTestSuite) TestEvolve_DetachedHeadFollowsSuccessor() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	obsolete := *suite.repo.LookupBranch("treecko").Target()
+	amendHead(&suite.repo, "treecko amended")
+	suite.repo.Repo.SetHeadDetached(&obsolete)
+
+	result := Evolve(suite.repo.Repo)
+
+	head, _ := suite.repo.Repo.Head()
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.False(suite.T(), head.IsBranch())
+	assert.Equal(suite.T(), *suite.repo.LookupBranch("treecko").Target(), *head.Target())
+}
+
+// Branches:
+//
+//	master ─┬─ mew ─── treecko ─── (merge eevee) ─── grovyle
+//	        └─ eevee ──────────────┘
+//
+// Action:
+//   - Amend [treecko]
+func (suite *EvolveTestSuite) TestEvolve_RecreatesMergeCommits() {
+	suite.repo.BranchWithCommit("eevee")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.CreateAndSwitchBranch("grovyle")
+	suite.repo.MergeBranch("eevee", "merge eevee")
+	suite.repo.WriteAndCommitFile("grovyle", "grovyle", "grovyle")
+
+	// The teammate's branch (eevee) is not tracked.
+	mew := suite.repo.LookupBranch("mew")
+	treecko := suite.repo.LookupBranch("treecko")
+	grovyle := suite.repo.LookupBranch("grovyle")
+	Init(suite.repo.Repo, mew, treecko, grovyle)
+
+	suite.repo.SwitchBranch("treecko")
+	amendHead(&suite.repo, "treecko amended")
+
+	result := Evolve(suite.repo.Repo)
+
+	grovyleTip := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("grovyle").Target())
+	merge := grovyleTip.Parent(0)
+
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.Equal(suite.T(), "merge eevee", merge.Message())
+	assert.Equal(suite.T(), uint(2), merge.ParentCount())
+	assert.Equal(suite.T(), *suite.repo.LookupBranch("treecko").Target(), *merge.ParentId(0))
+	assert.Equal(suite.T(), *suite.repo.LookupBranch("eevee").Target(), *merge.ParentId(1))
+}
+
+// Branches:
+//
+//	master ─┬─ treecko
+//	        ├─ mudkip
+//	        └─ torchic
+//
+// Action:
+//   - Amend [treecko] into both [mudkip] and [torchic]
+func (suite *EvolveTestSuite) TestEvolve_UnsupportedObsolescenceAction() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mudkip")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("torchic")
+	Init(suite.repo.Repo)
+
+	treecko := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("treecko").Target())
+	mudkip := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("mudkip").Target())
+	torchic := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("torchic").Target())
+	obsmap := &models.ObsolescenceMap{Actions: []models.ObsolescenceAction{{
+		ActionType: models.ActionTypeAmend,
+		Entries: []models.ObsolescenceEntry{
+			{Commit: treecko, Obsoleter: mudkip, HookType: models.PostRewriteAmend},
+			{Commit: treecko, Obsoleter: torchic, HookType: models.PostRewriteAmend},
+		},
+	}}}
+	store.WriteObsolescenceMap(obsmap, store.ObsoleteMapPath(suite.repo.Repo.Path()))
+	branchesBefore := suite.repo.ReadFile(".git/tree/branches")
+
+	result := Evolve(suite.repo.Repo)
+
+	var unsupported *UnsupportedObsolescenceError
+	assert.Equal(suite.T(), EvolveError, result.Type)
+	assert.True(suite.T(), errors.As(result.Error, &unsupported))
+	assert.Equal(suite.T(), 0, unsupported.ActionIndex)
+	assert.Equal(suite.T(), models.ActionTypeAmend, unsupported.ActionType)
+	assert.Contains(suite.T(), unsupported.Commits, *treecko.Id())
+	assert.Equal(suite.T(), *treecko.Id(), *suite.repo.LookupBranch("treecko").Target())
+	assert.Equal(suite.T(), branchesBefore, suite.repo.ReadFile(".git/tree/branches"))
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/evolving"))
+}
+
+// Branches:
+//
+//	master ─── mew ─── treecko
+//
+// Action:
+//   - Amend [mew], after an earlier action whose commits were garbage-collected
+func (suite *EvolveTestSuite) TestEvolve_SkipsEntriesOfMissingCommits() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("mew")
+	oldMew := suite.repo.LookupBranch("mew").Target().String()
+	amendHead(&suite.repo, "mew amended")
+	newMew := suite.repo.LookupBranch("mew").Target().String()
+
+	suite.repo.WriteFile(".git/tree/obsmap", fmt.Sprintf(`{
+  "version": 2,
+  "actions": [
+    {
+      "type": "amend",
+      "entries": [
+        {"commit": "1111111111111111111111111111111111111111", "obsoleter": "2222222222222222222222222222222222222222", "hook": "post-rewrite.amend"}
+      ]
+    },
+    {
+      "type": "amend",
+      "entries": [
+        {"commit": "%s", "obsoleter": "%s", "hook": "post-rewrite.amend"}
+      ]
+    }
+  ]
+}`, oldMew, newMew))
+
+	result := Evolve(suite.repo.Repo)
+
+	treecko := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("treecko").Target())
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.Equal(suite.T(), newMew, treecko.ParentId(0).String())
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko]
+//   - Another git-tree process holds the lock on the obsolescence map
+func (suite *EvolveTestSuite) TestEvolve_ReportsObsolescenceMapCleanupError() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	amendHead(&suite.repo, "treecko amended")
+
+	lock, err := store.AcquireLock(suite.repo.Repo.Path())
+	assert.Nil(suite.T(), err)
+	defer lock.Release()
+
+	result := Evolve(suite.repo.Repo)
+
+	assert.Equal(suite.T(), EvolveError, result.Type)
+	assert.ErrorContains(suite.T(), result.Error, "obsolescence map could not be cleaned up")
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "grovyle"))
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko]
+//   - Another process holds the lock on branch grovyle
+func (suite *EvolveTestSuite) TestEvolve_ReportsBranchMoveError() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	amendHead(&suite.repo, "treecko amended")
+	grovyleBefore := *suite.repo.LookupBranch("grovyle").Target()
+
+	suite.repo.WriteFile(".git/refs/heads/grovyle.lock", "")
+
+	result := Evolve(suite.repo.Repo)
+
+	assert.Equal(suite.T(), EvolveError, result.Type)
+	assert.ErrorContains(suite.T(), result.Error, "No branch was evolved")
+	assert.Equal(suite.T(), grovyleBefore, *suite.repo.LookupBranch("grovyle").Target())
+	assert.Equal(suite.T(), "treecko", headString(suite.repo.Repo))
+}
+
+func TestEvolveTestSuite(t *testing.T) {
+	suite.Run(t, new(EvolveTestSuite))
+}
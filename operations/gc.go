@@ -0,0 +1,104 @@
+package operations
+
+import (
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// The result of a GC operation.
+type GCResult struct {
+	// Number of obsolescence actions that were removed.
+	RemovedActions int
+	// Number of obsolescence entries that were removed (including the entries
+	// of removed actions).
+	RemovedEntries int
+}
+
+// Prune the obsolescence map of everything that no longer affects a tracked
+// commit.
+//
+// An action is removed once none of its obsoleted commits are reachable from
+// the tracked branches (e.g., because they were evolved). Entries that refer to
+// commits that no longer exist are removed as well.
+func GC(repo *git.Repository) (GCResult, error) {
+	if err := validateNoOperationInProgress(repo, "gc"); err != nil {
+		return GCResult{}, err
+	}
+	return gcObsolescenceMap(repo)
+}
+
+func gcObsolescenceMap(repo *git.Repository) (GCResult, error) {
+	obsmapFile := store.ObsoleteMapPath(repo.Path())
+	if !utils.FileExists(obsmapFile) {
+		return GCResult{}, nil
+	}
+
+	tracked, err := trackedCommitOids(repo)
+	if err != nil {
+		return GCResult{}, err
+	}
+
+	result := GCResult{}
+	err = store.UpdateObsolescenceMap(repo, obsmapFile, func(obsmap *models.ObsolescenceMap) error {
+		kept := []models.ObsolescenceAction{}
+		for i, action := range obsmap.Actions {
+			entries := existingEntries(action.Entries)
+			result.RemovedEntries += len(action.Entries) - len(entries)
+			action.Entries = entries
+
+			// The last action may still be waiting for the entries of a commit
+			// in progress (added by the `post-commit` hook).
+			isLast := i == len(obsmap.Actions)-1
+			if (isLast && len(entries) == 0) || affectsTrackedCommit(action, tracked) {
+				kept = append(kept, action)
+				continue
+			}
+
+			result.RemovedActions++
+			result.RemovedEntries += len(entries)
+		}
+		obsmap.Actions = kept
+		return nil
+	})
+	return result, err
+}
+
+// Returns the entries whose commits both still exist.
+func existingEntries(entries []models.ObsolescenceEntry) []models.ObsolescenceEntry {
+	existing := []models.ObsolescenceEntry{}
+	for _, entry := range entries {
+		if entry.Commit != nil && entry.Obsoleter != nil {
+			existing = append(existing, entry)
+		}
+	}
+	return existing
+}
+
+// Returns true if any commit obsoleted by `action` is in `tracked`.
+func affectsTrackedCommit(action models.ObsolescenceAction, tracked map[git.Oid]bool) bool {
+	for _, entry := range action.Entries {
+		if tracked[*entry.Commit.Id()] {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the Oid's of the commits in the branches tracked by git-tree.
+func trackedCommitOids(repo *git.Repository) (map[git.Oid]bool, error) {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+	branches := gitutil.LookupBranches(repo, branchMap.ListBranchNames()...)
+	root := gitutil.MergeBaseOctopus_Branches(repo, branches...)
+
+	oids := map[git.Oid]bool{}
+	for _, commit := range gitutil.LocalCommitsFromBranches_RootOid(repo, root, branches...) {
+		oids[*commit.Id()] = true
+	}
+	return oids, nil
+}
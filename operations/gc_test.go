@@ -0,0 +1,111 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type GCTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *GCTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *GCTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+func (suite *GCTestSuite) obsmapActions() int {
+	obsmap, err := store.ReadObsolescenceMap(suite.repo.Repo, store.ObsoleteMapPath(suite.repo.Repo.Path()))
+	assert.Nil(suite.T(), err)
+	return len(obsmap.Actions)
+}
+
+// Initial:
+//
+//	master ─── mew ─── treecko
+//
+// Action:
+//   - Amend [mew]
+func (suite *GCTestSuite) setupAmendedTree() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("mew")
+	amendHead(&suite.repo, "mew amended")
+}
+
+func (suite *GCTestSuite) TestGC_KeepsActionsAffectingTrackedCommits() {
+	suite.setupAmendedTree()
+
+	result, err := GC(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), GCResult{}, result)
+	assert.Equal(suite.T(), 1, suite.obsmapActions())
+}
+
+func (suite *GCTestSuite) TestGC_RunsAfterEvolve() {
+	suite.setupAmendedTree()
+
+	result := Evolve(suite.repo.Repo)
+
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.Equal(suite.T(), 0, suite.obsmapActions())
+}
+
+func (suite *GCTestSuite) TestGC_RemovesEntriesOfMissingCommits() {
+	suite.repo.BranchWithCommit("mew")
+	Init(suite.repo.Repo)
+	suite.repo.WriteFile(".git/tree/obsmap", `{
+  "version": 2,
+  "actions": [
+    {
+      "type": "amend",
+      "entries": [
+        {"commit": "1111111111111111111111111111111111111111", "obsoleter": "2222222222222222222222222222222222222222", "hook": "post-rewrite.amend"}
+      ]
+    },
+    {"type": "commit", "entries": []}
+  ]
+}`)
+
+	result, err := GC(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), GCResult{RemovedActions: 1, RemovedEntries: 1}, result)
+
+	// The last action is kept, as a commit may be in progress.
+	assert.Equal(suite.T(), 1, suite.obsmapActions())
+}
+
+func (suite *GCTestSuite) TestGC_DeletesRefsOfRemovedActions() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("mew")
+	oldMew := suite.repo.LookupBranch("mew").Target()
+	amendHead(&suite.repo, "mew amended")
+
+	_, err := suite.repo.Repo.References.Lookup(store.ObsolescenceRefName(oldMew))
+	assert.Nil(suite.T(), err)
+
+	// Evolving garbage-collects the amend action.
+	Evolve(suite.repo.Repo)
+
+	_, err = suite.repo.Repo.References.Lookup(store.ObsolescenceRefName(oldMew))
+	assert.NotNil(suite.T(), err)
+}
+
+func TestGCTestSuite(t *testing.T) {
+	suite.Run(t, new(GCTestSuite))
+}
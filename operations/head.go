@@ -0,0 +1,164 @@
+package operations
+
+import (
+	"fmt"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// Operations that move branches remember where HEAD was as a string: the name
+// of the branch HEAD points to, or the hash of the commit HEAD points to if it
+// is detached (e.g. while editing a commit during `git rebase -i`).
+//
+// Once the operation is done, HEAD is checked out again. A detached HEAD
+// follows its commit to the commit that rewrote it, so it is not left behind
+// on an obsolete commit.
+
+// Returns the name of the branch HEAD points to, or the commit HEAD points to
+// if it is detached.
+func headString(repo *git.Repository) string {
+	head, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+	if head.IsBranch() {
+		return gitutil.BranchName(head.Branch())
+	}
+	return head.Target().String()
+}
+
+// Returns the commit of `head` (see `headString()`) if HEAD is detached, or nil
+// if it points to a branch.
+func detachedHeadCommit(repo *git.Repository, head string) *git.Commit {
+	if head == "" {
+		return nil
+	}
+	if _, err := repo.LookupBranch(head, git.BranchLocal); err == nil {
+		return nil
+	}
+	oid, err := git.NewOid(head)
+	if err != nil {
+		return nil
+	}
+	commit, err := repo.LookupCommit(oid)
+	if err != nil {
+		return nil
+	}
+	return commit
+}
+
+// Check out the branch or commit described by `head` (see `headString()`).
+func checkoutHeadString(repo *git.Repository, head string) error {
+	if head == "" {
+		return nil
+	}
+	if branch, err := repo.LookupBranch(head, git.BranchLocal); err == nil {
+		return gitutil.CheckoutBranch(repo, branch)
+	}
+
+	oid, err := git.NewOid(head)
+	if err != nil {
+		return fmt.Errorf("Could not restore HEAD to %q", head)
+	}
+	commit, err := repo.LookupCommit(oid)
+	if err != nil {
+		return fmt.Errorf("Could not restore HEAD to %q", head)
+	}
+	commitTree, _ := commit.Tree()
+	if err := repo.CheckoutTree(commitTree, &git.CheckoutOptions{Strategy: git.CheckoutSafe}); err != nil {
+		return fmt.Errorf("Could not checkout tree: %s", err)
+	}
+	return gitutil.CheckoutCommit(repo, commit)
+}
+
+// Check out `head` (see `headString()`) again after an operation moved
+// branches, or left HEAD on one of its temporary branches.
+func restoreHead(repo *git.Repository, head string) {
+	checkoutHeadString(repo, head)
+}
+
+// Returns where a detached `head` should move to once the commits in
+// `rewritten` (a map from each commit to its new version) were rewritten: the
+// new version of its commit, or of the latest successor of its commit if it is
+// obsolete. Returns `head` unchanged if it points to a branch.
+func followRewrites(repo *git.Repository, head string, rewritten map[git.Oid]git.Oid) string {
+	commit := detachedHeadCommit(repo, head)
+	if commit == nil {
+		return head
+	}
+
+	// The operation already succeeded, so an unreadable obsolescence map only
+	// means that HEAD is not moved past the commits it rewrote.
+	successors, err := latestSuccessors(repo)
+	if err != nil {
+		successors = map[git.Oid]git.Oid{}
+	}
+	oid := *commit.Id()
+	visited := map[git.Oid]bool{}
+	for !visited[oid] {
+		visited[oid] = true
+		if next, ok := rewritten[oid]; ok {
+			oid = next
+		} else if next, ok := successors[oid]; ok {
+			oid = next
+		}
+	}
+	return oid.String()
+}
+
+// Returns a map from each obsolete commit to the commit that obsoleted it most
+// recently.
+func latestSuccessors(repo *git.Repository) (map[git.Oid]git.Oid, error) {
+	obsmap, err := store.ReadObsolescenceMap(repo, store.ObsoleteMapPath(repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+
+	successors := map[git.Oid]git.Oid{}
+	for _, action := range obsmap.Actions {
+		for _, entry := range action.Entries {
+			// Skip entries whose commits no longer exist.
+			if entry.Commit == nil || entry.Obsoleter == nil {
+				continue
+			}
+			successors[*entry.Commit.Id()] = *entry.Obsoleter.Id()
+		}
+	}
+	return successors, nil
+}
+
+// Returns a map from each commit that a rebase dropped from the branches in
+// `tempBranches` to its rebased copy, i.e. the commit of the rebased branch
+// with the same patch ID.
+//
+// The temporary branches still point to where their branches were before they
+// got rebased.
+func rebasedCommits(repo *git.Repository, tempBranches models.TempBranchMap) map[git.Oid]git.Oid {
+	rebased := map[git.Oid]git.Oid{}
+	for tempBranch, origBranch := range tempBranches {
+		// Look up the branch again, since it may have moved since `origBranch`
+		// was looked up.
+		branch, err := repo.LookupBranch(gitutil.BranchName(origBranch), git.BranchLocal)
+		if err != nil {
+			continue
+		}
+
+		copies := map[string]git.Oid{}
+		for _, commit := range gitutil.CommitsBetween(repo, tempBranch.Target(), branch.Target()) {
+			if patchId, ok := gitutil.PatchId(repo, commit); ok {
+				copies[patchId] = *commit.Id()
+			}
+		}
+		for _, commit := range gitutil.CommitsBetween(repo, branch.Target(), tempBranch.Target()) {
+			if patchId, ok := gitutil.PatchId(repo, commit); ok {
+				if rebasedOid, ok := copies[patchId]; ok {
+					rebased[*commit.Id()] = rebasedOid
+				}
+			}
+		}
+	}
+	return rebased
+}
@@ -0,0 +1,15 @@
+package operations
+
+import (
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/testutil"
+)
+
+// Simulate the git-hooks that fire when amending the commit at HEAD.
+func amendHead(repo *testutil.TestRepository, message string) {
+	oldOid := gitutil.HeadBranch(repo.Repo).Target().String()
+	ObsoletePreCommit(repo.Repo)
+	repo.AmendCommit(message)
+	newOid := gitutil.HeadBranch(repo.Repo).Target().String()
+	ObsoletePostRewriteAmend(repo.Repo, []string{oldOid + " " + newOid})
+}
@@ -0,0 +1,372 @@
+package operations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// A git-hook that git-tree installs, and the script (under `scripts/`) it runs.
+type gitTreeHook struct {
+	name   string
+	script string
+}
+
+var gitTreeHooks = []gitTreeHook{
+	{name: "pre-rebase", script: preRebaseFilename},
+	{name: "post-rewrite", script: postRewriteFilename},
+	{name: "pre-commit", script: preCommitFilename},
+	{name: "post-commit", script: postCommitFilename},
+}
+
+// The lines git-tree adds to a hook are wrapped in these markers, so they can
+// be found again when reinstalling or uninstalling.
+const hookBlockBegin = "# >>> git-tree >>>"
+const hookBlockEnd = "# <<< git-tree <<<"
+
+const hookShebang = "#!/usr/bin/env bash"
+
+type HookState int
+
+const (
+	HookInstalled HookState = iota
+	HookMissing
+	// Installed by a version of git-tree that did not use marker blocks.
+	HookOutdated
+	HookBroken
+)
+
+type HookStatus struct {
+	Hook string
+	// Path of the hook file.
+	Path  string
+	State HookState
+	// Why the hook is not installed, outdated or broken.
+	Problem string
+}
+
+// Returns the directory git runs the hooks of `repo` from: `core.hooksPath` if
+// it is set, or `.git/hooks` otherwise.
+func HooksDir(repo *git.Repository) (string, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	defer config.Free()
+
+	hooksPath, err := config.LookupString("core.hooksPath")
+	if err != nil || hooksPath == "" {
+		return defaultHooksDir(repo), nil
+	}
+
+	if hooksPath == "~" || strings.HasPrefix(hooksPath, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		hooksPath = filepath.Join(home, hooksPath[1:])
+	}
+
+	// Git runs the hooks from the root of the working tree, so relative paths
+	// are relative to it.
+	if !filepath.IsAbs(hooksPath) {
+		base := repo.Workdir()
+		if repo.IsBare() {
+			base = repo.Path()
+		}
+		hooksPath = filepath.Join(base, hooksPath)
+	}
+	return filepath.Clean(hooksPath), nil
+}
+
+// Returns the hooks directory of `repo` if it is set with `core.hooksPath`, or
+// "" if hooks are run from `.git/hooks`. Such a directory may be shared by
+// several repositories.
+func SharedHooksDir(repo *git.Repository) string {
+	dir, err := HooksDir(repo)
+	if err != nil || dir == defaultHooksDir(repo) {
+		return ""
+	}
+	return dir
+}
+
+// Hooks are shared by all worktrees, so they live in the common git directory.
+func defaultHooksDir(repo *git.Repository) string {
+	return filepath.Join(gitutil.CommonDir(repo.Path()), "hooks")
+}
+
+// Install the git-hooks that record obsolescences into the hooks directory of
+// `repo` (see `HooksDir()`).
+//
+// Installing is idempotent: the git-tree lines of each hook are wrapped in
+// marker blocks, which are refreshed rather than added again. Hooks that
+// already exist (e.g. from husky or the pre-commit framework) are kept, and
+// their original contents are backed up so uninstalling can restore them.
+func InstallHooks(repo *git.Repository) error {
+	dir, err := HooksDir(repo)
+	if err != nil {
+		return fmt.Errorf("Could not find the hooks directory: %s.", err.Error())
+	}
+
+	for _, hook := range gitTreeHooks {
+		if err := installHook(dir, hook); err != nil {
+			return fmt.Errorf("Could not install the %s hook: %s.", hook.name, err.Error())
+		}
+	}
+	return nil
+}
+
+// Remove the git-hooks installed by git-tree, restoring the original contents
+// of each hook if it was not changed since.
+//
+// Hooks are also removed from `.git/hooks` in case they were installed there
+// before `core.hooksPath` was set.
+func UninstallHooks(repo *git.Repository) error {
+	dir, err := HooksDir(repo)
+	if err != nil {
+		return fmt.Errorf("Could not find the hooks directory: %s.", err.Error())
+	}
+
+	dirs := []string{dir}
+	if dir != defaultHooksDir(repo) {
+		dirs = append(dirs, defaultHooksDir(repo))
+	}
+
+	for _, dir := range dirs {
+		if err := uninstallHooksFrom(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove the git-hooks installed by git-tree from hooks directory `dir`.
+func uninstallHooksFrom(dir string) error {
+	for _, hook := range gitTreeHooks {
+		if err := uninstallHook(dir, hook); err != nil {
+			return fmt.Errorf("Could not uninstall the %s hook: %s.", hook.name, err.Error())
+		}
+	}
+	return nil
+}
+
+// Returns the status of each git-hook git-tree needs.
+func HooksStatus(repo *git.Repository) ([]HookStatus, error) {
+	dir, err := HooksDir(repo)
+	if err != nil {
+		return nil, fmt.Errorf("Could not find the hooks directory: %s.", err.Error())
+	}
+
+	statuses := []HookStatus{}
+	for _, hook := range gitTreeHooks {
+		status := hookStatus(dir, hook)
+
+		// Hooks in `.git/hooks` are ignored once `core.hooksPath` is set.
+		if status.State == HookMissing && dir != defaultHooksDir(repo) {
+			if hookStatus(defaultHooksDir(repo), hook).State != HookMissing {
+				status.Problem = fmt.Sprintf("installed in %s, which git ignores because core.hooksPath is set",
+					defaultHooksDir(repo))
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func installHook(dir string, hook gitTreeHook) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// Copy `scripts/git-tree-{}.sh` into the hooks directory.
+	script, _ := gitHookScripts.ReadFile(hook.script)
+	scriptPath := hookScriptPath(dir, hook)
+	if err := os.WriteFile(scriptPath, script, 0755); err != nil {
+		return err
+	}
+	if err := os.Chmod(scriptPath, 0755); err != nil {
+		return err
+	}
+
+	hookPath := filepath.Join(dir, hook.name)
+	contents, mode, existed := readHook(hookPath)
+
+	if begin, end, found := findHookBlock(contents); found {
+		// Already installed: refresh the block in place.
+		contents = contents[:begin] + hookBlock(scriptPath) + contents[end:]
+	} else {
+		// Drop the call added by older versions of git-tree, then back up the
+		// hook before adding the block.
+		contents = removeLine(contents, hookCall(scriptPath))
+		if existed {
+			if err := os.WriteFile(hookBackupPath(dir, hook), []byte(contents), mode); err != nil {
+				return err
+			}
+		}
+		contents = addHookBlock(contents, scriptPath)
+	}
+
+	if err := os.WriteFile(hookPath, []byte(contents), mode); err != nil {
+		return err
+	}
+	return os.Chmod(hookPath, mode|0111)
+}
+
+func uninstallHook(dir string, hook gitTreeHook) error {
+	scriptPath := hookScriptPath(dir, hook)
+	os.Remove(scriptPath)
+
+	hookPath := filepath.Join(dir, hook.name)
+	contents, _, existed := readHook(hookPath)
+
+	backupPath := hookBackupPath(dir, hook)
+	backup, backupMode, hadBackup := readHook(backupPath)
+	defer os.Remove(backupPath)
+
+	if !existed {
+		return nil
+	}
+
+	// Restore the hook exactly if it was not changed since it was installed.
+	if hadBackup && contents == addHookBlock(backup, scriptPath) {
+		if err := os.WriteFile(hookPath, []byte(backup), backupMode); err != nil {
+			return err
+		}
+		return os.Chmod(hookPath, backupMode)
+	}
+	if !hadBackup && contents == addHookBlock("", scriptPath) {
+		return os.Remove(hookPath)
+	}
+
+	// Otherwise keep the changes made since, only removing git-tree's lines.
+	if begin, end, found := findHookBlock(contents); found {
+		contents = contents[:begin] + contents[end:]
+	}
+	contents = removeLine(contents, hookCall(scriptPath))
+	info, err := os.Stat(hookPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(hookPath, []byte(contents), info.Mode())
+}
+
+func hookStatus(dir string, hook gitTreeHook) HookStatus {
+	hookPath := filepath.Join(dir, hook.name)
+	status := HookStatus{Hook: hook.name, Path: hookPath}
+
+	contents, mode, existed := readHook(hookPath)
+	scriptPath := hookScriptPath(dir, hook)
+	begin, end, found := findHookBlock(contents)
+
+	switch {
+	case !existed:
+		status.State, status.Problem = HookMissing, "hook does not exist"
+	case !found && containsLine(contents, hookCall(scriptPath)):
+		status.State, status.Problem = HookOutdated, "installed without marker blocks"
+	case !found:
+		status.State, status.Problem = HookMissing, "hook does not call git-tree"
+	case contents[begin:end] != hookBlock(scriptPath):
+		status.State, status.Problem = HookBroken, fmt.Sprintf("hook does not call %s", scriptPath)
+	case mode&0111 == 0:
+		status.State, status.Problem = HookBroken, "hook is not executable"
+	case !isExecutable(scriptPath):
+		status.State, status.Problem = HookBroken, fmt.Sprintf("%s is missing or not executable", scriptPath)
+	default:
+		status.State = HookInstalled
+	}
+	return status
+}
+
+func hookScriptPath(dir string, hook gitTreeHook) string {
+	return filepath.Join(dir, filepath.Base(hook.script))
+}
+
+func hookBackupPath(dir string, hook gitTreeHook) string {
+	return filepath.Join(dir, "git-tree-"+hook.name+".orig")
+}
+
+func hookCall(scriptPath string) string {
+	return fmt.Sprintf(`%s "$@"`, scriptPath)
+}
+
+func hookBlock(scriptPath string) string {
+	return hookBlockBegin + "\n" + hookCall(scriptPath) + "\n" + hookBlockEnd + "\n"
+}
+
+// Returns the contents of hook `contents` with the git-tree block added.
+func addHookBlock(contents string, scriptPath string) string {
+	if contents == "" {
+		return hookShebang + "\n" + hookBlock(scriptPath)
+	}
+	if !strings.HasPrefix(contents, "#!") {
+		contents = hookShebang + "\n" + contents
+	}
+	if !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+
+	// Nothing runs after a hook `exec`s another program (like the hooks of the
+	// pre-commit framework do), so call git-tree first.
+	lines := strings.SplitAfter(contents, "\n")
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(strings.TrimSpace(line), "exec ") {
+			return lines[0] + hookBlock(scriptPath) + strings.Join(lines[1:], "")
+		}
+	}
+	return contents + hookBlock(scriptPath)
+}
+
+// Returns the start and end offsets of the git-tree block in `contents`.
+func findHookBlock(contents string) (int, int, bool) {
+	begin := strings.Index(contents, hookBlockBegin+"\n")
+	if begin == -1 {
+		return 0, 0, false
+	}
+	end := strings.Index(contents[begin:], hookBlockEnd+"\n")
+	if end == -1 {
+		return 0, 0, false
+	}
+	return begin, begin + end + len(hookBlockEnd) + 1, true
+}
+
+// Returns the contents of the file at `path`, its mode, and whether it exists.
+func readHook(path string) (string, os.FileMode, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0755, false
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0755, false
+	}
+	return string(contents), info.Mode(), true
+}
+
+func removeLine(contents string, line string) string {
+	lines := strings.SplitAfter(contents, "\n")
+	kept := []string{}
+	for _, l := range lines {
+		if strings.TrimSuffix(l, "\n") != line {
+			kept = append(kept, l)
+		}
+	}
+	return strings.Join(kept, "")
+}
+
+func containsLine(contents string, line string) bool {
+	for _, l := range strings.Split(contents, "\n") {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&0111 != 0
+}
@@ -0,0 +1,118 @@
+package operations
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/acamadeo/git-tree/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HooksTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *HooksTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *HooksTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+func (suite *HooksTestSuite) readHook(path string) string {
+	contents, _ := os.ReadFile(path)
+	return string(contents)
+}
+
+func (suite *HooksTestSuite) TestInstallHooks_IsIdempotent() {
+	Init(suite.repo.Repo)
+	hookFile := suite.repo.Repo.Path() + "hooks/pre-commit"
+	installed := suite.readHook(hookFile)
+
+	err := InstallHooks(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), installed, suite.readHook(hookFile))
+	assert.Equal(suite.T(), 1, strings.Count(installed, hookBlockBegin))
+}
+
+func (suite *HooksTestSuite) TestInstallHooks_UsesCoreHooksPath() {
+	config, _ := suite.repo.Repo.Config()
+	config.SetString("core.hooksPath", "shared-hooks")
+	config.Free()
+
+	Init(suite.repo.Repo)
+
+	hooksDir := suite.repo.Repo.Workdir() + "shared-hooks/"
+	scriptCall := hooksDir + `git-tree-pre-commit.sh "$@"`
+	assert.True(suite.T(), utils.FileContainsLine(hooksDir+"pre-commit", scriptCall),
+		"Expected file %q to contain line %q, but it does not", hooksDir+"pre-commit", scriptCall)
+	assert.False(suite.T(), utils.FileExists(suite.repo.Repo.Path()+"hooks/pre-commit"))
+}
+
+func (suite *HooksTestSuite) TestInstallHooks_CallsGitTreeBeforeExec() {
+	hookFile := suite.repo.Repo.Path() + "hooks/pre-commit"
+	os.MkdirAll(suite.repo.Repo.Path()+"hooks", os.ModePerm)
+	os.WriteFile(hookFile, []byte("#!/usr/bin/env bash\nexec pre-commit run\n"), 0755)
+
+	Init(suite.repo.Repo)
+
+	lines := strings.Split(suite.readHook(hookFile), "\n")
+	assert.Equal(suite.T(), hookBlockBegin, lines[1])
+	assert.Equal(suite.T(), "exec pre-commit run", lines[4])
+}
+
+func (suite *HooksTestSuite) TestUninstallHooks_RestoresOriginalHook() {
+	original := "#!/bin/sh\necho husky"
+	hookFile := suite.repo.Repo.Path() + "hooks/post-commit"
+	os.MkdirAll(suite.repo.Repo.Path()+"hooks", os.ModePerm)
+	os.WriteFile(hookFile, []byte(original), 0700)
+	Init(suite.repo.Repo)
+
+	err := UninstallHooks(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), original, suite.readHook(hookFile))
+	info, _ := os.Stat(hookFile)
+	assert.Equal(suite.T(), os.FileMode(0700), info.Mode().Perm())
+	assert.False(suite.T(), utils.FileExists(suite.repo.Repo.Path()+"hooks/pre-commit"))
+}
+
+func (suite *HooksTestSuite) TestUninstallHooks_KeepsLaterChanges() {
+	hookFile := suite.repo.Repo.Path() + "hooks/post-commit"
+	Init(suite.repo.Repo)
+	os.WriteFile(hookFile, []byte(suite.readHook(hookFile)+"echo later\n"), 0755)
+
+	UninstallHooks(suite.repo.Repo)
+
+	assert.Equal(suite.T(), hookShebang+"\necho later\n", suite.readHook(hookFile))
+}
+
+func (suite *HooksTestSuite) TestHooksStatus_ReportsMissingAndBrokenHooks() {
+	Init(suite.repo.Repo)
+	os.Remove(suite.repo.Repo.Path() + "hooks/git-tree-post-commit.sh")
+	os.Remove(suite.repo.Repo.Path() + "hooks/pre-rebase")
+
+	statuses, err := HooksStatus(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	states := map[string]HookState{}
+	for _, status := range statuses {
+		states[status.Hook] = status.State
+	}
+	assert.Equal(suite.T(), map[string]HookState{
+		"pre-rebase":   HookMissing,
+		"post-rewrite": HookInstalled,
+		"pre-commit":   HookInstalled,
+		"post-commit":  HookBroken,
+	}, states)
+}
+
+func TestHooksTestSuite(t *testing.T) {
+	suite.Run(t, new(HooksTestSuite))
+}
@@ -0,0 +1,47 @@
+package operations
+
+import (
+	"fmt"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// Returns the name of the git-tree operation that was interrupted by a merge
+// conflict, or "" if no operation is in progress.
+func operationInProgress(repo *git.Repository) string {
+	if utils.FileExists(store.RebasingPath(repo.Path())) {
+		return "rebase"
+	}
+	if utils.FileExists(store.SyncingPath(repo.Path())) {
+		return "sync"
+	}
+	if utils.FileExists(store.EvolvingPath(repo.Path())) {
+		return "evolve"
+	}
+	if utils.FileExists(store.DeletingPath(repo.Path())) {
+		return "delete"
+	}
+	return ""
+}
+
+// Returns an error if a git-tree operation is in progress. `action` describes
+// what the caller is trying to do (e.g., "track branches").
+func validateNoOperationInProgress(repo *git.Repository, action string) error {
+	if operation := operationInProgress(repo); operation != "" {
+		return fmt.Errorf("Cannot %s while a %s is in progress. Abort or continue the existing %s", action, operation, operation)
+	}
+	return nil
+}
+
+// Returns an error if a `git rebase -i` is in progress. Its state would be
+// mistaken for the state of a git-tree rebase, and its detached HEAD is only
+// moved once it finishes.
+func validateNoGitRebaseInProgress(repo *git.Repository, action string) error {
+	if gitutil.InteractiveRebaseInProgress(repo) {
+		return fmt.Errorf("Cannot %s while a `git rebase -i` is in progress. Continue or abort it first", action)
+	}
+	return nil
+}
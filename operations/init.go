@@ -3,13 +3,10 @@ package operations
 import (
 	"embed"
 	"fmt"
-	"os"
-	"regexp"
 
 	gitutil "github.com/acamadeo/git-tree/git"
 	"github.com/acamadeo/git-tree/models"
 	"github.com/acamadeo/git-tree/store"
-	"github.com/acamadeo/git-tree/utils"
 	git "github.com/libgit2/git2go/v34"
 )
 
@@ -40,12 +37,12 @@ func Init(repo *git.Repository, branches ...*git.Branch) error {
 	// Construct a branch map from the branches and store the branch map in our
 	// file.
 	branchMap := models.BranchMapFromRepo(repo, rootBranch, branches)
-	store.WriteBranchMap(branchMap, store.BranchMapPath(repo.Path()))
-
-	// Install `post-commit` and `post-rewrite` git-hooks.
-	installGitHooks(repo)
+	if err := store.WriteBranchMap(branchMap, store.BranchMapPath(repo.Path())); err != nil {
+		return fmt.Errorf("Could not write branch map: %s.", err.Error())
+	}
 
-	return nil
+	// Install the git-hooks that record obsolescences.
+	return InstallHooks(repo)
 }
 
 func createRootBranch(repo *git.Repository, branches []*git.Branch) (*git.Branch, error) {
@@ -55,42 +52,3 @@ func createRootBranch(repo *git.Repository, branches []*git.Branch) (*git.Branch
 	rootCommit, _ := repo.LookupCommit(rootOid)
 	return repo.CreateBranch(store.GitTreeRootBranch, rootCommit, false)
 }
-
-func installGitHooks(repo *git.Repository) {
-	// `pre-rebase` hook
-	hookFile := repo.Path() + "hooks/pre-rebase"
-	destFilename := repo.Path() + "hooks/git-tree-pre-rebase.sh"
-	installGitHook(hookFile, preRebaseFilename, destFilename)
-
-	// `post-rewrite` hook
-	hookFile = repo.Path() + "hooks/post-rewrite"
-	destFilename = repo.Path() + "hooks/git-tree-post-rewrite.sh"
-	installGitHook(hookFile, postRewriteFilename, destFilename)
-
-	// `pre-commit` hook
-	hookFile = repo.Path() + "hooks/pre-commit"
-	destFilename = repo.Path() + "hooks/git-tree-pre-commit.sh"
-	installGitHook(hookFile, preCommitFilename, destFilename)
-
-	// `post-commit` hook
-	hookFile = repo.Path() + "hooks/post-commit"
-	destFilename = repo.Path() + "hooks/git-tree-post-commit.sh"
-	installGitHook(hookFile, postCommitFilename, destFilename)
-}
-
-func installGitHook(hookFile string, sourceFilename string, destFilename string) {
-	// Copy `scripts/git-tree-post-{}.sh` into `.git/hooks/`.
-	sourceFile, _ := gitHookScripts.ReadFile(sourceFilename)
-	utils.OverwriteFile(destFilename, string(sourceFile))
-
-	// Call `git-tree-post-{}.sh` in the `post-{}` hook.
-	contents := utils.ReadFile(hookFile)
-	if matched, _ := regexp.MatchString(`^#!.*`, contents); !matched {
-		utils.PrependToFile(hookFile, "#!/bin/bash")
-	}
-	utils.AppendToFile(hookFile, fmt.Sprintf(`%s "$@"`, destFilename))
-
-	// Mark `git-tree-post-{}.sh` and `.git/hooks/post-{}` as executable.
-	os.Chmod(destFilename, 0755)
-	os.Chmod(hookFile, 0755)
-}
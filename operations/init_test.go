@@ -117,14 +117,19 @@ func (suite *InitTestSuite) TestInit_BranchMapFile_AllBranches() {
 
 	gotString := suite.repo.ReadFile(".git/tree/branches")
 	wantString :=
-		`git-tree-root
-git-tree-root master
-master mew
-mew burmy wurmple
-burmy mothim wormadam
-wurmple cascoon silcoon
-cascoon dustox
-silcoon beautifly`
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["mew"]},
+    {"branch": "mew", "children": ["burmy", "wurmple"]},
+    {"branch": "burmy", "children": ["mothim", "wormadam"]},
+    {"branch": "wurmple", "children": ["cascoon", "silcoon"]},
+    {"branch": "cascoon", "children": ["dustox"]},
+    {"branch": "silcoon", "children": ["beautifly"]}
+  ]
+}`
 
 	assert.Equal(suite.T(), gotString, wantString,
 		"Got branch map file: %v, but want file: %v", gotString, wantString)
@@ -159,9 +164,14 @@ func (suite *InitTestSuite) TestInit_BranchMapFile_SubsetOfBranches() {
 
 	gotString := suite.repo.ReadFile(".git/tree/branches")
 	wantString :=
-		`git-tree-root
-git-tree-root mew
-mew wormadam mothim silcoon dustox`
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["mew"]},
+    {"branch": "mew", "children": ["wormadam", "mothim", "silcoon", "dustox"]}
+  ]
+}`
 
 	assert.Equal(suite.T(), gotString, wantString,
 		"Got branch map file: %v, but want file: %v", gotString, wantString)
@@ -0,0 +1,118 @@
+package operations
+
+import (
+	"fmt"
+	"strings"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// Compare each commit of branch `branchName` to its previous version, as
+// recorded in the obsolescence map.
+//
+// The previous version is first replayed onto the parents of the current one,
+// so changes that only come from rebasing onto a new parent are not shown. If
+// it cannot be replayed cleanly, the two versions are compared as they are.
+//
+// Example:
+//
+//   - 9d3b2a1 Add treecko.txt
+//     amend from 3316a58
+//     diff --git a/treecko.txt b/treecko.txt
+//     ...
+//   - 4c0b1b3 Add grovyle.txt
+//     new commit
+func Interdiff(repo *git.Repository, branchName string) (string, error) {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return "", err
+	}
+	branch := branchMap.FindBranch(branchName)
+	if branch == nil {
+		return "", fmt.Errorf("Branch %q is not tracked by git-tree.", branchName)
+	}
+	parent := branchMap.FindParent(branchName)
+	if parent == nil {
+		return "", fmt.Errorf("Branch %q has no parent branch to compare against.", branchName)
+	}
+
+	commits := gitutil.CommitsBetween(repo, parent.Target(), branch.Target())
+	if len(commits) == 0 {
+		return fmt.Sprintf("Branch %q has no commits.", branchName), nil
+	}
+
+	output := []string{}
+	for _, commit := range commits {
+		output = append(output, fmt.Sprintf("• %s %s", gitutil.CommitShortHash(commit), commit.Summary()))
+
+		step, err := previousVersion(repo, commit)
+		if err != nil {
+			return "", err
+		}
+		if step == nil {
+			output = append(output, "    new commit")
+			continue
+		}
+
+		output = append(output, fmt.Sprintf("    %s from %s",
+			store.ActionTypeName(step.ActionType), gitutil.CommitShortHash(step.Predecessor)))
+
+		diff, rebased, err := interdiffCommits(repo, step.Predecessor, commit)
+		if err != nil {
+			return "", err
+		}
+		if !rebased {
+			output = append(output, "    (previous version does not apply onto the new parents; showing the full diff)")
+		}
+		if diff == "" {
+			output = append(output, "    no changes")
+			continue
+		}
+		output = append(output, strings.TrimSuffix(diff, "\n"))
+	}
+	return strings.Join(output, "\n"), nil
+}
+
+// Returns the latest step that produced `commit`, or nil if `commit` has no
+// previous version.
+func previousVersion(repo *git.Repository, commit *git.Commit) (*ObslogStep, error) {
+	steps, err := Obslog(repo, commit)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i].Successor.Id().Equal(commit.Id()) {
+			return &steps[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Returns the diff from `previous` to `current`, ignoring the changes between
+// their parents.
+//
+// Also returns false if `previous` could not be replayed onto the parents of
+// `current`, in which case the diff is between the two commits as they are.
+//
+// `previous` is replayed in memory, so no commit is created.
+func interdiffCommits(repo *git.Repository, previous *git.Commit, current *git.Commit) (string, bool, error) {
+	if previous.ParentCount() > 0 && previous.ParentCount() == current.ParentCount() {
+		if base, err := gitutil.RecreateTree(repo, previous, gitutil.ParentIds(current)); err == nil {
+			defer base.Free()
+
+			currentTree, err := current.Tree()
+			if err != nil {
+				return "", false, err
+			}
+			defer currentTree.Free()
+
+			diff, err := gitutil.DiffTrees(repo, base, currentTree)
+			return diff, true, err
+		}
+	}
+
+	diff, err := gitutil.DiffCommitTrees(repo, previous, current)
+	return diff, false, err
+}
@@ -0,0 +1,179 @@
+package operations
+
+import (
+	"fmt"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/testutil"
+	git "github.com/libgit2/git2go/v34"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type InterdiffTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *InterdiffTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *InterdiffTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+func (suite *InterdiffTestSuite) branchCommit(name string) *git.Commit {
+	return gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch(name).Target())
+}
+
+// Returns the number of commits in the object database.
+func (suite *InterdiffTestSuite) commitCount() int {
+	odb, _ := suite.repo.Repo.Odb()
+	count := 0
+	odb.ForEach(func(oid *git.Oid) error {
+		if _, objectType, err := odb.ReadHeader(oid); err == nil && objectType == git.ObjectCommit {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko] contents
+func (suite *InterdiffTestSuite) TestInterdiff_ShowsChangesOfAmendedCommit() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	oldTreecko := suite.branchCommit("treecko")
+	ObsoletePreCommit(suite.repo.Repo)
+	suite.repo.WriteFile("treecko", "treecko v2")
+	suite.repo.StageFiles()
+	suite.repo.AmendCommit("treecko")
+	ObsoletePostRewriteAmend(suite.repo.Repo, []string{oldTreecko.Id().String() + " " + suite.branchCommit("treecko").Id().String()})
+
+	gotString, err := Interdiff(suite.repo.Repo, "treecko")
+
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), gotString, "    amend from "+gitutil.CommitShortHash(oldTreecko))
+	assert.Contains(suite.T(), gotString, "-treecko\n+treecko v2")
+
+	gotString, err = Interdiff(suite.repo.Repo, "grovyle")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), fmt.Sprintf("• %s grovyle\n    new commit", gitutil.CommitShortHash(suite.branchCommit("grovyle"))), gotString)
+}
+
+// Branches:
+//
+//	master ─┬─ mudkip
+//	        └─ treecko
+//
+// Action:
+//   - Rebase [treecko] onto [mudkip] with `git rebase`
+func (suite *InterdiffTestSuite) TestInterdiff_IgnoresChangesFromNewParent() {
+	suite.repo.BranchWithCommit("mudkip")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	oldTreecko := suite.branchCommit("treecko")
+	ObsoletePreRebase(suite.repo.Repo)
+	newTreecko, _ := gitutil.RecreateCommit(suite.repo.Repo, oldTreecko, []git.Oid{*suite.repo.LookupBranch("mudkip").Target()})
+	treecko := suite.repo.LookupBranch("treecko")
+	gitutil.MoveBranchTarget(suite.repo.Repo, &treecko, &newTreecko)
+	ObsoletePostRewriteRebase(suite.repo.Repo, []string{oldTreecko.Id().String() + " " + newTreecko.String()})
+
+	// The branch map still lists master as the parent of treecko, so the commit
+	// of mudkip is listed as well.
+	gotString, err := Interdiff(suite.repo.Repo, "treecko")
+	wantString := fmt.Sprintf(`• %s mudkip
+    new commit
+• %s treecko
+    rebase from %s
+    no changes`,
+		gitutil.CommitShortHash(suite.branchCommit("mudkip")), gitutil.OidShortHash(newTreecko),
+		gitutil.CommitShortHash(oldTreecko))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+// Branches:
+//
+//	master ─── treecko
+//
+// Action:
+//   - Commit [grovyle] on top of [treecko], with the git-hooks installed
+func (suite *InterdiffTestSuite) TestInterdiff_CommitOnTopIsNewCommit() {
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	ObsoletePreCommit(suite.repo.Repo)
+	suite.repo.WriteAndCommitFile("grovyle", "grovyle", "grovyle")
+	ObsoletePostCommit(suite.repo.Repo)
+
+	gotString, err := Interdiff(suite.repo.Repo, "treecko")
+	wantString := fmt.Sprintf(`• %s treecko
+    new commit
+• %s grovyle
+    new commit`,
+		gitutil.CommitShortHash(suite.branchCommit("treecko").Parent(0)), gitutil.CommitShortHash(suite.branchCommit("treecko")))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+// Branches:
+//
+//	master ─┬─ mudkip
+//	        └─ treecko
+//
+// Action:
+//   - Rebase [treecko] onto [mudkip] and change its contents
+func (suite *InterdiffTestSuite) TestInterdiff_DoesNotCreateCommits() {
+	suite.repo.BranchWithCommit("mudkip")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	oldTreecko := suite.branchCommit("treecko")
+	ObsoletePreRebase(suite.repo.Repo)
+	suite.repo.SwitchBranch("mudkip")
+	suite.repo.CreateAndSwitchBranch("rebased")
+	suite.repo.WriteAndCommitFile("treecko", "treecko v2", "treecko")
+	newTreecko := *suite.repo.LookupBranch("rebased").Target()
+	treecko := suite.repo.LookupBranch("treecko")
+	gitutil.MoveBranchTarget(suite.repo.Repo, &treecko, &newTreecko)
+	ObsoletePostRewriteRebase(suite.repo.Repo, []string{oldTreecko.Id().String() + " " + newTreecko.String()})
+	commits := suite.commitCount()
+
+	gotString, err := Interdiff(suite.repo.Repo, "treecko")
+
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), gotString, "    rebase from "+gitutil.CommitShortHash(oldTreecko))
+	assert.Contains(suite.T(), gotString, "-treecko\n+treecko v2")
+	assert.Equal(suite.T(), commits, suite.commitCount())
+}
+
+func (suite *InterdiffTestSuite) TestInterdiff_UntrackedBranch() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.SwitchBranch("master")
+	Init(suite.repo.Repo, suite.repo.LookupBranch("master"))
+
+	_, err := Interdiff(suite.repo.Repo, "treecko")
+
+	assert.NotNil(suite.T(), err)
+}
+
+func TestInterdiffTestSuite(t *testing.T) {
+	suite.Run(t, new(InterdiffTestSuite))
+}
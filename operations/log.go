@@ -0,0 +1,147 @@
+package operations
+
+import (
+	"fmt"
+	"strings"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+type logRunner struct {
+	repo      *git.Repository
+	branchMap *models.BranchMap
+	// Set of commits that have been obsoleted by another commit.
+	obsolete map[git.Oid]bool
+	// The branch HEAD points to, or "" if HEAD is detached.
+	headBranch string
+	headOid    git.Oid
+	output     []string
+}
+
+// Render the tree of branches tracked by git-tree.
+//
+// Each branch lists the commits it adds on top of its parent branch (from
+// oldest to newest). Commits that have been obsoleted are marked `obsolete`,
+// and commits that sit on top of an obsolete commit are marked `troubled`.
+//
+// Example:
+//
+//	git-tree-root
+//	└─ master
+//	   └─ eevee
+//	      │  • cee417d Add eevee.txt [obsolete]
+//	      ├─ vaporeon (HEAD)
+//	      │     • d0c4e5c Add vaporeon.txt [troubled]
+//	      └─ flareon
+//	            • 592b7fb Add flareon.txt [troubled]
+func Log(repo *git.Repository) (string, error) {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return "", err
+	}
+	obsolete, err := obsoleteCommitSet(repo)
+	if err != nil {
+		return "", err
+	}
+	runner := logRunner{
+		repo:      repo,
+		branchMap: branchMap,
+		obsolete:  obsolete,
+	}
+
+	headRef, err := repo.Head()
+	if err == nil {
+		runner.headOid = *headRef.Target()
+		if headRef.IsBranch() {
+			runner.headBranch = gitutil.BranchName(headRef.Branch())
+		}
+	}
+
+	return runner.Execute(), nil
+}
+
+func (r *logRunner) Execute() string {
+	rootName := gitutil.BranchName(r.branchMap.Root)
+	r.output = append(r.output, rootName)
+	r.logChildren(rootName, "", false)
+	return strings.Join(r.output, "\n")
+}
+
+// Log each child of branch `parentName`. `prefix` is drawn before every line
+// belonging to the children. `troubled` is true if `parentName` (or one of its
+// ancestors) contains an obsolete commit.
+func (r *logRunner) logChildren(parentName string, prefix string, troubled bool) {
+	parent := r.branchMap.FindBranch(parentName)
+	children := r.branchMap.FindChildren(parentName)
+
+	for i, child := range children {
+		isLast := i == len(children)-1
+
+		connector, childPrefix := "├─ ", prefix+"│  "
+		if isLast {
+			connector, childPrefix = "└─ ", prefix+"   "
+		}
+
+		childName := gitutil.BranchName(child)
+		line := prefix + connector + childName
+		if childName == r.headBranch {
+			line += " (HEAD)"
+		}
+		r.output = append(r.output, line)
+
+		// Draw a line through the commits if the child has children of its own.
+		commitPrefix := childPrefix + "   "
+		if len(r.branchMap.FindChildren(childName)) > 0 {
+			commitPrefix = childPrefix + "│  "
+		}
+
+		childTroubled := r.logCommits(parent, child, commitPrefix, troubled)
+		r.logChildren(childName, childPrefix, childTroubled)
+	}
+}
+
+// Log the commits that `branch` adds on top of `parent`.
+//
+// Returns true if commits descending from `branch` are troubled.
+func (r *logRunner) logCommits(parent *git.Branch, branch *git.Branch, prefix string, troubled bool) bool {
+	for _, commit := range gitutil.CommitsBetween(r.repo, parent.Target(), branch.Target()) {
+		line := fmt.Sprintf("%s• %s %s", prefix, gitutil.CommitShortHash(commit), commit.Summary())
+
+		if r.obsolete[*commit.Id()] {
+			line += " [obsolete]"
+			troubled = true
+		} else if troubled {
+			line += " [troubled]"
+		}
+
+		if r.headBranch == "" && r.headOid.Equal(commit.Id()) {
+			line += " (HEAD)"
+		}
+		r.output = append(r.output, line)
+	}
+	return troubled
+}
+
+// Returns the set of commits that were obsoleted by another commit.
+//
+// `post-commit` entries link a commit to its new child, which does not make
+// the commit obsolete, so they are skipped.
+func obsoleteCommitSet(repo *git.Repository) (map[git.Oid]bool, error) {
+	obsmap, err := store.ReadObsolescenceMap(repo, store.ObsoleteMapPath(repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+
+	obsolete := map[git.Oid]bool{}
+	for _, action := range obsmap.Actions {
+		for _, entry := range action.Entries {
+			if entry.Commit != nil && entry.HookType != models.PostCommit {
+				obsolete[*entry.Commit.Id()] = true
+			}
+		}
+	}
+	return obsolete, nil
+}
@@ -0,0 +1,130 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LogTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *LogTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *LogTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+func (suite *LogTestSuite) shortHash(branchName string) string {
+	return gitutil.OidShortHash(*suite.repo.LookupBranch(branchName).Target())
+}
+
+// Branches:
+//
+//	master ─── treecko ─┬─ grovyle
+//	                    └─ mudkip
+func (suite *LogTestSuite) TestLog_DrawsBranchTree() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+
+	gotString, err := Log(suite.repo.Repo)
+	assert.Nil(suite.T(), err)
+	wantString := fmt.Sprintf(
+		`git-tree-root
+└─ master
+   └─ treecko
+      │  • %s treecko
+      ├─ grovyle
+      │     • %s grovyle
+      └─ mudkip (HEAD)
+            • %s mudkip`,
+		suite.shortHash("treecko"), suite.shortHash("grovyle"), suite.shortHash("mudkip"))
+
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Action:
+//   - Amend [treecko]
+func (suite *LogTestSuite) TestLog_MarksObsoleteAndTroubledCommits() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("treecko")
+	oldTreecko := suite.shortHash("treecko")
+	amendHead(&suite.repo, "treecko amended")
+
+	gotString, err := Log(suite.repo.Repo)
+	assert.Nil(suite.T(), err)
+	wantString := fmt.Sprintf(
+		`git-tree-root
+└─ master
+   └─ treecko (HEAD)
+      │  • %s treecko amended
+      └─ grovyle
+            • %s treecko [obsolete]
+            • %s grovyle [troubled]`,
+		suite.shortHash("treecko"), oldTreecko, suite.shortHash("grovyle"))
+
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+// Branches:
+//
+//	master ─── treecko
+//
+// Action:
+//   - Commit on top of [treecko], with the git-hooks installed
+func (suite *LogTestSuite) TestLog_NewCommitIsNotObsolete() {
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+	oldTreecko := suite.shortHash("treecko")
+
+	ObsoletePreCommit(suite.repo.Repo)
+	suite.repo.WriteAndCommitFile("grovyle", "grovyle", "grovyle")
+	ObsoletePostCommit(suite.repo.Repo)
+
+	gotString, err := Log(suite.repo.Repo)
+	assert.Nil(suite.T(), err)
+	wantString := fmt.Sprintf(
+		`git-tree-root
+└─ master
+   └─ treecko (HEAD)
+         • %s treecko
+         • %s grovyle`,
+		oldTreecko, suite.shortHash("treecko"))
+
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+func (suite *LogTestSuite) TestLog_RefusesNewerFormat() {
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+	suite.repo.WriteFile(".git/tree/branches", `{"version": 3, "branches": {}}`)
+
+	_, err := Log(suite.repo.Repo)
+
+	var newerFormat *store.NewerFormatError
+	assert.True(suite.T(), errors.As(err, &newerFormat))
+}
+
+func TestLogTestSuite(t *testing.T) {
+	suite.Run(t, new(LogTestSuite))
+}
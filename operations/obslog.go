@@ -0,0 +1,148 @@
+package operations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// A step in the evolution of a change: `Predecessor` was rewritten into
+// `Successor` by an action of type `ActionType`, recorded by hook `HookType`.
+type ObslogStep struct {
+	Predecessor *git.Commit
+	Successor   *git.Commit
+	ActionType  models.ActionType
+	HookType    models.HookType
+}
+
+// Returns every step in the evolution of the change containing `commit`, from
+// oldest to newest.
+//
+// Follows predecessor and successor links from `commit` through every action
+// of the obsolescence map, so steps that split or fold the change are included.
+func Obslog(repo *git.Repository, commit *git.Commit) ([]ObslogStep, error) {
+	obsmap, err := store.ReadObsolescenceMap(repo, store.ObsoleteMapPath(repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+
+	// Steps are in the order they were recorded.
+	steps := []ObslogStep{}
+	for _, action := range obsmap.Actions {
+		for _, entry := range action.Entries {
+			if entry.Commit == nil || entry.Obsoleter == nil {
+				continue
+			}
+			// `post-commit` links a commit to its child, which is a different
+			// change rather than a new version of it.
+			if entry.HookType == models.PostCommit {
+				continue
+			}
+			steps = append(steps, ObslogStep{
+				Predecessor: entry.Commit,
+				Successor:   entry.Obsoleter,
+				ActionType:  action.ActionType,
+				HookType:    entry.HookType,
+			})
+		}
+	}
+
+	// Find the versions of the change, i.e. every commit linked to `commit`.
+	versions := map[git.Oid]bool{*commit.Id(): true}
+	for found := true; found; {
+		found = false
+		for _, step := range steps {
+			predecessor, successor := *step.Predecessor.Id(), *step.Successor.Id()
+			if versions[predecessor] != versions[successor] {
+				versions[predecessor], versions[successor] = true, true
+				found = true
+			}
+		}
+	}
+
+	// A commit may be recorded more than once, so only keep the first step
+	// between two versions.
+	type link struct{ predecessor, successor git.Oid }
+	seen := map[link]bool{}
+	evolution := []ObslogStep{}
+	for _, step := range steps {
+		key := link{*step.Predecessor.Id(), *step.Successor.Id()}
+		if !versions[key.predecessor] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		evolution = append(evolution, step)
+	}
+	return evolution, nil
+}
+
+// Render the evolution of the change containing `commit`, from newest to
+// oldest version. Each version lists the steps that produced it. If `patch` is
+// true, each step is followed by the diff between the two versions.
+//
+// Example:
+//
+//   - 9d3b2a1 Add treecko.txt
+//     amend (post-rewrite.amend) from 3316a58
+//   - 3316a58 Add treecko.txt [obsolete]
+//     amend (post-rewrite.amend) from cf59c4b
+//   - cf59c4b Add treecko.txt [obsolete]
+func RenderObslog(repo *git.Repository, commit *git.Commit, patch bool) (string, error) {
+	evolution, err := Obslog(repo, commit)
+	if err != nil {
+		return "", err
+	}
+
+	// Order the versions newest first, by the first step that created each one.
+	// The original versions were not created by any step.
+	versions := []*git.Commit{commit}
+	createdAt := map[git.Oid]int{*commit.Id(): -1}
+	obsolete := map[git.Oid]bool{}
+	for index, step := range evolution {
+		for _, version := range []*git.Commit{step.Predecessor, step.Successor} {
+			if _, ok := createdAt[*version.Id()]; !ok {
+				createdAt[*version.Id()] = -1
+				versions = append(versions, version)
+			}
+		}
+		if createdAt[*step.Successor.Id()] == -1 {
+			createdAt[*step.Successor.Id()] = index
+		}
+		obsolete[*step.Predecessor.Id()] = true
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return createdAt[*versions[i].Id()] > createdAt[*versions[j].Id()]
+	})
+
+	output := []string{}
+	for _, version := range versions {
+		line := fmt.Sprintf("• %s %s", gitutil.CommitShortHash(version), version.Summary())
+		if obsolete[*version.Id()] {
+			line += " [obsolete]"
+		}
+		output = append(output, line)
+
+		for _, step := range evolution {
+			if !step.Successor.Id().Equal(version.Id()) {
+				continue
+			}
+			output = append(output, fmt.Sprintf("    %s (%s) from %s",
+				store.ActionTypeName(step.ActionType), store.HookTypeName(step.HookType),
+				gitutil.CommitShortHash(step.Predecessor)))
+
+			if patch {
+				diff, err := gitutil.DiffCommitTrees(repo, step.Predecessor, step.Successor)
+				if err != nil {
+					return "", err
+				}
+				output = append(output, strings.TrimSuffix(diff, "\n"))
+			}
+		}
+	}
+	return strings.Join(output, "\n"), nil
+}
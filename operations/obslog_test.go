@@ -0,0 +1,138 @@
+package operations
+
+import (
+	"fmt"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/testutil"
+	git "github.com/libgit2/git2go/v34"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ObslogTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *ObslogTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *ObslogTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+func (suite *ObslogTestSuite) headCommit() *git.Commit {
+	return gitutil.CommitByOid(suite.repo.Repo, *gitutil.HeadBranch(suite.repo.Repo).Target())
+}
+
+// Branches:
+//
+//	master ─── treecko
+//
+// Actions:
+//   - Amend [treecko] message
+//   - Amend [treecko] contents
+func (suite *ObslogTestSuite) setupAmendedTwice() (*git.Commit, *git.Commit, *git.Commit) {
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	first := suite.headCommit()
+	amendHead(&suite.repo, "treecko v2")
+	second := suite.headCommit()
+	suite.repo.WriteFile("treecko", "grovyle")
+	suite.repo.StageFiles()
+	amendHead(&suite.repo, "treecko v3")
+	third := suite.headCommit()
+	return first, second, third
+}
+
+func (suite *ObslogTestSuite) TestObslog_FollowsPredecessorsAndSuccessors() {
+	first, second, third := suite.setupAmendedTwice()
+
+	// The history is the same from any version of the change.
+	for _, commit := range []*git.Commit{first, second, third} {
+		steps, err := Obslog(suite.repo.Repo, commit)
+		assert.Nil(suite.T(), err)
+
+		assert.Equal(suite.T(), 2, len(steps))
+		assert.Equal(suite.T(), *first.Id(), *steps[0].Predecessor.Id())
+		assert.Equal(suite.T(), *second.Id(), *steps[0].Successor.Id())
+		assert.Equal(suite.T(), *second.Id(), *steps[1].Predecessor.Id())
+		assert.Equal(suite.T(), *third.Id(), *steps[1].Successor.Id())
+		assert.Equal(suite.T(), models.ActionTypeAmend, steps[1].ActionType)
+		assert.Equal(suite.T(), models.PostRewriteAmend, steps[1].HookType)
+	}
+}
+
+func (suite *ObslogTestSuite) TestObslog_UnrelatedCommitHasNoHistory() {
+	suite.setupAmendedTwice()
+	master := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("master").Target())
+
+	steps, err := Obslog(suite.repo.Repo, master)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), steps)
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+//
+// Actions:
+//   - Amend [treecko]
+//   - Commit [grovyle] on top of [treecko]
+func (suite *ObslogTestSuite) TestObslog_IgnoresNewCommitsOnTop() {
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+	first := suite.headCommit()
+	amendHead(&suite.repo, "treecko v2")
+	second := suite.headCommit()
+
+	ObsoletePreCommit(suite.repo.Repo)
+	suite.repo.WriteAndCommitFile("grovyle", "grovyle", "grovyle")
+	ObsoletePostCommit(suite.repo.Repo)
+	child := suite.headCommit()
+
+	steps, err := Obslog(suite.repo.Repo, second)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(steps))
+	assert.Equal(suite.T(), *first.Id(), *steps[0].Predecessor.Id())
+	assert.Equal(suite.T(), *second.Id(), *steps[0].Successor.Id())
+
+	steps, err = Obslog(suite.repo.Repo, child)
+	assert.Nil(suite.T(), err)
+	assert.Empty(suite.T(), steps)
+}
+
+func (suite *ObslogTestSuite) TestRenderObslog_ListsVersionsNewestFirst() {
+	first, second, third := suite.setupAmendedTwice()
+
+	gotString, err := RenderObslog(suite.repo.Repo, first, false)
+	wantString := fmt.Sprintf(`• %s treecko v3
+    amend (post-rewrite.amend) from %s
+• %s treecko v2 [obsolete]
+    amend (post-rewrite.amend) from %s
+• %s treecko [obsolete]`,
+		gitutil.CommitShortHash(third), gitutil.CommitShortHash(second),
+		gitutil.CommitShortHash(second), gitutil.CommitShortHash(first),
+		gitutil.CommitShortHash(first))
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+func (suite *ObslogTestSuite) TestRenderObslog_PatchShowsDiffBetweenVersions() {
+	_, _, third := suite.setupAmendedTwice()
+
+	gotString, err := RenderObslog(suite.repo.Repo, third, true)
+
+	assert.Nil(suite.T(), err)
+	assert.Contains(suite.T(), gotString, "-treecko\n+grovyle")
+}
+
+func TestObslogTestSuite(t *testing.T) {
+	suite.Run(t, new(ObslogTestSuite))
+}
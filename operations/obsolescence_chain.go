@@ -2,13 +2,35 @@ package operations
 
 import (
 	"fmt"
-	"log"
+	"strings"
 
 	gitutil "github.com/acamadeo/git-tree/git"
 	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
 	git "github.com/libgit2/git2go/v34"
 )
 
+// Returned when an action in the obsolescence map has a shape that cannot be
+// turned into an obsolescence chain.
+type UnsupportedObsolescenceError struct {
+	// Position of the action in the obsolescence map (starting at 0).
+	ActionIndex int
+	ActionType  models.ActionType
+	// The commits that make the action unsupported.
+	Commits []git.Oid
+	// Why the action is unsupported.
+	Reason string
+}
+
+func (e *UnsupportedObsolescenceError) Error() string {
+	hashes := []string{}
+	for _, oid := range e.Commits {
+		hashes = append(hashes, gitutil.OidShortHash(oid))
+	}
+	return fmt.Sprintf("Unsupported obsolescence action #%d (%s): %s [%s]",
+		e.ActionIndex+1, store.ActionTypeName(e.ActionType), e.Reason, strings.Join(hashes, " "))
+}
+
 // Represents the commits that obsoleted another set of commits in a Git action.
 type obsolescenceChain struct {
 	// The commit that the two commit chains extend from.
@@ -46,13 +68,26 @@ func (ocs *obsolescenceChains) FindChainWithObsoleteCommit(commit *git.Commit) *
 	return nil
 }
 
-func buildObsolescenceChains(repo *git.Repository, obsmap *models.ObsolescenceMap, branchMap *models.BranchMap) obsolescenceChains {
+// Returns an UnsupportedObsolescenceError if any action in `obsmap` cannot be
+// turned into an obsolescence chain.
+func buildObsolescenceChains(repo *git.Repository, obsmap *models.ObsolescenceMap, branchMap *models.BranchMap) (obsolescenceChains, error) {
 	branches := gitutil.LookupBranches(repo, branchMap.ListBranchNames()...)
 	chains := []obsolescenceChain{}
-	for _, action := range obsmap.Actions {
-		chains = append(chains, buildObsolescenceChain(repo, branches, action))
+	for i, action := range obsmap.Actions {
+		chain, reason, commits := buildObsolescenceChain(repo, branches, action)
+		if reason != "" {
+			return nil, &UnsupportedObsolescenceError{
+				ActionIndex: i,
+				ActionType:  action.ActionType,
+				Commits:     commits,
+				Reason:      reason,
+			}
+		}
+		if chain != nil {
+			chains = append(chains, *chain)
+		}
 	}
-	return chains
+	return chains, nil
 }
 
 // Maps a commit to a list of the commit's children.
@@ -76,13 +111,23 @@ type commitTree struct {
 //     by a commit in the other chain. If so, this chain is obsoleted; the other
 //     is obsoleter.
 //  6. Return the two chains. The root should not appear in either chain.
-func buildObsolescenceChain(repo *git.Repository, trackedBranches []*git.Branch, action models.ObsolescenceAction) obsolescenceChain {
+//
+// If the action is unsupported, returns why along with the offending commits.
+// Returns a nil chain if none of the action's commits are tracked.
+func buildObsolescenceChain(repo *git.Repository, trackedBranches []*git.Branch, action models.ObsolescenceAction) (*obsolescenceChain, string, []git.Oid) {
 	commits := []*git.Commit{}
 	for _, entry := range action.Entries {
+		// Commits that no longer exist can't be evolved; skip them.
+		if entry.Commit == nil || entry.Obsoleter == nil {
+			continue
+		}
 		commits = append(commits, entry.Commit)
 		commits = append(commits, entry.Obsoleter)
 	}
 	commits = uniqueCommits(commits)
+	if len(commits) == 0 {
+		return nil, "", nil
+	}
 
 	// Remove commits that aren't ancestors of the tracked branches.
 	//
@@ -91,18 +136,25 @@ func buildObsolescenceChain(repo *git.Repository, trackedBranches []*git.Branch,
 	rootOid := gitutil.MergeBaseOctopus_Commits(repo, commits...)
 	trackedCommits := gitutil.LocalCommitsFromBranches_RootOid(repo, rootOid, trackedBranches...)
 	commits = subtractCommits(commits, subtractCommits(commits, trackedCommits))
+	if len(commits) == 0 {
+		return nil, "", nil
+	}
 
 	commitTree := createCommitTree(repo, rootOid, commits)
-	fmt.Println("Commit tree:")
-	fmt.Println(commitTree)
-	validateCommitTree(commitTree)
+	if reason, offending := validateCommitTree(commitTree); reason != "" {
+		return nil, reason, offending
+	}
 
 	leftChain := flattenDescendantsToChain(repo, commitTree, 0)
 	rightChain := flattenDescendantsToChain(repo, commitTree, 1)
-	obsoleted, obsoleter := pickObsoletedObsoleter(action, leftChain, rightChain)
+	obsoleted, obsoleter, ok := pickObsoletedObsoleter(action, leftChain, rightChain)
+	if !ok {
+		offending := append(commitIdsOf(leftChain), commitIdsOf(rightChain)...)
+		return nil, "neither side of the action obsoletes the other", offending
+	}
 
 	root, _ := repo.LookupCommit(rootOid)
-	return obsolescenceChain{root: root, obsoleted: obsoleted, obsoleter: obsoleter}
+	return &obsolescenceChain{root: root, obsoleted: obsoleted, obsoleter: obsoleter}, "", nil
 }
 
 func createCommitTree(repo *git.Repository, root *git.Oid, commits []*git.Commit) commitTree {
@@ -110,28 +162,50 @@ func createCommitTree(repo *git.Repository, root *git.Oid, commits []*git.Commit
 		*root: {},
 	}
 
-	// Add every commit and its ancestors to the tree.
-	for _, commit := range commits {
-		for !commit.Id().Equal(root) {
-			parent := commit.Parent(0)
-			tree[*parent.Id()] = tree[*parent.Id()].Add(*commit.Id())
-			commit = parent
+	// Add every commit and its ancestors up to `root` to the tree. Parents
+	// merged in from outside the tree (i.e., not descending from `root`) are
+	// left out.
+	visited := map[git.Oid]bool{}
+	toVisit := commits
+	for len(toVisit) > 0 {
+		commit := toVisit[0]
+		toVisit = toVisit[1:]
+		if commit.Id().Equal(root) || visited[*commit.Id()] {
+			continue
+		}
+		visited[*commit.Id()] = true
+
+		for i := uint(0); i < commit.ParentCount(); i++ {
+			parentOid := commit.ParentId(i)
+			if !parentOid.Equal(root) {
+				if isDescendant, _ := repo.DescendantOf(parentOid, root); !isDescendant {
+					continue
+				}
+			}
+			tree[*parentOid] = tree[*parentOid].Add(*commit.Id())
+			toVisit = append(toVisit, commit.Parent(i))
 		}
 	}
 	return commitTree{root: *root, tree: tree}
 }
 
 // The root node should have two children. All other commits should have 1 child.
-func validateCommitTree(commitTree commitTree) {
-	rootChildren := len(commitTree.tree[commitTree.root])
-	if rootChildren < 1 || rootChildren > 2 {
-		log.Fatalf("Invalid commitTree: root has %d children\n", len(commitTree.tree[commitTree.root]))
+//
+// Returns why the tree is invalid along with the offending commits, or "" if
+// the tree is valid.
+func validateCommitTree(commitTree commitTree) (string, []git.Oid) {
+	rootChildren := commitTree.tree[commitTree.root]
+	if len(rootChildren) < 1 || len(rootChildren) > 2 {
+		offending := append([]git.Oid{commitTree.root}, rootChildren...)
+		return fmt.Sprintf("the commits branch off their common ancestor %d ways", len(rootChildren)), offending
 	}
 	for oid, children := range commitTree.tree {
 		if !oid.Equal(&commitTree.root) && len(children) != 1 {
-			log.Fatalf("Invalid commitTree: %s has %d children\n", gitutil.OidShortHash(oid), len(children))
+			offending := append([]git.Oid{oid}, children...)
+			return fmt.Sprintf("commit %s has %d children within the action", gitutil.OidShortHash(oid), len(children)), offending
 		}
 	}
+	return "", nil
 }
 
 // Split the commit tree into the two chains extending from the root commit.
@@ -154,13 +228,14 @@ func flattenDescendantsToChain(repo *git.Repository, commitTree commitTree, inde
 	return chain
 }
 
-// Returns (<obsoleted-chain>, <obsoleter-chain>).
-func pickObsoletedObsoleter(action models.ObsolescenceAction, leftChain, rightChain []*git.Commit) ([]*git.Commit, []*git.Commit) {
+// Returns (<obsoleted-chain>, <obsoleter-chain>, true), or false if neither
+// chain obsoleted the other.
+func pickObsoletedObsoleter(action models.ObsolescenceAction, leftChain, rightChain []*git.Commit) ([]*git.Commit, []*git.Commit, bool) {
 	if len(leftChain) > 0 && len(rightChain) == 0 {
-		return rightChain, leftChain
+		return rightChain, leftChain, true
 	}
 	if len(rightChain) > 0 && len(leftChain) == 0 {
-		return leftChain, rightChain
+		return leftChain, rightChain, true
 	}
 
 	// Create a set of the Oid's in each chain.
@@ -173,20 +248,21 @@ func pickObsoletedObsoleter(action models.ObsolescenceAction, leftChain, rightCh
 	}
 
 	for _, entry := range action.Entries {
+		if entry.Commit == nil || entry.Obsoleter == nil {
+			continue
+		}
 		_, obsoletedLeft := leftOids[*entry.Commit.Id()]
 		_, obsoletedRight := rightOids[*entry.Commit.Id()]
 		_, obsoleterLeft := leftOids[*entry.Obsoleter.Id()]
 		_, obsoleterRight := rightOids[*entry.Obsoleter.Id()]
 		if obsoletedLeft && obsoleterRight {
-			return leftChain, rightChain
+			return leftChain, rightChain, true
 		}
 		if obsoletedRight && obsoleterLeft {
-			return rightChain, leftChain
+			return rightChain, leftChain, true
 		}
 	}
-
-	log.Fatal("Neither `leftChain` nor `rightChain` had an obsoleted -> obsoleter relationship")
-	return leftChain, rightChain
+	return nil, nil, false
 }
 
 func uniqueCommits(commits []*git.Commit) []*git.Commit {
@@ -202,6 +278,14 @@ func uniqueCommits(commits []*git.Commit) []*git.Commit {
 	return unique
 }
 
+func commitIdsOf(commits []*git.Commit) []git.Oid {
+	oids := []git.Oid{}
+	for _, commit := range commits {
+		oids = append(oids, *commit.Id())
+	}
+	return oids
+}
+
 // Returns a list of commits from `a` that aren't in `b`.
 func subtractCommits(a []*git.Commit, b []*git.Commit) []*git.Commit {
 	oidsB := map[git.Oid]bool{}
@@ -21,8 +21,7 @@ import (
 func ObsoletePreRebase(repo *git.Repository) error {
 	// Add a new Obsolescence Action with the Rebase ActionType.
 	obsmapFile := store.ObsoleteMapPath(repo.Path())
-	store.AppendObsolescenceAction(repo, obsmapFile, models.ActionTypeRebase)
-	return nil
+	return store.AppendObsolescenceAction(repo, obsmapFile, models.ActionTypeRebase)
 }
 
 // -------------------------------------------------------------------------- \
@@ -38,8 +37,9 @@ func ObsoletePostRewriteAmend(repo *git.Repository, lines []string) error {
 	// receive `post-rewrite.amend` after `pre-commit`, we know this is an Amend
 	// action, not a Commit action.
 	obsmapFile := store.ObsoleteMapPath(repo.Path())
-	if store.LastObsolescenceActionType(repo, obsmapFile) == models.ActionTypeCommit {
-		store.SetLastObsolescenceActionType(repo, obsmapFile, models.ActionTypeAmend)
+	err := store.ReplaceLastObsolescenceActionType(repo, obsmapFile, models.ActionTypeCommit, models.ActionTypeAmend)
+	if err != nil {
+		return err
 	}
 
 	return appendEntriesToObsoleteMap(repo, lines, models.PostRewriteAmend)
@@ -70,7 +70,9 @@ func ObsoletePreCommit(repo *git.Repository) error {
 	if headCommit.ParentCount() > 0 {
 		headParent = headCommit.ParentId(0).String()
 	}
-	utils.OverwriteFile(store.PreCommitParentPath(repo.Path()), headParent)
+	if err := utils.OverwriteFile(store.PreCommitParentPath(repo.Path()), headParent); err != nil {
+		return err
+	}
 
 	// If an interactive rebase is in-progress, `pre-commit` was triggered
 	// within the rebase. Don't add a new action.
@@ -82,8 +84,7 @@ func ObsoletePreCommit(repo *git.Repository) error {
 	// If it was an Amend action, we'll modify the type of this action when the
 	// `post-rewrite.amend` hook fires.
 	obsmapFile := store.ObsoleteMapPath(repo.Path())
-	store.AppendObsolescenceAction(repo, obsmapFile, models.ActionTypeCommit)
-	return nil
+	return store.AppendObsolescenceAction(repo, obsmapFile, models.ActionTypeCommit)
 }
 
 // -------------------------------------------------------------------------- \
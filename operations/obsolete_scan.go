@@ -0,0 +1,197 @@
+package operations
+
+import (
+	"strings"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// -------------------------------------------------------------------------- \
+// ObsoleteScan                                                               |
+// -------------------------------------------------------------------------- /
+
+type reflogScanner struct {
+	repo *git.Repository
+	// Set of commits reachable from the tracked branches.
+	tracked map[git.Oid]bool
+	// Set of commits that are already obsolete.
+	obsolete map[git.Oid]bool
+	// Cache of the patch ID of each commit.
+	patchIds map[git.Oid]string
+}
+
+// Record the obsolescences that the git-hooks missed, by reconciling the
+// reflogs of the tracked branches with the obsolescence map.
+//
+// Tools that rewrite history without running the git-hooks (e.g. IDEs using
+// libgit2 or JGit, `git reset`, `git cherry-pick`, or `--no-verify`) still
+// leave an entry in the reflog of the branch they move:
+//   - A `commit (amend)` entry obsoletes the old commit by the new one.
+//   - Any other entry that drops commits from the branch obsoletes each dropped
+//     commit by the commit of the branch with the same patch ID (i.e. its
+//     rebased or cherry-picked copy).
+//
+// Only commits that are still reachable from the tracked branches and are not
+// obsolete yet are recorded, so scanning again adds nothing new.
+//
+// Returns the number of entries that were added.
+func ObsoleteScan(repo *git.Repository) (int, error) {
+	actions, added, err := scanReflogs(repo)
+	if err != nil || added == 0 {
+		return 0, err
+	}
+
+	obsmapFile := store.ObsoleteMapPath(repo.Path())
+	err = store.UpdateObsolescenceMap(repo, obsmapFile, func(obsmap *models.ObsolescenceMap) error {
+		obsmap.Actions = append(obsmap.Actions, actions...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// Returns the actions ObsoleteScan would record, and the number of entries
+// they hold, without recording them.
+func scanReflogs(repo *git.Repository) ([]models.ObsolescenceAction, int, error) {
+	tracked, err := trackedCommitOids(repo)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Only rewrites count as recorded: a commit that merely got a child through
+	// `post-commit` can still be rewritten without the git-hooks.
+	obsolete, err := obsoleteCommitSet(repo)
+	if err != nil {
+		return nil, 0, err
+	}
+	scanner := reflogScanner{
+		repo:     repo,
+		tracked:  tracked,
+		obsolete: obsolete,
+		patchIds: map[git.Oid]string{},
+	}
+
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return nil, 0, err
+	}
+	actions := []models.ObsolescenceAction{}
+	added := 0
+	for _, branch := range gitutil.LookupBranches(repo, branchMap.ListBranchNames()...) {
+		// Skip branches deleted outside of git-tree.
+		if branch == nil {
+			continue
+		}
+		for _, action := range scanner.scanBranch(branch) {
+			actions = append(actions, action)
+			added += len(action.Entries)
+		}
+	}
+	return actions, added, nil
+}
+
+// Returns an action for each entry of the reflog of `branch` that obsoleted
+// commits, from oldest to newest.
+func (s *reflogScanner) scanBranch(branch *git.Branch) []models.ObsolescenceAction {
+	actions := []models.ObsolescenceAction{}
+	for _, entry := range gitutil.BranchReflog(s.repo, gitutil.BranchName(branch)) {
+		if entry.Old.IsZero() || entry.Old.Equal(&entry.New) {
+			continue
+		}
+
+		var action models.ObsolescenceAction
+		if strings.HasPrefix(entry.Message, "commit (amend)") {
+			action = s.amendAction(entry)
+		} else {
+			action = s.rewriteAction(entry, branch.Target())
+		}
+
+		if len(action.Entries) > 0 {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// The commit the branch pointed to before the amend is obsoleted by the one it
+// points to after.
+func (s *reflogScanner) amendAction(entry gitutil.ReflogEntry) models.ObsolescenceAction {
+	action := models.ObsolescenceAction{ActionType: models.ActionTypeAmend}
+
+	old, err := s.repo.LookupCommit(&entry.Old)
+	if err != nil || !s.isUnrecorded(old) {
+		return action
+	}
+	amended, err := s.repo.LookupCommit(&entry.New)
+	if err != nil {
+		return action
+	}
+
+	s.obsolete[*old.Id()] = true
+	action.Entries = append(action.Entries, models.ObsolescenceEntry{
+		Commit:    old,
+		Obsoleter: amended,
+		HookType:  models.ReflogScan,
+	})
+	return action
+}
+
+// Each commit that `entry` dropped from the branch is obsoleted by the commit
+// with the same patch ID among those the branch (at `tip`) gained since.
+func (s *reflogScanner) rewriteAction(entry gitutil.ReflogEntry, tip *git.Oid) models.ObsolescenceAction {
+	action := models.ObsolescenceAction{ActionType: models.ActionTypeRebase}
+
+	dropped := gitutil.CommitsBetween(s.repo, &entry.New, &entry.Old)
+	if len(dropped) == 0 {
+		return action
+	}
+
+	// The copies of the dropped commits are not reachable from the old branch.
+	copies := map[string]*git.Commit{}
+	for _, commit := range gitutil.CommitsBetween(s.repo, &entry.Old, tip) {
+		if patchId, ok := s.patchId(commit); ok {
+			copies[patchId] = commit
+		}
+	}
+
+	for _, commit := range dropped {
+		if !s.isUnrecorded(commit) {
+			continue
+		}
+		patchId, ok := s.patchId(commit)
+		if !ok {
+			continue
+		}
+		rewritten, ok := copies[patchId]
+		if !ok {
+			continue
+		}
+
+		s.obsolete[*commit.Id()] = true
+		action.Entries = append(action.Entries, models.ObsolescenceEntry{
+			Commit:    commit,
+			Obsoleter: rewritten,
+			HookType:  models.ReflogScan,
+		})
+	}
+	return action
+}
+
+// Returns true if `commit` is reachable from the tracked branches, but is not
+// obsolete yet.
+func (s *reflogScanner) isUnrecorded(commit *git.Commit) bool {
+	return s.tracked[*commit.Id()] && !s.obsolete[*commit.Id()]
+}
+
+func (s *reflogScanner) patchId(commit *git.Commit) (string, bool) {
+	if patchId, ok := s.patchIds[*commit.Id()]; ok {
+		return patchId, patchId != ""
+	}
+	patchId, _ := gitutil.PatchId(s.repo, commit)
+	s.patchIds[*commit.Id()] = patchId
+	return patchId, patchId != ""
+}
@@ -0,0 +1,155 @@
+package operations
+
+import (
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	git "github.com/libgit2/git2go/v34"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ObsoleteScanTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *ObsoleteScanTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *ObsoleteScanTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+func (suite *ObsoleteScanTestSuite) branchCommit(name string) *git.Commit {
+	return gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch(name).Target())
+}
+
+func (suite *ObsoleteScanTestSuite) readObsmap() *models.ObsolescenceMap {
+	obsmap, err := store.ReadObsolescenceMap(suite.repo.Repo, store.ObsoleteMapPath(suite.repo.Repo.Path()))
+	assert.Nil(suite.T(), err)
+	return obsmap
+}
+
+// Branches:
+//
+//	master ─── mew ─── treecko
+//
+// Action:
+//   - Amend [mew] without running the git-hooks
+func (suite *ObsoleteScanTestSuite) TestObsoleteScan_FindsAmendWithoutHooks() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("mew")
+	oldMew := suite.branchCommit("mew")
+	suite.repo.AmendCommit("mew amended")
+
+	added, err := ObsoleteScan(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, added)
+
+	obsmap := suite.readObsmap()
+	assert.Equal(suite.T(), 1, len(obsmap.Actions))
+	assert.Equal(suite.T(), models.ActionTypeAmend, obsmap.Actions[0].ActionType)
+	entry := obsmap.Actions[0].Entries[0]
+	assert.Equal(suite.T(), *oldMew.Id(), *entry.Commit.Id())
+	assert.Equal(suite.T(), *suite.branchCommit("mew").Id(), *entry.Obsoleter.Id())
+	assert.Equal(suite.T(), models.ReflogScan, entry.HookType)
+
+	// Evolve can now fix treecko.
+	result := Evolve(suite.repo.Repo)
+
+	assert.Equal(suite.T(), EvolveSuccess, result.Type)
+	assert.Equal(suite.T(), *suite.branchCommit("mew").Id(), *suite.branchCommit("treecko").ParentId(0))
+}
+
+// Branches:
+//
+//	master ─┬─ mew ─── treecko
+//	        └─ mudkip
+//
+// Action:
+//   - Move [mew] onto [mudkip] without running the git-hooks
+func (suite *ObsoleteScanTestSuite) TestObsoleteScan_MatchesRewrittenCommitsByPatchId() {
+	suite.repo.BranchWithCommit("mudkip")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	oldMew := suite.branchCommit("mew")
+	newMew, _ := gitutil.RecreateCommit(suite.repo.Repo, oldMew, []git.Oid{*suite.repo.LookupBranch("mudkip").Target()})
+	mew := suite.repo.LookupBranch("mew")
+	gitutil.MoveBranchTarget(suite.repo.Repo, &mew, &newMew)
+
+	added, err := ObsoleteScan(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, added)
+
+	obsmap := suite.readObsmap()
+	assert.Equal(suite.T(), models.ActionTypeRebase, obsmap.Actions[0].ActionType)
+	assert.Equal(suite.T(), *oldMew.Id(), *obsmap.Actions[0].Entries[0].Commit.Id())
+	assert.Equal(suite.T(), newMew, *obsmap.Actions[0].Entries[0].Obsoleter.Id())
+}
+
+// Branches:
+//
+//	master ─── mew ─── treecko
+//
+// Actions:
+//   - Commit [treecko] on top of [mew], with the git-hooks installed
+//   - Amend [mew] with `--no-verify`
+func (suite *ObsoleteScanTestSuite) TestObsoleteScan_FindsAmendOfCommitWithRecordedChild() {
+	suite.repo.BranchWithCommit("mew")
+	Init(suite.repo.Repo)
+
+	suite.repo.CreateAndSwitchBranch("treecko")
+	ObsoletePreCommit(suite.repo.Repo)
+	suite.repo.WriteAndCommitFile("treecko", "treecko", "treecko")
+	ObsoletePostCommit(suite.repo.Repo)
+	Track(suite.repo.Repo, suite.repo.LookupBranch("treecko"))
+
+	suite.repo.SwitchBranch("mew")
+	oldMew := suite.branchCommit("mew")
+	suite.repo.AmendCommit("mew amended")
+
+	added, err := ObsoleteScan(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, added)
+
+	obsmap := suite.readObsmap()
+	action := obsmap.Actions[len(obsmap.Actions)-1]
+	assert.Equal(suite.T(), models.ActionTypeAmend, action.ActionType)
+	assert.Equal(suite.T(), *oldMew.Id(), *action.Entries[0].Commit.Id())
+	assert.Equal(suite.T(), models.ReflogScan, action.Entries[0].HookType)
+}
+
+func (suite *ObsoleteScanTestSuite) TestObsoleteScan_SkipsRecordedRewrites() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("mew")
+	suite.repo.AmendCommit("mew amended")
+	ObsoleteScan(suite.repo.Repo)
+	obsmapBefore := suite.repo.ReadFile(".git/tree/obsmap")
+
+	added, err := ObsoleteScan(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, added)
+	assert.Equal(suite.T(), obsmapBefore, suite.repo.ReadFile(".git/tree/obsmap"))
+}
+
+func TestObsoleteScanTestSuite(t *testing.T) {
+	suite.Run(t, new(ObsoleteScanTestSuite))
+}
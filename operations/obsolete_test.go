@@ -1,8 +1,10 @@
 package operations
 
 import (
+	"sync"
 	"testing"
 
+	"github.com/acamadeo/git-tree/store"
 	"github.com/acamadeo/git-tree/testutil"
 	"github.com/acamadeo/git-tree/utils"
 	"github.com/stretchr/testify/assert"
@@ -31,7 +33,12 @@ func (suite *ObsoleteTestSuite) TestObsoletePreRebase_AddActionToObsmap() {
 	filename := suite.repo.Repo.Path() + "tree/obsmap"
 	assert.True(suite.T(), utils.FileExists(filename), "Expected file %q to exist, but it does not", filename)
 
-	wantString := "action rebase"
+	wantString := `{
+  "version": 2,
+  "actions": [
+    {"type": "rebase", "entries": []}
+  ]
+}`
 	gotString := suite.repo.ReadFile(".git/tree/obsmap")
 	assert.Equal(suite.T(), wantString, gotString)
 }
@@ -48,14 +55,40 @@ func (suite *ObsoleteTestSuite) TestObsoletePostRewriteAmend_AddActionAndEntryTo
 	assert.True(suite.T(), utils.FileExists(filename), "Expected file %q to exist, but it does not", filename)
 
 	// Obsmap:
-	//   `action amend` (the action header)
+	//   An action of type `amend`
 	//   `Parent of HEAD (obsoleted)` - `HEAD (obsoleter)` - `post-rewrite.rebase`
-	wantString := `action amend
-cf59c4bf9d3036b68242d6e9db30c0d7654326b6 3316a58b9dd84c7b1864a3eb4d398ca643ac23c7 post-rewrite.amend`
+	wantString := `{
+  "version": 2,
+  "actions": [
+    {
+      "type": "amend",
+      "entries": [
+        {"commit": "cf59c4bf9d3036b68242d6e9db30c0d7654326b6", "obsoleter": "3316a58b9dd84c7b1864a3eb4d398ca643ac23c7", "hook": "post-rewrite.amend"}
+      ]
+    }
+  ]
+}`
 	gotString := suite.repo.ReadFile(".git/tree/obsmap")
 	assert.Equal(suite.T(), wantString, gotString)
 }
 
+func (suite *ObsoleteTestSuite) TestObsoletePostRewriteAmend_KeepsCommitsReachable() {
+	suite.repo.BranchWithCommit("treecko")
+
+	ObsoletePreCommit(suite.repo.Repo)
+	ObsoletePostRewriteAmend(suite.repo.Repo, []string{"cf59c4bf9d3036b68242d6e9db30c0d7654326b6 3316a58b9dd84c7b1864a3eb4d398ca643ac23c7"})
+
+	// Both the obsoleted and obsoleter commits are kept by hidden refs, so that
+	// `git gc` does not delete them.
+	for _, hash := range []string{"cf59c4bf9d3036b68242d6e9db30c0d7654326b6", "3316a58b9dd84c7b1864a3eb4d398ca643ac23c7"} {
+		ref, err := suite.repo.Repo.References.Lookup(store.ObsolescenceRefPrefix + hash)
+		assert.Nil(suite.T(), err)
+		if err == nil {
+			assert.Equal(suite.T(), hash, ref.Target().String())
+		}
+	}
+}
+
 func (suite *ObsoleteTestSuite) TestObsoletePostRewriteRebase_AddActionAndEntryToObsmap() {
 	suite.repo.BranchWithCommit("treecko")
 
@@ -68,10 +101,19 @@ func (suite *ObsoleteTestSuite) TestObsoletePostRewriteRebase_AddActionAndEntryT
 	assert.True(suite.T(), utils.FileExists(filename), "Expected file %q to exist, but it does not", filename)
 
 	// Obsmap:
-	//   `action rebase` (the action header)
+	//   An action of type `rebase`
 	//   `Parent of HEAD (obsoleted)` - `HEAD (obsoleter)` - `post-rewrite.rebase`
-	wantString := `action rebase
-cf59c4bf9d3036b68242d6e9db30c0d7654326b6 3316a58b9dd84c7b1864a3eb4d398ca643ac23c7 post-rewrite.rebase`
+	wantString := `{
+  "version": 2,
+  "actions": [
+    {
+      "type": "rebase",
+      "entries": [
+        {"commit": "cf59c4bf9d3036b68242d6e9db30c0d7654326b6", "obsoleter": "3316a58b9dd84c7b1864a3eb4d398ca643ac23c7", "hook": "post-rewrite.rebase"}
+      ]
+    }
+  ]
+}`
 	gotString := suite.repo.ReadFile(".git/tree/obsmap")
 	assert.Equal(suite.T(), wantString, gotString)
 }
@@ -99,7 +141,12 @@ func (suite *ObsoleteTestSuite) TestObsoletePreCommit_AddActionToObsmap() {
 	filename := suite.repo.Repo.Path() + "tree/obsmap"
 	assert.True(suite.T(), utils.FileExists(filename), "Expected file %q to exist, but it does not", filename)
 
-	wantString := "action commit"
+	wantString := `{
+  "version": 2,
+  "actions": [
+    {"type": "commit", "entries": []}
+  ]
+}`
 	gotString := suite.repo.ReadFile(".git/tree/obsmap")
 	assert.Equal(suite.T(), wantString, gotString)
 }
@@ -117,10 +164,19 @@ func (suite *ObsoleteTestSuite) TestObsoletePostCommit_AddEntryToObsmap() {
 	assert.True(suite.T(), utils.FileExists(filename), "Expected file %q to exist, but it does not", filename)
 
 	// Obsmap:
-	//   `action commit` (the action header)
+	//   An action of type `commit`
 	//   `Parent of HEAD (obsoleted)` - `HEAD (obsoleter)` - `post-commit`
-	wantString := `action commit
-3316a58b9dd84c7b1864a3eb4d398ca643ac23c7 d916506e4a229b277e6658504ec0321dabe9d797 post-commit`
+	wantString := `{
+  "version": 2,
+  "actions": [
+    {
+      "type": "commit",
+      "entries": [
+        {"commit": "3316a58b9dd84c7b1864a3eb4d398ca643ac23c7", "obsoleter": "d916506e4a229b277e6658504ec0321dabe9d797", "hook": "post-commit"}
+      ]
+    }
+  ]
+}`
 	gotString := suite.repo.ReadFile(".git/tree/obsmap")
 	assert.Equal(suite.T(), wantString, gotString)
 }
@@ -145,11 +201,51 @@ func (suite *ObsoleteTestSuite) TestObsoletePostCommit_DontAddEntryIfCommitWasAm
 	//
 	// NOTE: The action is still marked as `commit` because it gets changed in
 	// the `post-rewrite.amend` hook.
-	wantString := "action commit"
+	wantString := `{
+  "version": 2,
+  "actions": [
+    {"type": "commit", "entries": []}
+  ]
+}`
 	gotString := suite.repo.ReadFile(".git/tree/obsmap")
 	assert.Equal(suite.T(), wantString, gotString)
 }
 
+func (suite *ObsoleteTestSuite) TestObsoletePreRebase_ConcurrentHooksKeepAllActions() {
+	suite.repo.BranchWithCommit("treecko")
+
+	// Simulate several git processes firing hooks at the same time.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ObsoletePreRebase(suite.repo.Repo)
+		}()
+	}
+	wg.Wait()
+
+	obsmap, err := store.ReadObsolescenceMap(suite.repo.Repo, store.ObsoleteMapPath(suite.repo.Repo.Path()))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 8, len(obsmap.Actions))
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/lock"))
+}
+
+func (suite *ObsoleteTestSuite) TestObsoletePreRebase_TakesOverStaleLock() {
+	suite.repo.BranchWithCommit("treecko")
+
+	// Leave behind the lock of a process that no longer exists.
+	suite.repo.WriteFile(".git/tree/lock", "999999999")
+
+	err := ObsoletePreRebase(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	obsmap, err := store.ReadObsolescenceMap(suite.repo.Repo, store.ObsoleteMapPath(suite.repo.Repo.Path()))
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(obsmap.Actions))
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/lock"))
+}
+
 func TestObsoleteTestSuite(t *testing.T) {
 	suite.Run(t, new(ObsoleteTestSuite))
 }
@@ -0,0 +1,337 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// Start recording `operation` in the operation log by capturing the state of
+// the repository before it runs.
+//
+// The entry stays pending until FinishOplogEntry is called, which lets
+// operations interrupted by a merge conflict be recorded once they continue.
+//
+// Returns an error if the entry could not be written, in which case the
+// operation should not run, since it could not be undone.
+func BeginOplogEntry(repo *git.Repository, operation string) error {
+	entry := &models.OplogEntry{
+		Operation: operation,
+		Before:    takeSnapshot(repo),
+	}
+	if err := store.WriteOplogEntry(entry, store.OplogPendingPath(repo.Path())); err != nil {
+		DiscardOplogEntry(repo)
+		return fmt.Errorf("Could not record %q in the operation log: %s.", operation, err.Error())
+	}
+	return nil
+}
+
+// Complete the pending operation log entry, or discard it if the operation
+// failed with `err`.
+//
+// The entry is kept pending if the operation was interrupted and is still in
+// progress. Entries that were undone can no longer be redone once a new entry
+// is recorded.
+func FinishOplogEntry(repo *git.Repository, err error) {
+	pendingPath := store.OplogPendingPath(repo.Path())
+	if !utils.DirExists(pendingPath) || operationInProgress(repo) != "" {
+		return
+	}
+	if err != nil {
+		DiscardOplogEntry(repo)
+		return
+	}
+
+	entry := store.ReadOplogEntry(pendingPath)
+	entry.After = takeSnapshot(repo)
+	restrictToTrackedBranches(entry)
+	DiscardOplogEntry(repo)
+
+	// Operations that did not change anything (e.g. an aborted rebase) are not
+	// worth undoing.
+	if snapshotsEqual(entry.Before, entry.After) {
+		return
+	}
+
+	gitPath := repo.Path()
+	position := store.ReadOplogPosition(gitPath)
+	for index := store.OplogLength(gitPath); index > position; index-- {
+		os.RemoveAll(store.OplogEntryPath(gitPath, index))
+	}
+
+	// The position is only bumped once the entry is fully written, so a
+	// half-written entry is never undone.
+	if store.WriteOplogEntry(entry, store.OplogEntryPath(gitPath, position+1)) == nil {
+		store.WriteOplogPosition(gitPath, position+1)
+	}
+}
+
+// Discard the pending operation log entry (e.g. when an operation is aborted).
+func DiscardOplogEntry(repo *git.Repository) {
+	os.RemoveAll(store.OplogPendingPath(repo.Path()))
+}
+
+// Returns the entries of the operation log, oldest first, along with the
+// number of entries that are currently applied.
+func ReadOplog(repo *git.Repository) ([]*models.OplogEntry, int) {
+	gitPath := repo.Path()
+
+	entries := []*models.OplogEntry{}
+	for index := 1; index <= store.OplogLength(gitPath); index++ {
+		entries = append(entries, store.ReadOplogEntry(store.OplogEntryPath(gitPath, index)))
+	}
+	return entries, store.ReadOplogPosition(gitPath)
+}
+
+// Restore the repository to the state before the last applied operation.
+//
+// Returns the entry of the undone operation.
+func Undo(repo *git.Repository) (*models.OplogEntry, error) {
+	if err := validateOplogRestore(repo, "undo"); err != nil {
+		return nil, err
+	}
+
+	gitPath := repo.Path()
+	position := store.ReadOplogPosition(gitPath)
+	if position == 0 {
+		return nil, errors.New("Nothing to undo.")
+	}
+
+	entry := store.ReadOplogEntry(store.OplogEntryPath(gitPath, position))
+	if err := restoreSnapshot(repo, entry.After, entry.Before); err != nil {
+		return nil, err
+	}
+
+	if err := store.WriteOplogPosition(gitPath, position-1); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Replay the last undone operation.
+//
+// Returns the entry of the redone operation.
+func Redo(repo *git.Repository) (*models.OplogEntry, error) {
+	if err := validateOplogRestore(repo, "redo"); err != nil {
+		return nil, err
+	}
+
+	gitPath := repo.Path()
+	position := store.ReadOplogPosition(gitPath)
+	if position == store.OplogLength(gitPath) {
+		return nil, errors.New("Nothing to redo.")
+	}
+
+	entry := store.ReadOplogEntry(store.OplogEntryPath(gitPath, position+1))
+	if err := restoreSnapshot(repo, entry.Before, entry.After); err != nil {
+		return nil, err
+	}
+
+	if err := store.WriteOplogPosition(gitPath, position+1); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func validateOplogRestore(repo *git.Repository, action string) error {
+	if err := validateNoOperationInProgress(repo, action); err != nil {
+		return err
+	}
+	dirty, err := gitutil.HasUncommittedChanges(repo)
+	if err != nil {
+		return fmt.Errorf("Cannot %s: could not read the status of the working tree: %s", action, err.Error())
+	}
+	if dirty {
+		return fmt.Errorf("Cannot %s with uncommitted changes. Commit or stash them first", action)
+	}
+	return nil
+}
+
+// Capture the target of every local branch, HEAD and the git-tree files.
+//
+// Every branch is captured since the branches an operation touches are only
+// known once it finishes. See `restrictToTrackedBranches()`.
+func takeSnapshot(repo *git.Repository) models.RepoSnapshot {
+	snapshot := models.RepoSnapshot{
+		Branches: map[string]git.Oid{},
+		Head:     headString(repo),
+		Files:    map[string]string{},
+	}
+
+	for _, branch := range gitutil.AllLocalBranches(repo) {
+		snapshot.Branches[gitutil.BranchName(branch)] = *branch.Target()
+	}
+
+	for _, file := range store.OplogSnapshotFiles {
+		path := store.GitTreeFilePath(repo.Path(), file)
+		if utils.FileExists(path) {
+			snapshot.Files[filepath.Base(path)] = utils.ReadFile(path)
+		}
+	}
+	return snapshot
+}
+
+// Only keep the branches tracked before or after the operation (including the
+// root branch). Branches missing from one of the snapshots are recorded with
+// the zero oid.
+func restrictToTrackedBranches(entry *models.OplogEntry) {
+	names := map[string]bool{store.GitTreeRootBranch: true}
+	for _, snapshot := range []models.RepoSnapshot{entry.Before, entry.After} {
+		for _, name := range store.BranchMapFileNames(snapshot.Files["branches"]) {
+			names[name] = true
+		}
+	}
+
+	restrict := func(branches map[string]git.Oid) map[string]git.Oid {
+		output := map[string]git.Oid{}
+		for name := range names {
+			output[name] = branches[name]
+		}
+		return output
+	}
+	entry.Before.Branches = restrict(entry.Before.Branches)
+	entry.After.Branches = restrict(entry.After.Branches)
+}
+
+func snapshotsEqual(a models.RepoSnapshot, b models.RepoSnapshot) bool {
+	if a.Head != b.Head || len(a.Branches) != len(b.Branches) || len(a.Files) != len(b.Files) {
+		return false
+	}
+	for name, oid := range a.Branches {
+		if other, ok := b.Branches[name]; !ok || !other.Equal(&oid) {
+			return false
+		}
+	}
+	for name, contents := range a.Files {
+		if other, ok := b.Files[name]; !ok || other != contents {
+			return false
+		}
+	}
+	return true
+}
+
+// Move the repository from snapshot `current` to snapshot `target`.
+//
+// Every branch is validated before anything is modified, so the restore is
+// refused as a whole if any branch moved since the snapshot was taken. If
+// the restore then fails partway, the branches, git-tree files and HEAD are
+// put back as they were.
+func restoreSnapshot(repo *git.Repository, current models.RepoSnapshot, target models.RepoSnapshot) error {
+	changed := []string{}
+	for name, targetOid := range target.Branches {
+		currentOid := current.Branches[name]
+		if currentOid.Equal(&targetOid) {
+			continue
+		}
+
+		if actual := branchOid(repo, name); !actual.Equal(&currentOid) {
+			return fmt.Errorf("Branch %q has moved since the operation. Cannot restore it", name)
+		}
+		if !targetOid.IsZero() {
+			if _, err := repo.LookupCommit(&targetOid); err != nil {
+				return fmt.Errorf("Commit %s of branch %q no longer exists", gitutil.OidShortHash(targetOid), name)
+			}
+		}
+		changed = append(changed, name)
+	}
+
+	if err := validateOtherWorktrees(repo, changed); err != nil {
+		return err
+	}
+
+	// Keep HEAD where the user left it unless the operation moved it.
+	original := takeSnapshot(repo)
+	head := original.Head
+	if current.Head != target.Head {
+		head = target.Head
+	}
+
+	if err := applySnapshot(repo, changed, target, head); err != nil {
+		applySnapshot(repo, changed, original, original.Head)
+		return fmt.Errorf("%s. Nothing was restored", strings.TrimSuffix(err.Error(), "."))
+	}
+
+	// Update the other worktrees where a restored branch is checked out.
+	restored := []string{}
+	for _, name := range changed {
+		if targetOid := target.Branches[name]; !targetOid.IsZero() {
+			restored = append(restored, name)
+		}
+	}
+	return updateOtherWorktrees(repo, restored)
+}
+
+// Point the `changed` branches and the git-tree files at their state in
+// `snapshot`, then check out `head`.
+func applySnapshot(repo *git.Repository, changed []string, snapshot models.RepoSnapshot, head string) error {
+	// Detach HEAD so that its branch can be moved or deleted, and so the working
+	// tree is checked out relative to the current commit.
+	if headRef, err := repo.Head(); err == nil {
+		repo.SetHeadDetached(headRef.Target())
+	}
+
+	for _, name := range changed {
+		if err := setBranchOid(repo, name, snapshot.Branches[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range store.OplogSnapshotFiles {
+		path := store.GitTreeFilePath(repo.Path(), file)
+		if contents, ok := snapshot.Files[filepath.Base(path)]; ok {
+			if err := store.WriteGitTreeFile(repo.Path(), file, contents); err != nil {
+				return err
+			}
+		} else {
+			os.Remove(path)
+		}
+	}
+
+	// Keep the commits of the restored obsolescence map reachable.
+	obsmap, err := store.ReadObsolescenceMap(repo, store.ObsoleteMapPath(repo.Path()))
+	if err != nil {
+		return err
+	}
+	if err := store.SyncObsolescenceRefs(repo, obsmap); err != nil {
+		return err
+	}
+
+	return checkoutHeadString(repo, head)
+}
+
+// Returns the target of branch `name`, or the zero oid if it does not exist.
+func branchOid(repo *git.Repository, name string) git.Oid {
+	branch, err := repo.LookupBranch(name, git.BranchLocal)
+	if err != nil {
+		return git.Oid{}
+	}
+	return *branch.Target()
+}
+
+// Point branch `name` at `oid`, deleting the branch if `oid` is zero.
+func setBranchOid(repo *git.Repository, name string, oid git.Oid) error {
+	if oid.IsZero() {
+		branch, err := repo.LookupBranch(name, git.BranchLocal)
+		if err != nil {
+			return nil
+		}
+		if err := branch.Delete(); err != nil {
+			return fmt.Errorf("Could not delete branch %q: %s.", name, err.Error())
+		}
+		return nil
+	}
+
+	msg := fmt.Sprintf("[git-tree] restore branch target for %s", name)
+	if _, err := repo.References.Create("refs/heads/"+name, &oid, true, msg); err != nil {
+		return fmt.Errorf("Could not restore branch %q: %s.", name, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package operations
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OplogTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *OplogTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *OplogTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+// Records `Init()` in the operation log.
+func (suite *OplogTestSuite) recordInit() {
+	BeginOplogEntry(suite.repo.Repo, "init")
+	err := Init(suite.repo.Repo)
+	FinishOplogEntry(suite.repo.Repo, err)
+}
+
+// Records rebasing `source` onto `dest` in the operation log.
+func (suite *OplogTestSuite) recordRebase(source string, dest string) {
+	BeginOplogEntry(suite.repo.Repo, "rebase -s "+source+" -d "+dest)
+	result := RebaseTree(suite.repo.Repo, suite.repo.LookupBranch(source), suite.repo.LookupBranch(dest))
+	FinishOplogEntry(suite.repo.Repo, result.Error)
+}
+
+// Initial:
+//
+//	master ─── mew ─┬─ treecko
+//	                └─ mudkip
+func (suite *OplogTestSuite) setupStarterTree() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.BranchWithCommit("mudkip")
+}
+
+func (suite *OplogTestSuite) TestUndo_RestoresBranchesAfterRebase() {
+	suite.setupStarterTree()
+	suite.recordInit()
+	treeckoBefore := *suite.repo.LookupBranch("treecko").Target()
+	branchesBefore := suite.repo.ReadFile(".git/tree/branches")
+
+	suite.recordRebase("treecko", "mudkip")
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("mudkip", "treecko"))
+
+	entry, err := Undo(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "rebase -s treecko -d mudkip", entry.Operation)
+	assert.Equal(suite.T(), treeckoBefore, *suite.repo.LookupBranch("treecko").Target())
+	assert.Equal(suite.T(), branchesBefore, suite.repo.ReadFile(".git/tree/branches"))
+	assert.Equal(suite.T(), "mudkip", gitutil.BranchName(gitutil.HeadBranch(suite.repo.Repo)))
+}
+
+func (suite *OplogTestSuite) TestUndo_FailureRestoresRepository() {
+	suite.setupStarterTree()
+	suite.recordInit()
+	suite.recordRebase("treecko", "mudkip")
+
+	treeckoAfter := *suite.repo.LookupBranch("treecko").Target()
+	branchesAfter := suite.repo.ReadFile(".git/tree/branches")
+	headAfter := headString(suite.repo.Repo)
+
+	// A stale ref lock keeps `treecko` from being restored.
+	suite.repo.WriteFile(".git/refs/heads/treecko.lock", "")
+
+	_, err := Undo(suite.repo.Repo)
+
+	assert.NotNil(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "Nothing was restored")
+	assert.Equal(suite.T(), treeckoAfter, *suite.repo.LookupBranch("treecko").Target())
+	assert.Equal(suite.T(), branchesAfter, suite.repo.ReadFile(".git/tree/branches"))
+	assert.Equal(suite.T(), headAfter, headString(suite.repo.Repo))
+}
+
+func (suite *OplogTestSuite) TestRedo_ReplaysUndoneOperation() {
+	suite.setupStarterTree()
+	suite.recordInit()
+	suite.recordRebase("treecko", "mudkip")
+	treeckoAfter := *suite.repo.LookupBranch("treecko").Target()
+	Undo(suite.repo.Repo)
+
+	entry, err := Redo(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "rebase -s treecko -d mudkip", entry.Operation)
+	assert.Equal(suite.T(), treeckoAfter, *suite.repo.LookupBranch("treecko").Target())
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("mudkip", "treecko"))
+}
+
+func (suite *OplogTestSuite) TestUndo_InitRemovesGitTreeFiles() {
+	suite.setupStarterTree()
+	suite.recordInit()
+
+	_, err := Undo(suite.repo.Repo)
+
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/branches"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("git-tree-root"))
+}
+
+func (suite *OplogTestSuite) TestUndo_RefusedIfBranchMoved() {
+	suite.setupStarterTree()
+	suite.recordInit()
+	suite.recordRebase("treecko", "mudkip")
+
+	// Move treecko after the rebase.
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("grovyle", "grovyle", "grovyle")
+	treeckoMoved := *suite.repo.LookupBranch("treecko").Target()
+
+	_, err := Undo(suite.repo.Repo)
+
+	wantError := errors.New("Branch \"treecko\" has moved since the operation. Cannot restore it")
+	assert.Equal(suite.T(), wantError.Error(), err.Error(),
+		"Operation got error %v, but want error %v", err, wantError)
+	assert.Equal(suite.T(), treeckoMoved, *suite.repo.LookupBranch("treecko").Target())
+}
+
+func (suite *OplogTestSuite) TestUndo_NothingToUndo() {
+	_, err := Undo(suite.repo.Repo)
+
+	wantError := errors.New("Nothing to undo.")
+	assert.Equal(suite.T(), wantError.Error(), err.Error(),
+		"Operation got error %v, but want error %v", err, wantError)
+}
+
+func (suite *OplogTestSuite) TestBeginOplogEntry_ReportsWriteError() {
+	suite.setupStarterTree()
+	Init(suite.repo.Repo)
+
+	// A file in place of the operation log directory makes every write fail.
+	oplogPath := store.OplogPath(suite.repo.Repo.Path())
+	os.RemoveAll(oplogPath)
+	os.WriteFile(oplogPath, []byte{}, 0644)
+
+	err := BeginOplogEntry(suite.repo.Repo, "init")
+
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *OplogTestSuite) TestFinishOplogEntry_DiscardsUndoneEntries() {
+	suite.setupStarterTree()
+	suite.recordInit()
+	suite.recordRebase("treecko", "mudkip")
+	Undo(suite.repo.Repo)
+
+	suite.recordRebase("mudkip", "treecko")
+
+	entries, position := ReadOplog(suite.repo.Repo)
+	assert.Equal(suite.T(), 2, len(entries))
+	assert.Equal(suite.T(), 2, position)
+	assert.Equal(suite.T(), "rebase -s mudkip -d treecko", entries[1].Operation)
+}
+
+func TestOplogTestSuite(t *testing.T) {
+	suite.Run(t, new(OplogTestSuite))
+}
@@ -0,0 +1,203 @@
+package operations
+
+import (
+	"fmt"
+	"strings"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// The branches a RebaseTree operation would move, computed by replaying their
+// commits in memory without creating any branches or commits.
+type RebaseTreePlan struct {
+	// Branches that would be moved, in the order they would be rebased.
+	Branches []RebaseTreePlanBranch
+}
+
+// A branch that would be moved by RebaseTree.
+type RebaseTreePlanBranch struct {
+	Branch string
+	// The branch (or commit) it would be rebased onto.
+	Onto string
+	// The commits that would be rebased, oldest first.
+	Commits []*git.Commit
+	// The commits that would hit a merge conflict.
+	Conflicts []RebaseTreePlanConflict
+}
+
+// A commit that would hit a merge conflict when being rebased.
+type RebaseTreePlanConflict struct {
+	Commit *git.Commit
+	// Position of the commit among the commits of its branch, starting at 1.
+	Step int
+	// Paths of the conflicting files.
+	Paths []string
+}
+
+type rebaseTreePlanner struct {
+	repo      *git.Repository
+	branchMap *models.BranchMap
+	mergeMode gitutil.MergeMode
+	plan      *RebaseTreePlan
+}
+
+// -------------------------------------------------------------------------- \
+// RebaseTreeDryRun                                                           |
+// -------------------------------------------------------------------------- /
+
+// Compute the branches that RebaseTree would move and the merge conflicts it
+// would hit, without rewriting anything.
+//
+// Branches are visited in the same order as RebaseTree. Each commit is
+// cherry-picked in memory onto the predicted result of the previous one. A
+// conflict does not stop the prediction: the conflicting files are assumed to
+// be resolved to the version of the commit being rebased, so conflicts
+// reported after the first one are estimates.
+func RebaseTreeDryRun(repo *git.Repository, source *git.Branch, dest *git.Branch, opts RebaseTreeOptions) (*RebaseTreePlan, error) {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateRebaseTree(repo, source, dest, branchMap); err != nil {
+		return nil, err
+	}
+	return planRebaseTree(repo, branchMap, source, gitutil.BranchName(dest), *dest.Target(), opts)
+}
+
+// Compute the branches that RebaseTreeOntoCommit would move and the merge
+// conflicts it would hit, without rewriting anything. See RebaseTreeDryRun().
+func RebaseTreeOntoCommitDryRun(repo *git.Repository, source *git.Branch, dest *git.Commit, opts RebaseTreeOptions) (*RebaseTreePlan, error) {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateRebaseTreeOntoCommit(repo, source, dest, branchMap); err != nil {
+		return nil, err
+	}
+	return planRebaseTree(repo, branchMap, source, gitutil.CommitShortHash(dest), *dest.Id(), opts)
+}
+
+// Plan rebasing branch `source` and its descendants onto commit `dest`, which
+// is described as `destName`.
+func planRebaseTree(repo *git.Repository, branchMap *models.BranchMap, source *git.Branch, destName string, dest git.Oid, opts RebaseTreeOptions) (*RebaseTreePlan, error) {
+	planner := &rebaseTreePlanner{
+		repo:      repo,
+		branchMap: branchMap,
+		mergeMode: opts.MergeMode,
+		plan:      &RebaseTreePlan{},
+	}
+
+	sourceParent := branchMap.FindParent(gitutil.BranchName(source))
+	destTree, err := gitutil.CommitByOid(repo, dest).Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := planner.planRecurse(*sourceParent.Target(), destName, destTree, source); err != nil {
+		return nil, err
+	}
+	return planner.plan, nil
+}
+
+// Plan rebasing the commits in `toMove` that aren't in `upstream` onto the tree
+// `onto`, the predicted tip of branch `ontoName`, then recurse into the
+// children of `toMove` (see `rebaseTreeRunner.executeRecurse()`).
+func (p *rebaseTreePlanner) planRecurse(upstream git.Oid, ontoName string, onto *git.Tree, toMove *git.Branch) error {
+	branchName := gitutil.BranchName(toMove)
+	planned := RebaseTreePlanBranch{Branch: branchName, Onto: ontoName, Commits: p.commitsToRebase(upstream, toMove)}
+
+	tree := onto
+	for i, commit := range planned.Commits {
+		newTree, paths, err := gitutil.CherrypickOntoTree(p.repo, commit, tree)
+		if err != nil {
+			return err
+		}
+		if len(paths) > 0 {
+			planned.Conflicts = append(planned.Conflicts, RebaseTreePlanConflict{Commit: commit, Step: i + 1, Paths: paths})
+		}
+		tree = newTree
+	}
+	p.plan.Branches = append(p.plan.Branches, planned)
+
+	for _, child := range p.branchMap.FindChildren(branchName) {
+		if err := p.planRecurse(*toMove.Target(), branchName, tree, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the commits in `toMove` that aren't in `upstream` and would be
+// rebased, oldest first.
+//
+// libgit2 drops merge commits when rebasing, so they are skipped unless merges
+// are recreated.
+func (p *rebaseTreePlanner) commitsToRebase(upstream git.Oid, toMove *git.Branch) []*git.Commit {
+	commits := []*git.Commit{}
+	for _, commit := range gitutil.CommitsBetween(p.repo, &upstream, toMove.Target()) {
+		if commit.ParentCount() > 1 && p.mergeMode == gitutil.FlattenMerges {
+			continue
+		}
+		commits = append(commits, commit)
+	}
+	return commits
+}
+
+// Returns the number of commits that would be rebased.
+func (p *RebaseTreePlan) CommitCount() int {
+	count := 0
+	for _, branch := range p.Branches {
+		count += len(branch.Commits)
+	}
+	return count
+}
+
+// Returns the number of commits that would hit a merge conflict.
+func (p *RebaseTreePlan) ConflictCount() int {
+	count := 0
+	for _, branch := range p.Branches {
+		count += len(branch.Conflicts)
+	}
+	return count
+}
+
+// Render the plan.
+//
+// Example:
+//
+//	rebase treecko onto mudkip (2 commits)
+//	    conflict at 1/2 156720b Add pokedex.txt: pokedex.txt
+//	rebase grovyle onto treecko (1 commit)
+//
+//	2 branches, 3 commits, 1 conflict
+func (p *RebaseTreePlan) String() string {
+	output := []string{}
+	for _, branch := range p.Branches {
+		output = append(output, fmt.Sprintf("rebase %s onto %s (%s)", branch.Branch, branch.Onto, countNoun(len(branch.Commits), "commit")))
+		for _, conflict := range branch.Conflicts {
+			output = append(output, fmt.Sprintf("    conflict at %d/%d %s %s: %s",
+				conflict.Step, len(branch.Commits), gitutil.CommitShortHash(conflict.Commit),
+				conflict.Commit.Summary(), strings.Join(conflict.Paths, ", ")))
+		}
+	}
+
+	output = append(output, "", fmt.Sprintf("%s, %s, %s",
+		countNoun(len(p.Branches), "branch"), countNoun(p.CommitCount(), "commit"), countNoun(p.ConflictCount(), "conflict")))
+	return strings.Join(output, "\n")
+}
+
+// Returns e.g. "1 commit" or "2 commits".
+func countNoun(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	if strings.HasSuffix(noun, "ch") {
+		return fmt.Sprintf("%d %ses", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
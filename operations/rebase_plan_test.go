@@ -0,0 +1,119 @@
+package operations
+
+import (
+	"fmt"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RebasePlanTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *RebasePlanTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *RebasePlanTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+func (suite *RebasePlanTestSuite) target(branchName string) string {
+	return suite.repo.LookupBranch(branchName).Target().String()
+}
+
+// Initial:
+//
+//	master ─┬─ treecko ─── grovyle
+//	        └─ mudkip
+func (suite *RebasePlanTestSuite) TestRebaseTreeDryRun_ListsMovedBranches() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	plan, err := RebaseTreeDryRun(suite.repo.Repo, source, dest, RebaseTreeOptions{})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), `rebase treecko onto mudkip (1 commit)
+rebase grovyle onto treecko (1 commit)
+
+2 branches, 2 commits, 0 conflicts`, plan.String())
+}
+
+// Initial:
+//
+//	master ─── mew ─┬─ treecko ─── grovyle ─── sceptile
+//	                └─ mudkip
+func (suite *RebasePlanTestSuite) TestRebaseTreeDryRun_PredictsConflicts() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.CreateAndSwitchBranch("grovyle")
+	suite.repo.WriteAndCommitFile("pokedex", "grovyle", "Add grovyle to pokedex")
+	suite.repo.WriteAndCommitFile("favorite", "grovyle", "Make grovyle favorite")
+	suite.repo.BranchWithCommit("sceptile")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("favorite", "mudkip", "Make mudkip favorite")
+	Init(suite.repo.Repo)
+
+	grovyle := suite.repo.LookupBranch("grovyle")
+	conflictHash := gitutil.CommitShortHash(gitutil.CommitByOid(suite.repo.Repo, *grovyle.Target()))
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	plan, err := RebaseTreeDryRun(suite.repo.Repo, source, dest, RebaseTreeOptions{})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), fmt.Sprintf(`rebase treecko onto mudkip (1 commit)
+rebase grovyle onto treecko (2 commits)
+    conflict at 2/2 %s Make grovyle favorite: favorite
+rebase sceptile onto grovyle (1 commit)
+
+3 branches, 4 commits, 1 conflict`, conflictHash), plan.String())
+}
+
+// Initial:
+//
+//	master ─── mew ─┬─ treecko
+//	                └─ mudkip
+func (suite *RebasePlanTestSuite) TestRebaseTreeDryRun_DoesNotRewriteAnything() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("starter", "treecko", "treecko")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("starter", "mudkip", "mudkip")
+	Init(suite.repo.Repo)
+
+	oldTreecko := suite.target("treecko")
+	oldMudkip := suite.target("mudkip")
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	plan, err := RebaseTreeDryRun(suite.repo.Repo, source, dest, RebaseTreeOptions{})
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, plan.ConflictCount())
+	assert.Equal(suite.T(), oldTreecko, suite.target("treecko"))
+	assert.Equal(suite.T(), oldMudkip, suite.target("mudkip"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-treecko"))
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/rebasing"))
+	assert.Equal(suite.T(), "mudkip", suite.repo.ReadFile("starter"))
+
+	// The real rebase can still be started.
+	gotResult := RebaseTree(suite.repo.Repo, source, dest)
+	assert.Equal(suite.T(), RebaseTreeMergeConflict, gotResult.Type)
+}
+
+func TestRebasePlanTestSuite(t *testing.T) {
+	suite.Run(t, new(RebasePlanTestSuite))
+}
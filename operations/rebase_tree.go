@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/acamadeo/git-tree/common"
 	gitutil "github.com/acamadeo/git-tree/git"
 	"github.com/acamadeo/git-tree/models"
 	"github.com/acamadeo/git-tree/store"
@@ -29,6 +30,20 @@ type RebaseTreeResult struct {
 	Error error
 }
 
+// Options for a RebaseTree operation.
+type RebaseTreeOptions struct {
+	// How merge commits in the rebased branches are handled.
+	MergeMode gitutil.MergeMode
+	// If true, branches are rebased in memory and the working tree is only
+	// checked out once every branch was moved. The working tree is still used
+	// to resolve merge conflicts.
+	InMemory bool
+}
+
+// The branch that points to the destination of a RebaseTree operation when it
+// is not a branch (see RebaseTreeOntoCommit()).
+const rebaseDestBranch = "git-tree-rebase-dest"
+
 type rebaseTreeRunner struct {
 	repo      *git.Repository
 	source    *git.Branch
@@ -36,6 +51,22 @@ type rebaseTreeRunner struct {
 	branchMap *models.BranchMap
 	// A map from the temporary branch to the branch it replaced.
 	tempBranches models.TempBranchMap
+	mergeMode    gitutil.MergeMode
+	// The commit HEAD pointed to before the rebase started, if HEAD was
+	// detached (see `headString()`).
+	detachedHead string
+	// Where HEAD pointed to before the rebase started (see `headString()`).
+	// Only set when the rebase is started, not when it is continued.
+	head string
+	// The commit the root of the tree pointed to before it was moved back to
+	// make room for a destination that did not descend from it, if it was.
+	oldRoot *git.Oid
+	// If true, branches are rebased in memory (see RebaseTreeOptions).
+	inMemory bool
+	// Map from each branch that was rebased in memory to its rebased tip. The
+	// branches are moved once every branch was rebased, or before a merge
+	// conflict is shown in the working tree.
+	rebasedTips map[string]git.Oid
 }
 
 // -------------------------------------------------------------------------- \
@@ -45,18 +76,107 @@ type rebaseTreeRunner struct {
 // Rebase a branch and all its descendants onto another branch.
 //
 // Under the hood, this is performed as a sequence of git rebase operations.
+// Merge commits are flattened; see RebaseTreeWithOptions to recreate them.
 func RebaseTree(repo *git.Repository, source *git.Branch, dest *git.Branch) RebaseTreeResult {
+	return RebaseTreeWithOptions(repo, source, dest, RebaseTreeOptions{})
+}
+
+// Rebase a branch and all its descendants onto another branch, as configured
+// by `opts`.
+func RebaseTreeWithOptions(repo *git.Repository, source *git.Branch, dest *git.Branch, opts RebaseTreeOptions) RebaseTreeResult {
 	// Read the branch map file.
-	branchMap := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
 
 	if err := validateRebaseTree(repo, source, dest, branchMap); err != nil {
 		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
 	}
+	if err := validateRebaseTreeOptions(repo, opts); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	// A destination that is not tracked must still descend from the root of
+	// the tree, so that the source can be re-anchored under it. The root is
+	// moved back if the rebase is aborted or fails.
+	oldRoot := *branchMap.Root.Target()
+	if branchMap.FindBranch(gitutil.BranchName(dest)) == nil {
+		description := fmt.Sprintf("Destination %s", gitutil.OidShortHash(*dest.Target()))
+		if err := moveRootBelow(repo, branchMap.Root, dest.Target(), description); err != nil {
+			return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+		}
+	}
 
 	runner := newRebaseTreeRunner(repo, source, dest, branchMap)
+	if !branchMap.Root.Target().Equal(&oldRoot) {
+		runner.oldRoot = &oldRoot
+	}
+	runner.mergeMode = opts.MergeMode
+	runner.inMemory = opts.InMemory
+	runner.head = headString(repo)
+	if detached, _ := repo.IsHeadDetached(); detached {
+		runner.detachedHead = runner.head
+	}
 	return runner.Execute()
 }
 
+// Rebase a branch and all its descendants onto commit `dest`, which need not be
+// pointed to by a tracked branch (e.g. `origin/main`, a tag, or a hash), as
+// configured by `opts`.
+//
+// The destination is not tracked. Once rebased, the source is re-anchored in
+// the branch map under its closest tracked ancestor, i.e. under the root of the
+// tree unless `dest` is on a tracked branch, in which case it must be the tip
+// of that branch. If `dest` does not descend from the root, the root is moved
+// back to its merge-base with `dest` first, and moved forward again if the
+// rebase is aborted or fails.
+func RebaseTreeOntoCommit(repo *git.Repository, source *git.Branch, dest *git.Commit, opts RebaseTreeOptions) RebaseTreeResult {
+	if err := ValidateRebaseTreeOntoCommit(repo, source, dest, opts); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	// libgit2 rebases onto branches, so point a temporary branch at `dest`.
+	// It is deleted along with the other files of the rebase.
+	destBranch, err := repo.CreateBranch(rebaseDestBranch, dest, false)
+	if err != nil {
+		err := fmt.Errorf("Could not create branch %q: %s", rebaseDestBranch, err.Error())
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	result := RebaseTreeWithOptions(repo, source, destBranch, opts)
+	if result.Type == RebaseTreeError && !utils.FileExists(store.RebasingPath(repo.Path())) {
+		destBranch.Delete()
+	}
+	return result
+}
+
+// Returns an error if RebaseTreeWithOptions() would refuse to rebase `source`
+// onto `dest`, without changing anything.
+func ValidateRebaseTree(repo *git.Repository, source *git.Branch, dest *git.Branch, opts RebaseTreeOptions) error {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return err
+	}
+	if err := validateRebaseTree(repo, source, dest, branchMap); err != nil {
+		return err
+	}
+	return validateRebaseTreeOptions(repo, opts)
+}
+
+// Returns an error if RebaseTreeOntoCommit() would refuse to rebase `source`
+// onto `dest`, without changing anything.
+func ValidateRebaseTreeOntoCommit(repo *git.Repository, source *git.Branch, dest *git.Commit, opts RebaseTreeOptions) error {
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return err
+	}
+	if err := validateRebaseTreeOntoCommit(repo, source, dest, branchMap); err != nil {
+		return err
+	}
+	return validateRebaseTreeOptions(repo, opts)
+}
+
 // -------------------------------------------------------------------------- \
 // RebaseTreeContinue                                                         |
 // -------------------------------------------------------------------------- /
@@ -69,7 +189,10 @@ func RebaseTreeContinue(repo *git.Repository) RebaseTreeResult {
 	}
 
 	// Read the branch map file.
-	branchMap := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
 
 	// Look up source and dest branches.
 	sourceName := utils.ReadFile(store.RebasingSourcePath(repo.Path()))
@@ -77,8 +200,18 @@ func RebaseTreeContinue(repo *git.Repository) RebaseTreeResult {
 
 	destName := utils.ReadFile(store.RebasingDestPath(repo.Path()))
 	dest := branchMap.FindBranch(destName)
+	if dest == nil {
+		// The destination is not tracked (see RebaseTreeOntoCommit()).
+		dest, _ = repo.LookupBranch(destName, git.BranchLocal)
+	}
 
 	runner := newRebaseTreeRunner(repo, source, dest, branchMap)
+	runner.mergeMode = persistedMergeMode(repo)
+	runner.inMemory = utils.FileExists(store.RebasingInMemoryPath(repo.Path()))
+	runner.detachedHead = utils.ReadFile(store.RebasingHeadPath(repo.Path()))
+	if oldRoot, err := git.NewOid(utils.ReadFile(store.RebasingRootPath(repo.Path()))); err == nil {
+		runner.oldRoot = oldRoot
+	}
 
 	// Populate the runner with temporary branches from previous runs.
 	path := store.RebasingTempsPath(repo.Path())
@@ -115,6 +248,27 @@ func continueExistingRebase(repo *git.Repository) RebaseTreeResult {
 
 // Abort a RebaseTree operation in progress.
 func RebaseTreeAbort(repo *git.Repository) RebaseTreeResult {
+	if result := abortExistingRebase(repo); result.Type != RebaseTreeSuccess {
+		return result
+	}
+
+	// Move the root of the tree back, in case it was moved for the
+	// destination.
+	if oldRoot, err := git.NewOid(utils.ReadFile(store.RebasingRootPath(repo.Path()))); err == nil {
+		restoreRoot(repo, oldRoot)
+	}
+
+	// Delete storage files.
+	detachedHead := utils.ReadFile(store.RebasingHeadPath(repo.Path()))
+	deleteStorage(repo)
+	restoreHead(repo, detachedHead)
+
+	return RebaseTreeResult{Type: RebaseTreeSuccess}
+}
+
+// Abort the in-progress git rebase, moving every branch that was already
+// rebased back to its original position.
+func abortExistingRebase(repo *git.Repository) RebaseTreeResult {
 	rebase, err := gitutil.OpenRebase(repo)
 	if err != nil {
 		err := fmt.Errorf("Error opening rebase: %v", err)
@@ -131,11 +285,10 @@ func RebaseTreeAbort(repo *git.Repository) RebaseTreeResult {
 	tempBranchMap := store.ReadTemporaryBranches(repo, path)
 
 	// Move rebased branches back to their original positions.
-	restoreRebasedBranches(tempBranchMap)
+	restoreRebasedBranches(repo, tempBranchMap)
 
-	// Delete temporary branches and storage files.
+	// Delete temporary branches.
 	deleteTemporaryBranches(tempBranchMap)
-	deleteStorage(repo)
 
 	return RebaseTreeResult{Type: RebaseTreeSuccess}
 }
@@ -144,8 +297,8 @@ func RebaseTreeAbort(repo *git.Repository) RebaseTreeResult {
 // returning an error if it is not.
 func validateRebaseTree(repo *git.Repository, source *git.Branch, dest *git.Branch, branchMap *models.BranchMap) error {
 	// Cannot run `git-tree rebase` if another rebase is in progress.
-	if utils.FileExists(store.RebasingPath(repo.Path())) {
-		return errors.New("Cannot rebase while another rebase is in progress. Abort or continue the existing rebase")
+	if err := validateNoRebaseInProgress(repo); err != nil {
+		return err
 	}
 
 	// Source and destination cannot be the same.
@@ -165,9 +318,80 @@ func validateRebaseTree(repo *git.Repository, source *git.Branch, dest *git.Bran
 		return errors.New("Source is already a child of destination")
 	}
 
+	if branchMap.FindBranch(destName) == nil {
+		if err := validateUntrackedDest(repo, branchMap, sourceName, dest.Target()); err != nil {
+			return err
+		}
+	}
+
+	// Source and its descendants may be checked out in other worktrees.
+	return validateOtherWorktrees(repo, branchMap.ListSubtreeBranchNames(sourceName))
+}
+
+// validateRebaseTreeOntoCommit checks whether the RebaseTreeOntoCommit
+// operation is valid, returning an error if it is not.
+func validateRebaseTreeOntoCommit(repo *git.Repository, source *git.Branch, dest *git.Commit, branchMap *models.BranchMap) error {
+	if err := validateNoRebaseInProgress(repo); err != nil {
+		return err
+	}
+
+	sourceName := gitutil.BranchName(source)
+	if err := validateUntrackedDest(repo, branchMap, sourceName, dest.Id()); err != nil {
+		return err
+	}
+	return validateOtherWorktrees(repo, branchMap.ListSubtreeBranchNames(sourceName))
+}
+
+// Returns an error if commit `dest`, which no tracked branch points to, cannot
+// be the destination of rebasing branch `sourceName`.
+func validateUntrackedDest(repo *git.Repository, branchMap *models.BranchMap, sourceName string, dest *git.Oid) error {
+	// The destination cannot be one of the commits to move.
+	if isCommitOfSubtree(repo, branchMap, sourceName, dest) {
+		return errors.New("Source cannot be an ancestor of destination")
+	}
+
+	// The source is re-anchored under the closest tracked ancestor of the
+	// destination, so the destination cannot be in the middle of a tracked
+	// branch: the source would then carry the lower commits of that branch.
+	if branchName := common.ContainingBranch(repo, branchMap, dest); branchName != "" {
+		branch, _ := repo.LookupBranch(branchName, git.BranchLocal)
+		if !branch.Target().Equal(dest) {
+			return fmt.Errorf("Destination %s is in the middle of branch %q. Split the branch at it first with `git-tree branch --split`", gitutil.OidShortHash(*dest), branchName)
+		}
+	}
 	return nil
 }
 
+// Returns an error if the RebaseTree operation cannot run as configured by
+// `opts`.
+func validateRebaseTreeOptions(repo *git.Repository, opts RebaseTreeOptions) error {
+	// The working tree is only checked out once every branch was rebased in
+	// memory, which local changes would prevent.
+	if !opts.InMemory {
+		return nil
+	}
+	dirty, err := gitutil.HasUncommittedChanges(repo)
+	if err != nil {
+		return fmt.Errorf("Cannot rebase in memory: could not read the status of the working tree: %s", err.Error())
+	}
+	if dirty {
+		return errors.New("Cannot rebase in memory with uncommitted changes. Commit or stash them first")
+	}
+	return nil
+}
+
+// Returns an error if a `git-tree rebase` or any other git-tree operation is in
+// progress.
+func validateNoRebaseInProgress(repo *git.Repository) error {
+	if utils.FileExists(store.RebasingPath(repo.Path())) {
+		return errors.New("Cannot rebase while another rebase is in progress. Abort or continue the existing rebase")
+	}
+	if err := validateNoGitRebaseInProgress(repo, "rebase"); err != nil {
+		return err
+	}
+	return validateNoOperationInProgress(repo, "rebase")
+}
+
 func newRebaseTreeRunner(repo *git.Repository, source *git.Branch, dest *git.Branch, branchMap *models.BranchMap) *rebaseTreeRunner {
 	return &rebaseTreeRunner{
 		repo:         repo,
@@ -175,6 +399,7 @@ func newRebaseTreeRunner(repo *git.Repository, source *git.Branch, dest *git.Bra
 		dest:         dest,
 		branchMap:    branchMap,
 		tempBranches: models.TempBranchMap{},
+		rebasedTips:  map[string]git.Oid{},
 	}
 }
 
@@ -196,17 +421,46 @@ func (r *rebaseTreeRunner) Execute() RebaseTreeResult {
 
 	sourceParent := r.branchMap.FindParent(sourceName)
 	destBranch := r.branchMap.FindBranch(destName)
+	if destBranch == nil {
+		destBranch = r.dest
+	}
 	sourceBranch := r.branchMap.FindBranch(sourceName)
 
 	result := r.executeRecurse(sourceParent, destBranch, sourceBranch)
 	if result.Type == RebaseTreeMergeConflict {
-		r.handleMergeConflict()
+		if err := r.handleMergeConflict(); err != nil {
+			return persistError("rebase", err)
+		}
 		return result
 	} else if result.Type == RebaseTreeError {
+		// Without a merge conflict to resolve, the rebase cannot be continued
+		// or aborted, so undo what was rebased so far.
+		if r.head != "" {
+			r.rollback()
+			result.Error = fmt.Errorf("%s. No branch was rebased", result.Error.Error())
+		}
 		return result
 	}
 
-	r.handleSuccess()
+	if err := r.moveRebasedBranches(); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	movedBranches := movedBranchNames(r.tempBranches)
+	if err := r.handleSuccess(); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: fmt.Errorf("Could not write branch map: %s.", err.Error())}
+	}
+	if r.inMemory {
+		// The branches were moved without checking them out. Check out HEAD
+		// once, now that every branch is in place and the rebase is finished,
+		// so that a failed checkout leaves nothing to continue or abort.
+		if err := gitutil.UpdateWorkdir(r.repo); err != nil {
+			return RebaseTreeResult{Type: RebaseTreeError, Error: fmt.Errorf("Branches were rebased, but the working tree could not be updated: %s", err.Error())}
+		}
+	}
+	if err := updateOtherWorktrees(r.repo, movedBranches); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
 	return RebaseTreeResult{Type: RebaseTreeSuccess}
 }
 
@@ -222,7 +476,7 @@ func (r *rebaseTreeRunner) executeRecurse(parent, onto, toMove *git.Branch) Reba
 		// gets interrupted (here or in a downstream branch).
 		tempBranch = r.createTempBranch(toMove)
 
-		rebaseResult := gitutil.Rebase(r.repo, parent, onto, &toMove)
+		rebaseResult := r.rebase(parent, onto, &toMove)
 
 		// Pause the rebase if we encountered an error.
 		if rebaseResult.Type == gitutil.RebaseError {
@@ -249,6 +503,82 @@ func (r *rebaseTreeRunner) executeRecurse(parent, onto, toMove *git.Branch) Reba
 	return RebaseTreeResult{Type: RebaseTreeSuccess}
 }
 
+// Rebase the commits in `toMove` that aren't in `upstream` onto `onto`.
+//
+// libgit2 flattens merge commits, so branches with merge commits are replayed
+// in memory when merges should be recreated.
+//
+// When rebasing in memory, a branch that hits a merge conflict is rebased again
+// with libgit2, so that the conflict shows up in the working tree.
+func (r *rebaseTreeRunner) rebase(upstream, onto *git.Branch, toMove **git.Branch) gitutil.RebaseResult {
+	if r.inMemory {
+		newTip, result := gitutil.RebaseInMemory(r.repo, *upstream.Target(), r.rebasedTarget(onto), *(*toMove).Target(), r.mergeMode)
+		if result.Type != gitutil.RebaseMergeConflict {
+			if result.Type == gitutil.RebaseSuccess {
+				r.rebasedTips[gitutil.BranchName(*toMove)] = newTip
+			}
+			return result
+		}
+
+		// The conflict is resolved on top of the branches rebased so far.
+		if err := r.moveRebasedBranches(); err != nil {
+			return gitutil.RebaseResult{Type: gitutil.RebaseError, Error: err}
+		}
+		onto, _ = r.repo.LookupBranch(gitutil.BranchName(onto), git.BranchLocal)
+	}
+
+	if r.mergeMode == gitutil.RecreateMerges && gitutil.HasMergeCommits(r.repo, upstream.Target(), (*toMove).Target()) {
+		return gitutil.RebaseRecreatingMerges(r.repo, upstream, onto, toMove)
+	}
+	return gitutil.Rebase(r.repo, upstream, onto, toMove)
+}
+
+// Returns the commit `branch` points to, or its rebased tip if it was rebased in
+// memory but not moved yet.
+func (r *rebaseTreeRunner) rebasedTarget(branch *git.Branch) git.Oid {
+	if tip, ok := r.rebasedTips[gitutil.BranchName(branch)]; ok {
+		return tip
+	}
+	return *branch.Target()
+}
+
+// Move the branches that were rebased in memory to their rebased tips, without
+// checking them out.
+func (r *rebaseTreeRunner) moveRebasedBranches() error {
+	for branchName, tip := range r.rebasedTips {
+		branch, err := r.repo.LookupBranch(branchName, git.BranchLocal)
+		if err != nil {
+			return fmt.Errorf("Could not find rebased branch %q", branchName)
+		}
+		msg := fmt.Sprintf("[git-tree] rebase %s in memory", branchName)
+		if _, err := branch.SetTarget(&tip, msg); err != nil {
+			return fmt.Errorf("Could not move branch %q: %s", branchName, err.Error())
+		}
+		delete(r.rebasedTips, branchName)
+	}
+	return nil
+}
+
+// Undo a rebase that failed without stopping on a merge conflict: move the
+// branches rebased so far back to their original positions, delete the
+// temporary branches and check out HEAD again.
+func (r *rebaseTreeRunner) rollback() {
+	// A libgit2 rebase that failed partway through is still open.
+	abortOpenRebase(r.repo)
+
+	// Detach HEAD first so the working tree is checked out relative to it.
+	if head, err := r.repo.Head(); err == nil {
+		r.repo.SetHeadDetached(head.Target())
+	}
+
+	restoreRebasedBranches(r.repo, r.tempBranches)
+	deleteTemporaryBranches(r.tempBranches)
+	if r.oldRoot != nil {
+		restoreRoot(r.repo, r.oldRoot)
+	}
+	restoreHead(r.repo, r.head)
+}
+
 // Returns the persisted temporary branch that replaced the given branch, or nil
 // if no temporary branch exists.
 //
@@ -279,27 +609,92 @@ func (r *rebaseTreeRunner) createTempBranch(branch *git.Branch) *git.Branch {
 	return tempBranch
 }
 
-func (r *rebaseTreeRunner) handleMergeConflict() {
+func (r *rebaseTreeRunner) handleMergeConflict() error {
 	// Create a file indicating a rebase is in progress.
 	path := store.RebasingPath(r.repo.Path())
-	utils.OverwriteFile(path, "")
+	if err := utils.OverwriteFile(path, ""); err != nil {
+		return err
+	}
 
 	// Store the `source` and `dest` branches.
 	path = store.RebasingSourcePath(r.repo.Path())
-	utils.OverwriteFile(path, gitutil.BranchName(r.source))
+	if err := utils.OverwriteFile(path, gitutil.BranchName(r.source)); err != nil {
+		return err
+	}
 
 	path = store.RebasingDestPath(r.repo.Path())
-	utils.OverwriteFile(path, gitutil.BranchName(r.dest))
+	if err := utils.OverwriteFile(path, gitutil.BranchName(r.dest)); err != nil {
+		return err
+	}
+
+	if r.detachedHead != "" {
+		path = store.RebasingHeadPath(r.repo.Path())
+		if err := utils.OverwriteFile(path, r.detachedHead); err != nil {
+			return err
+		}
+	}
 
-	// Store the temporary branches with pointers to each one's original branch.
-	path = store.RebasingTempsPath(r.repo.Path())
-	store.WriteTemporaryBranches(r.tempBranches, path)
+	return r.persistTempBranches()
 }
 
-func (r *rebaseTreeRunner) handleSuccess() {
+// Store the temporary branches with pointers to each one's original branch.
+//
+// Also stores the merge mode and whether branches are rebased in memory, which
+// apply to the branches still to rebase.
+func (r *rebaseTreeRunner) persistTempBranches() error {
+	path := store.RebasingTempsPath(r.repo.Path())
+	if err := store.WriteTemporaryBranches(r.tempBranches, path); err != nil {
+		return err
+	}
+
+	if r.mergeMode == gitutil.RecreateMerges {
+		if err := utils.OverwriteFile(store.RebasingMergesPath(r.repo.Path()), ""); err != nil {
+			return err
+		}
+	}
+	if r.inMemory {
+		if err := utils.OverwriteFile(store.RebasingInMemoryPath(r.repo.Path()), ""); err != nil {
+			return err
+		}
+	}
+	if r.oldRoot != nil {
+		return utils.OverwriteFile(store.RebasingRootPath(r.repo.Path()), r.oldRoot.String())
+	}
+	return nil
+}
+
+// Returns the result of an operation that could not save its state after
+// stopping on a merge conflict.
+func persistError(operation string, err error) RebaseTreeResult {
+	return RebaseTreeResult{Type: RebaseTreeError, Error: fmt.Errorf("Could not save the in-progress %s: %s.", operation, err.Error())}
+}
+
+// Returns the merge mode of the interrupted operation.
+func persistedMergeMode(repo *git.Repository) gitutil.MergeMode {
+	if utils.FileExists(store.RebasingMergesPath(repo.Path())) {
+		return gitutil.RecreateMerges
+	}
+	return gitutil.FlattenMerges
+}
+
+func (r *rebaseTreeRunner) handleSuccess() error {
+	// libgit2 leaves HEAD on the last rebased branch, so check out a detached
+	// HEAD again at the rebased copy of its commit.
+	detachedHead := r.rebasedHead(r.detachedHead)
 	deleteTemporaryBranches(r.tempBranches)
 	deleteStorage(r.repo)
-	r.updateAndWriteBranchMap()
+	restoreHead(r.repo, detachedHead)
+	return r.updateAndWriteBranchMap()
+}
+
+// Returns where `head` (see `headString()`) should be checked out once the
+// branches were rebased: a detached HEAD follows its commit to its rebased
+// copy.
+func (r *rebaseTreeRunner) rebasedHead(head string) string {
+	if detachedHeadCommit(r.repo, head) == nil {
+		return head
+	}
+	return followRewrites(r.repo, head, rebasedCommits(r.repo, r.tempBranches))
 }
 
 func (r *rebaseTreeRunner) updateBranchMap() {
@@ -313,6 +708,17 @@ func (r *rebaseTreeRunner) updateBranchMap() {
 	parent := r.branchMap.FindParent(sourceName)
 	parentName := gitutil.BranchName(parent)
 
+	if dest == nil {
+		// The destination is not tracked, so re-anchor `source` under its
+		// closest tracked ancestor (usually the root of the tree). It is looked
+		// up again, since it was moved.
+		r.branchMap.RemoveChildren(parentName, []string{sourceName})
+		rebased, _ := r.repo.LookupBranch(sourceName, git.BranchLocal)
+		r.branchMap.ReplaceBranch(sourceName, rebased)
+		r.branchMap.AddBranch(r.repo, rebased)
+		return
+	}
+
 	// Move `source` under `dest`.
 	childrenMap := r.branchMap.Children
 	childrenMap[dest] = append(childrenMap[dest], source)
@@ -326,18 +732,30 @@ func (r *rebaseTreeRunner) updateAndWriteBranchMap() error {
 
 	// Rewrite the branch map file to disk.
 	branchFile := store.BranchMapPath(r.repo.Path())
-	store.WriteBranchMap(r.branchMap, branchFile)
-
-	return nil
+	return store.WriteBranchMap(r.branchMap, branchFile)
 }
 
 // Restore branches that have already been rebased back to their original
 // positions, using the temporary branches map.
-func restoreRebasedBranches(tempBranches models.TempBranchMap) {
+func restoreRebasedBranches(repo *git.Repository, tempBranches models.TempBranchMap) {
 	for tempBranch, origBranch := range tempBranches {
-		// Move `origBranch` to point back to `tempBranch`.
+		// Look up the branch again, since it may have moved since `origBranch`
+		// was looked up.
+		branch, err := repo.LookupBranch(gitutil.BranchName(origBranch), git.BranchLocal)
+		if err != nil {
+			continue
+		}
+
+		// Move the branch to point back to `tempBranch`.
 		tempBranchOid := tempBranch.Reference.Target()
-		origBranch.Branch().SetTarget(tempBranchOid, "aborting RebaseTree")
+		branch.SetTarget(tempBranchOid, "aborting RebaseTree")
+	}
+}
+
+// Move the root of the tree back to `target`.
+func restoreRoot(repo *git.Repository, target *git.Oid) {
+	if root, err := repo.LookupBranch(store.GitTreeRootBranch, git.BranchLocal); err == nil {
+		root.SetTarget(target, "[git-tree] restore root")
 	}
 }
 
@@ -363,4 +781,43 @@ func deleteStorage(repo *git.Repository) {
 	// Delete the file with the RebaseTree temporary branches.
 	rebasingTempsPath := store.RebasingTempsPath(repo.Path())
 	os.Remove(rebasingTempsPath)
+
+	// Delete the file indicating merge commits are recreated.
+	os.Remove(store.RebasingMergesPath(repo.Path()))
+
+	// Delete the file with the detached HEAD.
+	os.Remove(store.RebasingHeadPath(repo.Path()))
+
+	// Delete the file indicating branches are rebased in memory.
+	os.Remove(store.RebasingInMemoryPath(repo.Path()))
+
+	// Delete the file with where the root of the tree was.
+	os.Remove(store.RebasingRootPath(repo.Path()))
+
+	// Delete the branch at a destination that is not a branch.
+	if branch, err := repo.LookupBranch(rebaseDestBranch, git.BranchLocal); err == nil {
+		branch.Delete()
+	}
+}
+
+// Returns true if commit `oid` is one of the commits of branch `branchName` or
+// its descendants that are not in the parent of `branchName`.
+func isCommitOfSubtree(repo *git.Repository, branchMap *models.BranchMap, branchName string, oid *git.Oid) bool {
+	parent := branchMap.FindParent(branchName)
+	if parent == nil {
+		return false
+	}
+
+	for _, name := range branchMap.ListSubtreeBranchNames(branchName) {
+		branch, err := repo.LookupBranch(name, git.BranchLocal)
+		if err != nil {
+			continue
+		}
+		for _, commit := range gitutil.CommitsBetween(repo, parent.Target(), branch.Target()) {
+			if commit.Id().Equal(oid) {
+				return true
+			}
+		}
+	}
+	return false
 }
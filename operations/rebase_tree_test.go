@@ -127,6 +127,34 @@ func (suite *RebaseTreeTestSuite) TestRebaseTree_RebaseOneChild() {
 		"Expected rebased repository to match expected, but it does not")
 }
 
+// Initial:
+//
+//	master ─── mew ─┬─ treecko ─── grovyle
+//	                └─ mudkip
+//
+// HEAD is detached at treecko, in the middle of the rebased stack.
+func (suite *RebaseTreeTestSuite) TestRebaseTree_DetachedHeadFollowsRebasedCommit() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.Repo.SetHeadDetached(suite.repo.LookupBranch("treecko").Target())
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	gotResult := RebaseTree(suite.repo.Repo, source, dest)
+
+	head, _ := suite.repo.Repo.Head()
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.False(suite.T(), head.IsBranch())
+	assert.Equal(suite.T(), *suite.repo.LookupBranch("treecko").Target(), *head.Target())
+	assert.True(suite.T(), suite.repo.FileExists("mudkip"))
+	assert.False(suite.T(), suite.repo.FileExists("grovyle"))
+}
+
 // Initial:
 //
 //	master ─── mew ─┬─ treecko ─── grovyle
@@ -912,7 +940,12 @@ func (suite *RebaseTreeTestSuite) TestRebaseTree_MergeConflict_CreatesFiles() {
 		"Got rebasing-dest file: %v, but want file: %v", gotString, wantString)
 
 	gotString = suite.repo.ReadFile(".git/tree/rebasing-temps")
-	wantString = "rebase-treecko treecko"
+	wantString = `{
+  "version": 2,
+  "branches": [
+    {"temp": "rebase-treecko", "original": "treecko"}
+  ]
+}`
 	assert.Equal(suite.T(), gotString, wantString,
 		"Got rebasing-temps file: %v, but want file: %v", gotString, wantString)
 }
@@ -940,8 +973,13 @@ func (suite *RebaseTreeTestSuite) TestRebaseTree_MergeConflict_RebasingTempsCont
 
 	// Should contain only branches that we attempted to rebase (we never reached `sceptile`).
 	gotString := suite.repo.ReadFile(".git/tree/rebasing-temps")
-	wantString := `rebase-grovyle grovyle
-rebase-treecko treecko`
+	wantString := `{
+  "version": 2,
+  "branches": [
+    {"temp": "rebase-grovyle", "original": "grovyle"},
+    {"temp": "rebase-treecko", "original": "treecko"}
+  ]
+}`
 	assert.Equal(suite.T(), gotString, wantString,
 		"Got rebasing-temps file: %v, but want file: %v", gotString, wantString)
 }
@@ -982,6 +1020,333 @@ func (suite *RebaseTreeTestSuite) TestRebaseTree_MergeConflict_TemporaryBranches
 		"Expected temporary branch to point to %v, but it points to %v", *grovyleOid, *tempGrovyleOid)
 }
 
+// Initial:
+//
+//	master ─┬─ mew ─┬─ treecko ─── (merge eevee)
+//	        │       └─ mudkip
+//	        └─ eevee
+func (suite *RebaseTreeTestSuite) setupTreeWithMerge() {
+	suite.repo.BranchWithCommit("eevee")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.MergeBranch("eevee", "merge eevee")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.BranchWithCommit("mudkip")
+
+	mew := suite.repo.LookupBranch("mew")
+	treecko := suite.repo.LookupBranch("treecko")
+	mudkip := suite.repo.LookupBranch("mudkip")
+	Init(suite.repo.Repo, mew, treecko, mudkip)
+}
+
+// Rebase treecko onto mudkip:
+//
+//	master ─┬─ mew ─── mudkip ─── treecko ─── eevee'
+//	        └─ eevee
+func (suite *RebaseTreeTestSuite) TestRebaseTree_FlattensMergeCommits() {
+	suite.setupTreeWithMerge()
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	gotResult := RebaseTree(suite.repo.Repo, source, dest)
+
+	treeckoTip := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("treecko").Target())
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("mudkip", "treecko"))
+	assert.Equal(suite.T(), uint(1), treeckoTip.ParentCount())
+	assert.True(suite.T(), suite.repo.FileExists("eevee"))
+}
+
+// Rebase treecko onto mudkip, recreating merges:
+//
+//	master ─┬─ mew ─── mudkip ─── treecko ─── (merge eevee)
+//	        └─ eevee ─────────────────────────┘
+func (suite *RebaseTreeTestSuite) TestRebaseTree_RecreatesMergeCommits() {
+	suite.setupTreeWithMerge()
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	opts := RebaseTreeOptions{MergeMode: gitutil.RecreateMerges}
+	gotResult := RebaseTreeWithOptions(suite.repo.Repo, source, dest, opts)
+
+	treeckoTip := gitutil.CommitByOid(suite.repo.Repo, *suite.repo.LookupBranch("treecko").Target())
+	eevee := suite.repo.LookupBranch("eevee")
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("mudkip", "treecko"))
+	assert.Equal(suite.T(), uint(2), treeckoTip.ParentCount())
+	assert.Equal(suite.T(), "merge eevee", treeckoTip.Message())
+	assert.Equal(suite.T(), *eevee.Target(), *treeckoTip.ParentId(1))
+}
+
+// Initial:
+//
+//	master ─┬─ mew ─── treecko ─── (merge eevee)
+//	        ├─ mudkip
+//	        └─ eevee
+//
+// treecko and mudkip both write file `favorite`.
+func (suite *RebaseTreeTestSuite) TestRebaseTree_RecreateMergesConflictRollsBack() {
+	suite.repo.BranchWithCommit("eevee")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("favorite", "treecko", "treecko")
+	suite.repo.MergeBranch("eevee", "merge eevee")
+	suite.repo.SwitchBranch("master")
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("favorite", "mudkip", "mudkip")
+
+	mew := suite.repo.LookupBranch("mew")
+	treecko := suite.repo.LookupBranch("treecko")
+	mudkip := suite.repo.LookupBranch("mudkip")
+	Init(suite.repo.Repo, mew, treecko, mudkip)
+	oldMew := *mew.Target()
+	oldTreecko := *treecko.Target()
+
+	// mew is rebased before treecko hits the conflict.
+	opts := RebaseTreeOptions{MergeMode: gitutil.RecreateMerges}
+	gotResult := RebaseTreeWithOptions(suite.repo.Repo, mew, mudkip, opts)
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.Contains(suite.T(), gotResult.Error.Error(), "Merge conflict while recreating commit")
+	assert.Equal(suite.T(), oldMew, *suite.repo.LookupBranch("mew").Target())
+	assert.Equal(suite.T(), oldTreecko, *suite.repo.LookupBranch("treecko").Target())
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-mew"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-treecko"))
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/rebasing"))
+
+	// HEAD is back where it was.
+	assert.Equal(suite.T(), "mudkip", gitutil.BranchName(gitutil.HeadBranch(suite.repo.Repo)))
+	assert.Equal(suite.T(), "mudkip", suite.repo.ReadFile("favorite"))
+	assert.False(suite.T(), suite.repo.FileExists("mew"))
+}
+
+// Initial:
+//
+//	master ─── mew ─┬─ treecko ─── grovyle
+//	                └─ mudkip
+//
+// HEAD is on grovyle.
+func (suite *RebaseTreeTestSuite) TestRebaseTree_InMemory() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+	suite.repo.SwitchBranch("grovyle")
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	gotResult := RebaseTreeWithOptions(suite.repo.Repo, source, dest, RebaseTreeOptions{InMemory: true})
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("mudkip", "treecko"))
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "grovyle"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-treecko"))
+
+	// HEAD stays on its branch, whose files are checked out at the end.
+	assert.Equal(suite.T(), "grovyle", gitutil.BranchName(gitutil.HeadBranch(suite.repo.Repo)))
+	assert.True(suite.T(), suite.repo.FileExists("mudkip"))
+	assert.True(suite.T(), suite.repo.FileExists("grovyle"))
+	dirty, err := gitutil.HasUncommittedChanges(suite.repo.Repo)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), dirty)
+}
+
+// Initial:
+//
+//	master ─── mew ─┬─ treecko
+//	                └─ mudkip
+//
+// HEAD is on treecko, which has uncommitted changes.
+func (suite *RebaseTreeTestSuite) TestRebaseTree_InMemory_RefusesUncommittedChanges() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.WriteFile("treecko", "uncommitted")
+	oldTreecko := *suite.repo.LookupBranch("treecko").Target()
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	gotResult := RebaseTreeWithOptions(suite.repo.Repo, source, dest, RebaseTreeOptions{InMemory: true})
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.Contains(suite.T(), gotResult.Error.Error(), "uncommitted changes")
+	assert.Equal(suite.T(), oldTreecko, *suite.repo.LookupBranch("treecko").Target())
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-treecko"))
+	assert.Equal(suite.T(), "uncommitted", suite.repo.ReadFile("treecko"))
+}
+
+// Initial:
+//
+//	master ─── mew ─┬─ treecko ─── grovyle ─── sceptile
+//	                └─ mudkip
+//
+// Result:
+//
+//	master ─── mew ─── mudkip ─── treecko ─── grovyle ─── sceptile
+func (suite *RebaseTreeTestSuite) TestRebaseTree_InMemory_ShowsMergeConflictInWorkingTree() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.CreateAndSwitchBranch("grovyle")
+	suite.repo.WriteAndCommitFile("favorite", "grovyle", "grovyle")
+	suite.repo.BranchWithCommit("sceptile")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("favorite", "mudkip", "mudkip")
+	Init(suite.repo.Repo)
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	gotResult := RebaseTreeWithOptions(suite.repo.Repo, source, dest, RebaseTreeOptions{InMemory: true})
+
+	// treecko was rebased in memory and moved before the conflict was shown.
+	assert.Equal(suite.T(), RebaseTreeMergeConflict, gotResult.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("mudkip", "treecko"))
+	assert.True(suite.T(), suite.repo.FileExists(".git/tree/rebasing-in-memory"))
+	assert.Contains(suite.T(), suite.repo.ReadFile("favorite"), "<<<<<<<")
+
+	suite.repo.WriteFile("favorite", "grovyle")
+	suite.repo.StageFiles()
+	gotResult = RebaseTreeContinue(suite.repo.Repo)
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "grovyle"))
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("grovyle", "sceptile"))
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/rebasing-in-memory"))
+}
+
+// Initial:
+//
+//	master ─┬─ mew ─── treecko
+//	        └─ (wild)
+//
+// `wild` is an untracked commit.
+//
+// Result:
+//
+//	master ─┬─ mew
+//	        └─ (wild) ─── treecko
+func (suite *RebaseTreeTestSuite) TestRebaseTreeOntoCommit_ReanchorsSourceUnderTrackedAncestor() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.SwitchBranch("master")
+	Init(suite.repo.Repo)
+	suite.repo.BranchWithCommit("wild")
+	wild, _ := gitutil.CommitByRevision(suite.repo.Repo, "wild")
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.LookupBranch("wild").Delete()
+
+	source := suite.repo.LookupBranch("treecko")
+	gotResult := RebaseTreeOntoCommit(suite.repo.Repo, source, wild, RebaseTreeOptions{})
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	treecko, _ := gitutil.CommitByRevision(suite.repo.Repo, "treecko")
+	assert.Equal(suite.T(), wild.Id().String(), treecko.ParentId(0).String())
+	assert.Nil(suite.T(), suite.repo.LookupBranch("git-tree-rebase-dest"))
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["mew", "treecko"]}
+  ]
+}`
+	assert.Equal(suite.T(), wantString, gotString)
+}
+
+// Initial:
+//
+//	master ─── mew ─── treecko
+func (suite *RebaseTreeTestSuite) TestRebaseTreeOntoCommit_DestCannotBeCommitOfSource() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("tree", "tree", "tree")
+	suite.repo.WriteAndCommitFile("cko", "cko", "cko")
+	Init(suite.repo.Repo)
+
+	source := suite.repo.LookupBranch("treecko")
+	dest, _ := gitutil.CommitByRevision(suite.repo.Repo, "treecko~1")
+	gotResult := RebaseTreeOntoCommit(suite.repo.Repo, source, dest, RebaseTreeOptions{})
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.EqualError(suite.T(), gotResult.Error, "Source cannot be an ancestor of destination")
+	assert.Nil(suite.T(), suite.repo.LookupBranch("git-tree-rebase-dest"))
+}
+
+// Initial:
+//
+//	master ─┬─ mew ─── treecko ─── grovyle
+//	        └─ mudkip
+func (suite *RebaseTreeTestSuite) TestRebaseTreeOntoCommit_DestCannotBeInMiddleOfBranch() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("tree", "tree", "tree")
+	suite.repo.WriteAndCommitFile("cko", "cko", "cko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+	oldMudkip := *suite.repo.LookupBranch("mudkip").Target()
+
+	source := suite.repo.LookupBranch("mudkip")
+	dest, _ := gitutil.CommitByRevision(suite.repo.Repo, "treecko~1")
+	gotResult := RebaseTreeOntoCommit(suite.repo.Repo, source, dest, RebaseTreeOptions{})
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.Contains(suite.T(), gotResult.Error.Error(), "is in the middle of branch \"treecko\"")
+	assert.Equal(suite.T(), oldMudkip, *suite.repo.LookupBranch("mudkip").Target())
+	assert.Nil(suite.T(), suite.repo.LookupBranch("git-tree-rebase-dest"))
+}
+
+// Initial:
+//
+//	master ─┬─ mew ─── treecko
+//	        └─ (wild)
+//
+// Only mew and treecko are tracked, so the root of the tree is at mew. treecko
+// and `wild`, an untracked commit, both write file `favorite`.
+func (suite *RebaseTreeTestSuite) TestRebaseTreeOntoCommit_AbortRestoresRoot() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("favorite", "treecko", "treecko")
+	suite.repo.SwitchBranch("master")
+	suite.repo.CreateAndSwitchBranch("wild")
+	suite.repo.WriteAndCommitFile("favorite", "wild", "wild")
+	wild, _ := gitutil.CommitByRevision(suite.repo.Repo, "wild")
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.LookupBranch("wild").Delete()
+
+	mew := suite.repo.LookupBranch("mew")
+	treecko := suite.repo.LookupBranch("treecko")
+	Init(suite.repo.Repo, mew, treecko)
+	oldRoot := *suite.repo.LookupBranch("git-tree-root").Target()
+
+	gotResult := RebaseTreeOntoCommit(suite.repo.Repo, treecko, wild, RebaseTreeOptions{})
+
+	// The root was moved back to master to make room for the destination.
+	assert.Equal(suite.T(), RebaseTreeMergeConflict, gotResult.Type)
+	assert.NotEqual(suite.T(), oldRoot, *suite.repo.LookupBranch("git-tree-root").Target())
+	assert.True(suite.T(), suite.repo.FileExists(".git/tree/rebasing-root"))
+
+	gotResult = RebaseTreeAbort(suite.repo.Repo)
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.Equal(suite.T(), oldRoot, *suite.repo.LookupBranch("git-tree-root").Target())
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/rebasing-root"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("git-tree-rebase-dest"))
+}
+
 // -------------------------------------------------------------------------- \
 // RebaseTreeContinue                                                         |
 // -------------------------------------------------------------------------- /
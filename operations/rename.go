@@ -0,0 +1,46 @@
+package operations
+
+import (
+	"fmt"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// Rename tracked branch `oldName` to `newName`, updating the branch map.
+//
+// Interrupted operations persist branch names, so renaming is refused while
+// one is in progress.
+func Rename(repo *git.Repository, oldName string, newName string) error {
+	if err := validateNoOperationInProgress(repo, "rename a branch"); err != nil {
+		return err
+	}
+
+	branchMapPath := store.BranchMapPath(repo.Path())
+	branchMap, err := store.ReadBranchMap(repo, branchMapPath)
+	if err != nil {
+		return err
+	}
+
+	if oldName == gitutil.BranchName(branchMap.Root) {
+		return fmt.Errorf("Cannot rename the root branch %q", oldName)
+	}
+	if branchMap.FindParent(oldName) == nil {
+		return fmt.Errorf("Branch %q is not tracked by git-tree", oldName)
+	}
+
+	branch, err := repo.LookupBranch(oldName, git.BranchLocal)
+	if err != nil {
+		return fmt.Errorf("Branch %q does not exist in the git repository.", oldName)
+	}
+
+	// Renaming the branch also updates HEAD if it points to the branch.
+	newBranch, err := branch.Move(newName, false)
+	if err != nil {
+		return fmt.Errorf("Could not rename branch: %s.", err.Error())
+	}
+
+	branchMap.ReplaceBranch(oldName, newBranch)
+	return store.WriteBranchMap(branchMap, branchMapPath)
+}
@@ -0,0 +1,97 @@
+package operations
+
+import (
+	"errors"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RenameTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *RenameTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *RenameTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+func (suite *RenameTestSuite) TestRename_UpdatesBranchMapFile() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	err := Rename(suite.repo.Repo, "treecko", "mudkip")
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["mudkip"]},
+    {"branch": "mudkip", "children": ["grovyle"]}
+  ]
+}`
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, gotString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+	assert.Nil(suite.T(), suite.repo.LookupBranch("treecko"))
+	assert.NotNil(suite.T(), suite.repo.LookupBranch("mudkip"))
+}
+
+// Branches:
+//
+//	master ─── treecko ─── grovyle
+func (suite *RenameTestSuite) TestRename_HeadFollowsBranch() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	Init(suite.repo.Repo)
+
+	Rename(suite.repo.Repo, "grovyle", "sceptile")
+
+	headName := gitutil.BranchName(gitutil.HeadBranch(suite.repo.Repo))
+	assert.Equal(suite.T(), "sceptile", headName)
+}
+
+// Initial:
+//
+//	master ─── mew ─┬─ treecko
+//	                └─ mudkip
+func (suite *RenameTestSuite) TestRename_RefusedDuringRebaseTree() {
+	// Setup initial - write conflicting contents to the same file.
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("starter", "treecko", "treecko")
+	suite.repo.SwitchBranch("mew")
+	suite.repo.CreateAndSwitchBranch("mudkip")
+	suite.repo.WriteAndCommitFile("starter", "mudkip", "mudkip")
+	Init(suite.repo.Repo)
+
+	source := suite.repo.LookupBranch("treecko")
+	dest := suite.repo.LookupBranch("mudkip")
+	RebaseTree(suite.repo.Repo, source, dest)
+
+	err := Rename(suite.repo.Repo, "treecko", "grovyle")
+
+	wantError := errors.New("Cannot rename a branch while a rebase is in progress. Abort or continue the existing rebase")
+	assert.Equal(suite.T(), wantError.Error(), err.Error(),
+		"Operation got error %v, but want error %v", err, wantError)
+	assert.NotNil(suite.T(), suite.repo.LookupBranch("treecko"))
+}
+
+func TestRenameTestSuite(t *testing.T) {
+	suite.Run(t, new(RenameTestSuite))
+}
@@ -1,3 +1,7 @@
 #!/usr/bin/env bash
 
+# The hooks directory may be shared (through `core.hooksPath`) with repositories
+# that don't use git-tree.
+[ -d "$(git rev-parse --git-common-dir)/tree" ] || exit 0
+
 git-tree obsolete post-commit
@@ -0,0 +1,276 @@
+package operations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/utils"
+	git "github.com/libgit2/git2go/v34"
+)
+
+type syncRunner struct {
+	*rebaseTreeRunner
+	// The revision being synced onto.
+	trunk string
+	// Temporary branch pointing to the new tip of the trunk.
+	onto *git.Branch
+	// Branch HEAD pointed to before the sync started, or its commit if HEAD was
+	// detached (see `headString()`).
+	headBranch string
+}
+
+// -------------------------------------------------------------------------- \
+// Sync                                                                       |
+// -------------------------------------------------------------------------- /
+
+// Rebase every stack in the tree onto the tip of `trunk`, then move the root
+// of the tree (`git-tree-root`) forward to the tip of `trunk`.
+//
+// `trunk` may be any revision (e.g. `main` or `origin/main`). If `trunk` is a
+// tracked branch directly under the root, the stacks extending from it are
+// rebased onto its new tip.
+//
+// Under the hood, this is performed as a sequence of RebaseTree operations.
+func Sync(repo *git.Repository, trunk string) RebaseTreeResult {
+	return SyncWithOptions(repo, trunk, RebaseTreeOptions{})
+}
+
+// Rebase every stack in the tree onto the tip of `trunk`, as configured by
+// `opts`.
+func SyncWithOptions(repo *git.Repository, trunk string, opts RebaseTreeOptions) RebaseTreeResult {
+	// Read the branch map file.
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	trunkCommit, err := validateSync(repo, trunk, branchMap)
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	runner := newSyncRunner(repo, branchMap, trunk, nil, headString(repo))
+	runner.mergeMode = opts.MergeMode
+	runner.head = runner.headBranch
+	if err := validateOtherWorktrees(repo, runner.branchesToMove()); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	// Libgit2 rebases onto branches, so point a temporary branch at the trunk.
+	runner.onto = gitutil.CreateBranchAtCommit(repo, trunkCommit, "git-tree-sync-onto")
+	return runner.Execute()
+}
+
+// -------------------------------------------------------------------------- \
+// SyncContinue                                                               |
+// -------------------------------------------------------------------------- /
+
+func SyncContinue(repo *git.Repository) RebaseTreeResult {
+	// Try finishing the in-progress rebase.
+	rebaseResult := continueExistingRebase(repo)
+	if rebaseResult.Type != RebaseTreeSuccess {
+		return rebaseResult
+	}
+
+	// Read the branch map file.
+	branchMap, err := store.ReadBranchMap(repo, store.BranchMapPath(repo.Path()))
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+
+	// Look up the trunk, its temporary branch and the original HEAD branch.
+	trunk := utils.ReadFile(store.SyncingPath(repo.Path()))
+	ontoName := utils.ReadFile(store.SyncingOntoPath(repo.Path()))
+	onto, _ := repo.LookupBranch(ontoName, git.BranchLocal)
+	headBranch := utils.ReadFile(store.SyncingHeadPath(repo.Path()))
+
+	runner := newSyncRunner(repo, branchMap, trunk, onto, headBranch)
+	runner.mergeMode = persistedMergeMode(repo)
+
+	// Populate the runner with temporary branches from previous runs.
+	path := store.RebasingTempsPath(repo.Path())
+	runner.tempBranches = store.ReadTemporaryBranches(repo, path)
+
+	return runner.Execute()
+}
+
+// -------------------------------------------------------------------------- \
+// SyncAbort                                                                  |
+// -------------------------------------------------------------------------- /
+
+// Abort a Sync operation in progress.
+func SyncAbort(repo *git.Repository) RebaseTreeResult {
+	if result := abortExistingRebase(repo); result.Type != RebaseTreeSuccess {
+		return result
+	}
+
+	// Delete the temporary trunk branch.
+	ontoName := utils.ReadFile(store.SyncingOntoPath(repo.Path()))
+	if onto, err := repo.LookupBranch(ontoName, git.BranchLocal); err == nil {
+		onto.Delete()
+	}
+
+	headBranch := utils.ReadFile(store.SyncingHeadPath(repo.Path()))
+	deleteSyncStorage(repo)
+	restoreHead(repo, headBranch)
+
+	return RebaseTreeResult{Type: RebaseTreeSuccess}
+}
+
+// validateSync checks whether the Sync operation is valid, returning the tip
+// of the trunk if it is.
+func validateSync(repo *git.Repository, trunk string, branchMap *models.BranchMap) (*git.Commit, error) {
+	if err := validateNoRebaseInProgress(repo); err != nil {
+		return nil, err
+	}
+
+	trunkCommit, err := gitutil.CommitByRevision(repo, trunk)
+	if err != nil {
+		return nil, fmt.Errorf("Could not find trunk %q", trunk)
+	}
+
+	// A tracked trunk must sit directly under the root. Otherwise its own
+	// ancestors would be rebased onto it.
+	rootName := gitutil.BranchName(branchMap.Root)
+	if branchMap.FindBranch(trunk) != nil && !branchMap.IsBranchParent(rootName, trunk) {
+		return nil, fmt.Errorf("Trunk %q must be a child of %q", trunk, rootName)
+	}
+
+	return trunkCommit, nil
+}
+
+func newSyncRunner(repo *git.Repository, branchMap *models.BranchMap, trunk string, onto *git.Branch, headBranch string) *syncRunner {
+	return &syncRunner{
+		rebaseTreeRunner: newRebaseTreeRunner(repo, nil, nil, branchMap),
+		trunk:            trunk,
+		onto:             onto,
+		headBranch:       headBranch,
+	}
+}
+
+func (r *syncRunner) Execute() RebaseTreeResult {
+	if r.onto == nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: errors.New("Could not find the trunk of the in-progress sync")}
+	}
+
+	for _, stack := range r.stacks() {
+		result := r.syncStack(stack)
+		if result.Type == RebaseTreeMergeConflict {
+			if err := r.handleMergeConflict(); err != nil {
+				return persistError("sync", err)
+			}
+			return result
+		} else if result.Type == RebaseTreeError {
+			// Without a merge conflict to resolve, the sync cannot be
+			// continued or aborted, so undo what was synced so far.
+			if r.head != "" {
+				r.rollback()
+				r.onto.Delete()
+				result.Error = fmt.Errorf("%s. No branch was synced", result.Error.Error())
+			}
+			return result
+		}
+	}
+
+	movedBranches := movedBranchNames(r.tempBranches)
+	if result := r.handleSuccess(); result.Type != RebaseTreeSuccess {
+		return result
+	}
+	if err := updateOtherWorktrees(r.repo, movedBranches); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: err}
+	}
+	return RebaseTreeResult{Type: RebaseTreeSuccess}
+}
+
+// Returns the branches at the bottom of each stack that should be moved onto
+// the trunk.
+func (r *syncRunner) stacks() []*git.Branch {
+	rootName := gitutil.BranchName(r.branchMap.Root)
+
+	stacks := []*git.Branch{}
+	for _, child := range r.branchMap.FindChildren(rootName) {
+		// If the trunk is tracked, the stacks extending from it get moved
+		// onto its new tip instead.
+		childName := gitutil.BranchName(child)
+		if childName == r.trunk {
+			stacks = append(stacks, r.branchMap.FindChildren(childName)...)
+			continue
+		}
+		stacks = append(stacks, child)
+	}
+	return stacks
+}
+
+// Returns the names of the branches moved by the sync: the root and the
+// branches of each stack.
+func (r *syncRunner) branchesToMove() []string {
+	branchNames := []string{gitutil.BranchName(r.branchMap.Root)}
+	for _, stack := range r.stacks() {
+		branchNames = append(branchNames, r.branchMap.ListSubtreeBranchNames(gitutil.BranchName(stack))...)
+	}
+	return branchNames
+}
+
+// Rebase `stack` and all its descendants onto the trunk.
+func (r *syncRunner) syncStack(stack *git.Branch) RebaseTreeResult {
+	// Only move the commits that are not already part of the trunk.
+	baseOid, err := r.repo.MergeBase(stack.Target(), r.onto.Target())
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: fmt.Errorf("Could not find merge base: %v", err)}
+	}
+	baseCommit, _ := r.repo.LookupCommit(baseOid)
+	base := gitutil.CreateBranchAtCommit(r.repo, baseCommit, "git-tree-sync-base")
+	defer base.Delete()
+
+	return r.executeRecurse(base, r.onto, stack)
+}
+
+func (r *syncRunner) handleMergeConflict() error {
+	// Create a file indicating a sync is in progress, containing the trunk.
+	if err := utils.OverwriteFile(store.SyncingPath(r.repo.Path()), r.trunk); err != nil {
+		return err
+	}
+
+	// Store the temporary trunk branch and the original HEAD branch.
+	if err := utils.OverwriteFile(store.SyncingOntoPath(r.repo.Path()), gitutil.BranchName(r.onto)); err != nil {
+		return err
+	}
+	if err := utils.OverwriteFile(store.SyncingHeadPath(r.repo.Path()), r.headBranch); err != nil {
+		return err
+	}
+
+	return r.persistTempBranches()
+}
+
+func (r *syncRunner) handleSuccess() RebaseTreeResult {
+	trunkOid := *r.onto.Target()
+	headBranch := r.rebasedHead(r.headBranch)
+	r.onto.Delete()
+	deleteTemporaryBranches(r.tempBranches)
+	deleteSyncStorage(r.repo)
+	restoreHead(r.repo, headBranch)
+
+	// Move the root of the tree forward to the tip of the trunk.
+	rootName := gitutil.BranchName(r.branchMap.Root)
+	root, err := r.repo.LookupBranch(rootName, git.BranchLocal)
+	if err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: fmt.Errorf("Could not find root branch %q.", rootName)}
+	}
+	if _, err := root.SetTarget(&trunkOid, "[git-tree] sync root onto trunk"); err != nil {
+		return RebaseTreeResult{Type: RebaseTreeError, Error: fmt.Errorf("Could not move root branch: %s.", err.Error())}
+	}
+
+	return RebaseTreeResult{Type: RebaseTreeSuccess}
+}
+
+func deleteSyncStorage(repo *git.Repository) {
+	deleteStorage(repo)
+
+	os.Remove(store.SyncingPath(repo.Path()))
+	os.Remove(store.SyncingOntoPath(repo.Path()))
+	os.Remove(store.SyncingHeadPath(repo.Path()))
+}
@@ -0,0 +1,155 @@
+package operations
+
+import (
+	"errors"
+	"testing"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SyncTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *SyncTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *SyncTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+// Initial:
+//
+//	master ─── treecko
+func (suite *SyncTestSuite) TestSync_TrunkMustExist() {
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+
+	gotResult := Sync(suite.repo.Repo, "hoenn")
+
+	wantError := errors.New("Could not find trunk \"hoenn\"")
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.Equal(suite.T(), wantError.Error(), gotResult.Error.Error(),
+		"Operation got error %v, but want error %v", gotResult.Error, wantError)
+}
+
+// Initial:
+//
+//	master ─── treecko ─┬─ grovyle
+//	                    └─ mudkip
+//
+// Action:
+//   - Commit [hoenn] to master
+//
+// Result:
+//
+//	master (hoenn) ─── treecko ─┬─ grovyle
+//	                            └─ mudkip
+func (suite *SyncTestSuite) TestSync_RebasesStacksOntoTrackedTrunk() {
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.BranchWithCommit("grovyle")
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.BranchWithCommit("mudkip")
+	Init(suite.repo.Repo)
+
+	suite.repo.SwitchBranch("master")
+	suite.repo.WriteAndCommitFile("hoenn", "hoenn", "hoenn")
+	suite.repo.SwitchBranch("mudkip")
+
+	gotResult := Sync(suite.repo.Repo, "master")
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("master", "treecko"))
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "grovyle"))
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "mudkip"))
+
+	// The root of the tree moves forward to the tip of the trunk.
+	root := suite.repo.LookupBranch(store.GitTreeRootBranch)
+	master := suite.repo.LookupBranch("master")
+	assert.True(suite.T(), root.Target().Equal(master.Target()))
+
+	// HEAD stays on the branch it was on.
+	assert.True(suite.T(), suite.repo.FileExists("mudkip"))
+	assert.True(suite.T(), suite.repo.FileExists("hoenn"))
+}
+
+// Initial:
+//
+//	master ─┬─ mew ─── treecko ─── (merge eevee)
+//	        └─ eevee
+//
+// Action:
+//   - Commit [hoenn] to master
+//
+// treecko and hoenn both write file `favorite`.
+func (suite *SyncTestSuite) TestSync_ErrorRollsBack() {
+	suite.repo.BranchWithCommit("eevee")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.CreateAndSwitchBranch("treecko")
+	suite.repo.WriteAndCommitFile("favorite", "treecko", "treecko")
+	suite.repo.MergeBranch("eevee", "merge eevee")
+
+	mew := suite.repo.LookupBranch("mew")
+	treecko := suite.repo.LookupBranch("treecko")
+	Init(suite.repo.Repo, mew, treecko)
+	oldMew := *mew.Target()
+	oldTreecko := *treecko.Target()
+	oldRoot := *suite.repo.LookupBranch(store.GitTreeRootBranch).Target()
+
+	suite.repo.SwitchBranch("master")
+	suite.repo.WriteAndCommitFile("favorite", "hoenn", "hoenn")
+	suite.repo.SwitchBranch("treecko")
+
+	// mew is synced before treecko hits the conflict.
+	opts := RebaseTreeOptions{MergeMode: gitutil.RecreateMerges}
+	gotResult := SyncWithOptions(suite.repo.Repo, "master", opts)
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.Equal(suite.T(), oldMew, *suite.repo.LookupBranch("mew").Target())
+	assert.Equal(suite.T(), oldTreecko, *suite.repo.LookupBranch("treecko").Target())
+	assert.Equal(suite.T(), oldRoot, *suite.repo.LookupBranch(store.GitTreeRootBranch).Target())
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-mew"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("rebase-treecko"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("git-tree-sync-onto"))
+	assert.Nil(suite.T(), suite.repo.LookupBranch("git-tree-sync-base"))
+
+	// HEAD is back where it was.
+	assert.Equal(suite.T(), "treecko", gitutil.BranchName(gitutil.HeadBranch(suite.repo.Repo)))
+	assert.Equal(suite.T(), "treecko", suite.repo.ReadFile("favorite"))
+}
+
+// Initial:
+//
+//	master ─── treecko
+//
+// Action:
+//   - Commit [hoenn] to master
+//   - Another process holds the lock on the root branch
+func (suite *SyncTestSuite) TestSync_ReportsRootMoveError() {
+	suite.repo.BranchWithCommit("treecko")
+	Init(suite.repo.Repo)
+	oldRoot := *suite.repo.LookupBranch(store.GitTreeRootBranch).Target()
+
+	suite.repo.SwitchBranch("master")
+	suite.repo.WriteAndCommitFile("hoenn", "hoenn", "hoenn")
+	suite.repo.SwitchBranch("treecko")
+	suite.repo.WriteFile(".git/refs/heads/"+store.GitTreeRootBranch+".lock", "")
+
+	gotResult := Sync(suite.repo.Repo, "master")
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.ErrorContains(suite.T(), gotResult.Error, "Could not move root branch")
+	assert.Equal(suite.T(), oldRoot, *suite.repo.LookupBranch(store.GitTreeRootBranch).Target())
+}
+
+func TestSyncTestSuite(t *testing.T) {
+	suite.Run(t, new(SyncTestSuite))
+}
@@ -0,0 +1,137 @@
+package operations
+
+import (
+	"fmt"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/store"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// -------------------------------------------------------------------------- \
+// Track                                                                      |
+// -------------------------------------------------------------------------- /
+
+// Start tracking the given branches with git-tree.
+//
+// Each branch is inserted into the tree under its closest tracked ancestor.
+// Tracked branches that descend from it are moved under it. If a branch does
+// not descend from the root of the tree, the root is moved back to the
+// branch's merge-base with the root.
+func Track(repo *git.Repository, branches ...*git.Branch) error {
+	if err := validateNoOperationInProgress(repo, "track branches"); err != nil {
+		return err
+	}
+
+	branchMapPath := store.BranchMapPath(repo.Path())
+	branchMap, err := store.ReadBranchMap(repo, branchMapPath)
+	if err != nil {
+		return err
+	}
+
+	// A branch given more than once is only tracked once.
+	seen := map[string]bool{}
+	unique := []*git.Branch{}
+	for _, branch := range branches {
+		if name := gitutil.BranchName(branch); !seen[name] {
+			seen[name] = true
+			unique = append(unique, branch)
+		}
+	}
+	branches = unique
+
+	for _, branch := range branches {
+		name := gitutil.BranchName(branch)
+		if name == store.GitTreeRootBranch {
+			return fmt.Errorf("Cannot track branch %q", name)
+		}
+		if branchMap.FindBranch(name) != nil || branchMap.FindParent(name) != nil {
+			return fmt.Errorf("Branch %q is already tracked", name)
+		}
+	}
+
+	// The root ends up at the merge-base of the root and every branch, which is
+	// computed before anything is modified.
+	oldRoot := *branchMap.Root.Target()
+	newRoot := oldRoot
+	for _, branch := range branches {
+		mergeBase, err := repo.MergeBase(&newRoot, branch.Target())
+		if err != nil {
+			return fmt.Errorf("Branch %q does not share history with the tree", gitutil.BranchName(branch))
+		}
+		newRoot = *mergeBase
+	}
+
+	if err := moveRoot(branchMap.Root, &newRoot, "[git-tree] move root below tracked branch"); err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		branchMap.AddBranch(repo, branch)
+	}
+
+	if err := store.WriteBranchMap(branchMap, branchMapPath); err != nil {
+		moveRoot(branchMap.Root, &oldRoot, "[git-tree] restore root after failed track")
+		return err
+	}
+	return nil
+}
+
+// Move `root` back to its merge-base with commit `target`, if `target` does not
+// descend from it. `description` names `target` in errors.
+func moveRootBelow(repo *git.Repository, root *git.Branch, target *git.Oid, description string) error {
+	mergeBase, err := repo.MergeBase(root.Target(), target)
+	if err != nil {
+		return fmt.Errorf("%s does not share history with the tree", description)
+	}
+	return moveRoot(root, mergeBase, "[git-tree] move root below tracked branch")
+}
+
+// Point `root` at `target`, keeping `root` itself up to date so that the branch
+// map that holds it sees the move.
+func moveRoot(root *git.Branch, target *git.Oid, message string) error {
+	if target.Equal(root.Target()) {
+		return nil
+	}
+
+	newRoot, err := root.SetTarget(target, message)
+	if err != nil {
+		return fmt.Errorf("Could not move root branch: %s.", err.Error())
+	}
+	*root = *newRoot.Branch()
+	return nil
+}
+
+// -------------------------------------------------------------------------- \
+// Untrack                                                                    |
+// -------------------------------------------------------------------------- /
+
+// Stop tracking the given branches with git-tree. The branches themselves are
+// left untouched.
+//
+// The children of each branch become children of the branch's parent.
+func Untrack(repo *git.Repository, branchNames ...string) error {
+	if err := validateNoOperationInProgress(repo, "untrack branches"); err != nil {
+		return err
+	}
+
+	branchMapPath := store.BranchMapPath(repo.Path())
+	branchMap, err := store.ReadBranchMap(repo, branchMapPath)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range branchNames {
+		if name == gitutil.BranchName(branchMap.Root) {
+			return fmt.Errorf("Cannot untrack the root branch %q", name)
+		}
+		if branchMap.FindParent(name) == nil {
+			return fmt.Errorf("Branch %q is not tracked by git-tree", name)
+		}
+	}
+
+	for _, name := range branchNames {
+		branchMap.RemoveBranch(name)
+	}
+
+	return store.WriteBranchMap(branchMap, branchMapPath)
+}
@@ -0,0 +1,198 @@
+package operations
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TrackTestSuite struct {
+	suite.Suite
+	repo testutil.TestRepository
+}
+
+func (suite *TrackTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+}
+
+func (suite *TrackTestSuite) TearDownTest() {
+	suite.repo.Free()
+}
+
+// Branches:
+//
+//	master ─── mew ─── burmy ───┬─ wormadam
+//	                            └─ mothim
+func (suite *TrackTestSuite) setupBurmyTree() {
+	suite.repo.BranchWithCommit("mew")
+	suite.repo.BranchWithCommit("burmy")
+	suite.repo.BranchWithCommit("wormadam")
+	suite.repo.SwitchBranch("burmy")
+	suite.repo.BranchWithCommit("mothim")
+}
+
+// -------------------------------------------------------------------------- \
+// Track                                                                      |
+// -------------------------------------------------------------------------- /
+
+func (suite *TrackTestSuite) TestTrack_ReparentsDescendants() {
+	suite.setupBurmyTree()
+	mew := suite.repo.LookupBranch("mew")
+	wormadam := suite.repo.LookupBranch("wormadam")
+	mothim := suite.repo.LookupBranch("mothim")
+	Init(suite.repo.Repo, mew, wormadam, mothim)
+
+	err := Track(suite.repo.Repo, suite.repo.LookupBranch("burmy"))
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["mew"]},
+    {"branch": "mew", "children": ["burmy"]},
+    {"branch": "burmy", "children": ["wormadam", "mothim"]}
+  ]
+}`
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, gotString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+}
+
+func (suite *TrackTestSuite) TestTrack_MovesRootBelowBranch() {
+	suite.setupBurmyTree()
+	mew := suite.repo.LookupBranch("mew")
+	wormadam := suite.repo.LookupBranch("wormadam")
+	Init(suite.repo.Repo, mew, wormadam)
+
+	err := Track(suite.repo.Repo, suite.repo.LookupBranch("master"))
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["mew"]},
+    {"branch": "mew", "children": ["wormadam"]}
+  ]
+}`
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, gotString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+
+	master := suite.repo.LookupBranch("master")
+	gitTreeRoot := suite.repo.LookupBranch("git-tree-root")
+	assert.Zero(suite.T(), master.Reference.Cmp(gitTreeRoot.Reference),
+		"Expected branch %q to point to branch %q, but it does not", "git-tree-root", "master")
+}
+
+// Action:
+//   - Another git-tree process holds the lock on the branch map
+func (suite *TrackTestSuite) TestTrack_WriteErrorRestoresRoot() {
+	suite.setupBurmyTree()
+	mew := suite.repo.LookupBranch("mew")
+	wormadam := suite.repo.LookupBranch("wormadam")
+	Init(suite.repo.Repo, mew, wormadam)
+	rootBefore := *suite.repo.LookupBranch(store.GitTreeRootBranch).Target()
+	branchesBefore := suite.repo.ReadFile(".git/tree/branches")
+
+	lock, err := store.AcquireLock(suite.repo.Repo.Path())
+	assert.Nil(suite.T(), err)
+	defer lock.Release()
+
+	err = Track(suite.repo.Repo, suite.repo.LookupBranch("master"))
+
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), rootBefore, *suite.repo.LookupBranch(store.GitTreeRootBranch).Target())
+	assert.Equal(suite.T(), branchesBefore, suite.repo.ReadFile(".git/tree/branches"))
+}
+
+func (suite *TrackTestSuite) TestTrack_DuplicateBranchTrackedOnce() {
+	suite.setupBurmyTree()
+	mew := suite.repo.LookupBranch("mew")
+	Init(suite.repo.Repo, mew)
+
+	burmy := suite.repo.LookupBranch("burmy")
+	err := Track(suite.repo.Repo, burmy, burmy)
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["mew"]},
+    {"branch": "mew", "children": ["burmy"]}
+  ]
+}`
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, gotString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+}
+
+func (suite *TrackTestSuite) TestTrack_BranchAlreadyTracked() {
+	suite.setupBurmyTree()
+	Init(suite.repo.Repo)
+
+	err := Track(suite.repo.Repo, suite.repo.LookupBranch("burmy"))
+
+	wantError := errors.New("Branch \"burmy\" is already tracked")
+	assert.Equal(suite.T(), wantError.Error(), err.Error(),
+		"Operation got error %v, but want error %v", err, wantError)
+}
+
+// -------------------------------------------------------------------------- \
+// Untrack                                                                    |
+// -------------------------------------------------------------------------- /
+
+func (suite *TrackTestSuite) TestUntrack_HandsChildrenToParent() {
+	suite.setupBurmyTree()
+	Init(suite.repo.Repo)
+
+	err := Untrack(suite.repo.Repo, "burmy")
+
+	gotString := suite.repo.ReadFile(".git/tree/branches")
+	wantString :=
+		`{
+  "version": 2,
+  "root": "git-tree-root",
+  "tree": [
+    {"branch": "git-tree-root", "children": ["master"]},
+    {"branch": "master", "children": ["mew"]},
+    {"branch": "mew", "children": ["mothim", "wormadam"]}
+  ]
+}`
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), wantString, gotString,
+		"Got branch map file: %v, but want file: %v", gotString, wantString)
+
+	// The branch itself is left untouched.
+	assert.NotNil(suite.T(), suite.repo.LookupBranch("burmy"))
+}
+
+func (suite *TrackTestSuite) TestUntrack_BranchNotTracked() {
+	suite.setupBurmyTree()
+	mew := suite.repo.LookupBranch("mew")
+	Init(suite.repo.Repo, mew)
+
+	err := Untrack(suite.repo.Repo, "burmy")
+
+	wantError := errors.New("Branch \"burmy\" is not tracked by git-tree")
+	assert.Equal(suite.T(), wantError.Error(), err.Error(),
+		"Operation got error %v, but want error %v", err, wantError)
+}
+
+func TestTrackTestSuite(t *testing.T) {
+	suite.Run(t, new(TrackTestSuite))
+}
@@ -0,0 +1,52 @@
+package operations
+
+import (
+	"fmt"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+	"github.com/acamadeo/git-tree/models"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// Returns an error if any of `branchNames`, which are about to be moved, is
+// checked out in another worktree with uncommitted changes. That worktree
+// could not be updated without losing them.
+func validateOtherWorktrees(repo *git.Repository, branchNames []string) error {
+	for branchName, worktree := range gitutil.WorktreesOfBranches(repo, branchNames...) {
+		worktreeRepo, err := git.OpenRepository(worktree.Path)
+		if err != nil {
+			return fmt.Errorf("Could not open worktree %s, where branch %q is checked out: %s", worktree.Path, branchName, err.Error())
+		}
+		dirty, err := gitutil.HasUncommittedChanges(worktreeRepo)
+		worktreeRepo.Free()
+
+		if err != nil {
+			return fmt.Errorf("Could not read the status of worktree %s, where branch %q is checked out: %s", worktree.Path, branchName, err.Error())
+		}
+		if dirty {
+			return fmt.Errorf("Branch %q is checked out in worktree %s, which has uncommitted changes. Commit or stash them first", branchName, worktree.Path)
+		}
+	}
+	return nil
+}
+
+// Update the files of the other worktrees where any of `branchNames`, which
+// were just moved, is checked out.
+func updateOtherWorktrees(repo *git.Repository, branchNames []string) error {
+	for branchName, worktree := range gitutil.WorktreesOfBranches(repo, branchNames...) {
+		if err := gitutil.UpdateWorktree(worktree.Path); err != nil {
+			return fmt.Errorf("Branch %q was moved, but worktree %s could not be updated: %s", branchName, worktree.Path, err.Error())
+		}
+	}
+	return nil
+}
+
+// Returns the names of the branches moved by a RebaseTree operation, i.e. the
+// branches that have a temporary branch.
+func movedBranchNames(tempBranches models.TempBranchMap) []string {
+	branchNames := []string{}
+	for _, origBranch := range tempBranches {
+		branchNames = append(branchNames, gitutil.BranchName(origBranch))
+	}
+	return branchNames
+}
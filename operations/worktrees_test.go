@@ -0,0 +1,91 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/acamadeo/git-tree/store"
+	"github.com/acamadeo/git-tree/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WorktreesTestSuite struct {
+	suite.Suite
+	repo     testutil.TestRepository
+	worktree testutil.TestRepository
+}
+
+// Initial:
+//
+//	master ─┬─ treecko
+//	        └─ mudkip    (checked out in another worktree)
+func (suite *WorktreesTestSuite) SetupTest() {
+	suite.repo = testutil.CreateTestRepo()
+	suite.repo.BranchWithCommit("treecko")
+	suite.repo.SwitchBranch("master")
+	suite.repo.BranchWithCommit("mudkip")
+	suite.repo.SwitchBranch("master")
+	Init(suite.repo.Repo)
+
+	suite.worktree = suite.repo.AddWorktree("mudkip")
+}
+
+func (suite *WorktreesTestSuite) TearDownTest() {
+	suite.worktree.Free()
+	suite.repo.Free()
+}
+
+func (suite *WorktreesTestSuite) TestStore_SharesTreeButNotOperationsInProgress() {
+	mainPath := suite.repo.Repo.Path()
+	worktreePath := suite.worktree.Repo.Path()
+
+	assert.Equal(suite.T(), store.BranchMapPath(mainPath), store.BranchMapPath(worktreePath))
+	assert.Equal(suite.T(), store.ObsoleteMapPath(mainPath), store.ObsoleteMapPath(worktreePath))
+	assert.NotEqual(suite.T(), store.RebasingPath(mainPath), store.RebasingPath(worktreePath))
+	assert.NotEqual(suite.T(), store.PreCommitParentPath(mainPath), store.PreCommitParentPath(worktreePath))
+}
+
+func (suite *WorktreesTestSuite) TestStore_WorktreesShareLock() {
+	lock, err := store.AcquireLock(suite.worktree.Repo.Path())
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.repo.FileExists(".git/tree/lock"))
+	lock.Release()
+	assert.False(suite.T(), suite.repo.FileExists(".git/tree/lock"))
+}
+
+func (suite *WorktreesTestSuite) TestRebaseTree_UpdatesOtherWorktree() {
+	source := suite.repo.LookupBranch("mudkip")
+	dest := suite.repo.LookupBranch("treecko")
+	gotResult := RebaseTree(suite.repo.Repo, source, dest)
+
+	assert.Equal(suite.T(), RebaseTreeSuccess, gotResult.Type)
+	assert.True(suite.T(), suite.repo.IsBranchAncestor("treecko", "mudkip"))
+	assert.True(suite.T(), suite.worktree.FileExists("treecko"))
+	assert.True(suite.T(), suite.worktree.FileExists("mudkip"))
+}
+
+func (suite *WorktreesTestSuite) TestRebaseTree_RefusesDirtyWorktree() {
+	suite.worktree.WriteFile("mudkip", "uncommitted")
+
+	source := suite.repo.LookupBranch("mudkip")
+	dest := suite.repo.LookupBranch("treecko")
+	gotResult := RebaseTree(suite.repo.Repo, source, dest)
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.Contains(suite.T(), gotResult.Error.Error(), "has uncommitted changes")
+	assert.False(suite.T(), suite.repo.IsBranchAncestor("treecko", "mudkip"))
+	assert.Equal(suite.T(), "uncommitted", suite.worktree.ReadFile("mudkip"))
+}
+
+func (suite *WorktreesTestSuite) TestDelete_RefusesBranchOfOtherWorktree() {
+	gotResult := Delete(suite.repo.Repo, "mudkip", false)
+
+	assert.Equal(suite.T(), RebaseTreeError, gotResult.Type)
+	assert.Contains(suite.T(), gotResult.Error.Error(), "which is checked out in worktree")
+	assert.NotNil(suite.T(), suite.repo.LookupBranch("mudkip"))
+}
+
+func TestWorktreesTestSuite(t *testing.T) {
+	suite.Run(t, new(WorktreesTestSuite))
+}
@@ -1,8 +1,6 @@
 package store
 
 import (
-	"bufio"
-	"os"
 	"strings"
 
 	gitutil "github.com/acamadeo/git-tree/git"
@@ -12,94 +10,139 @@ import (
 	"golang.org/x/exp/maps"
 )
 
-// Read branch map file.
+// The branch map file holds the root branch and, for each branch with
+// children, the branch's children (in depth-first order):
 //
-// It is expected that the file exists.
-func ReadBranchMap(repo *git.Repository, filepath string) *models.BranchMap {
-	readFile, _ := os.Open(filepath)
-	defer readFile.Close()
+//	{
+//	  "version": 2,
+//	  "root": "git-tree-root",
+//	  "tree": [
+//	    {"branch": "git-tree-root", "children": ["master"]},
+//	    ...
+//	  ]
+//	}
+type branchMapDoc struct {
+	Version int             `json:"version"`
+	Root    string          `json:"root"`
+	Tree    []branchMapNode `json:"tree"`
+}
 
-	fileScanner := bufio.NewScanner(readFile)
-	fileScanner.Split(bufio.ScanLines)
+type branchMapNode struct {
+	Branch   string   `json:"branch"`
+	Children []string `json:"children"`
+}
 
-	lines := []string{}
-	for fileScanner.Scan() {
-		lines = append(lines, fileScanner.Text())
+// Read branch map file.
+//
+// It is expected that the file exists. Returns a NewerFormatError if it was
+// written by a newer version of git-tree.
+func ReadBranchMap(repo *git.Repository, filepath string) (*models.BranchMap, error) {
+	doc, _, err := readBranchMapDoc(filepath)
+	if err != nil {
+		return nil, err
 	}
-
-	return string2BranchMap(repo, lines)
+	return doc2BranchMap(repo, doc), nil
 }
 
 // Write branch map file.
-func WriteBranchMap(branchMap *models.BranchMap, filepath string) {
-	utils.OverwriteFile(filepath, branchMap2String(branchMap))
+func WriteBranchMap(branchMap *models.BranchMap, filepath string) error {
+	return writeLocked(filepath, encodeFile(branchMap2Doc(branchMap)))
 }
 
-/**
- * Converts branch to and from a string representation for storage.
- */
+// Returns true if the branch map file was in the line-based format.
+func readBranchMapDoc(filepath string) (*branchMapDoc, bool, error) {
+	doc := &branchMapDoc{}
+	legacy, err := decodeFile(filepath, utils.ReadFile(filepath), doc, func(lines []string) {
+		*doc = legacyLines2BranchMapDoc(lines)
+	})
+	return doc, legacy, err
+}
 
-func branchAndChildrenString(branchMap *models.BranchMap, branch string) string {
-	names := []string{branch}
+// Returns the names of the branches in `contents`, the contents of a branch map
+// file in any format.
+func BranchMapFileNames(contents string) []string {
+	doc := &branchMapDoc{}
+	decodeFile(gitTreeFileNames[BranchMap], contents, doc, func(lines []string) {
+		*doc = legacyLines2BranchMapDoc(lines)
+	})
+
+	names := extractBranchNames(doc.Tree)
+	if doc.Root != "" {
+		names = append(names, doc.Root)
+	}
+	return names
+}
 
-	children := branchMap.FindChildren(branch)
-	for _, child := range children {
-		names = append(names, gitutil.BranchName(child))
+// In the line-based format, the first line is the name of the root branch.
+// Each following line lists a branch and then its children.
+func legacyLines2BranchMapDoc(lines []string) branchMapDoc {
+	doc := branchMapDoc{Version: FormatVersion, Tree: []branchMapNode{}}
+	if len(lines) == 0 {
+		return doc
 	}
-	return strings.Join(names, " ")
+
+	doc.Root = strings.TrimSpace(lines[0])
+	for _, line := range lines[1:] {
+		names := strings.Fields(line)
+		doc.Tree = append(doc.Tree, branchMapNode{Branch: names[0], Children: names[1:]})
+	}
+	return doc
 }
 
-func branchMap2StringRecurse(branchMap *models.BranchMap, branch string) []string {
+/**
+ * Converts branch to and from a representation for storage.
+ */
+
+func branchMap2DocRecurse(branchMap *models.BranchMap, branch string) []branchMapNode {
 	// Skip branches without any children.
 	children := branchMap.FindChildren(branch)
 	if len(children) == 0 {
-		return []string{}
+		return []branchMapNode{}
 	}
 
-	output := []string{}
-
 	// Add the current branch's children.
-	branchAndChildren := branchAndChildrenString(branchMap, branch)
-	output = append(output, branchAndChildren)
+	node := branchMapNode{Branch: branch, Children: []string{}}
+	for _, child := range children {
+		node.Children = append(node.Children, gitutil.BranchName(child))
+	}
+	output := []branchMapNode{node}
 
 	// Add children in DFS order.
 	for _, child := range children {
 		childName := gitutil.BranchName(child)
-		output = append(output, branchMap2StringRecurse(branchMap, childName)...)
+		output = append(output, branchMap2DocRecurse(branchMap, childName)...)
 	}
 	return output
 }
 
-func branchMap2String(branchMap *models.BranchMap) string {
-	// First print the name of the root branch.
+func branchMap2Doc(branchMap *models.BranchMap) *branchMapDoc {
 	rootName := gitutil.BranchName(branchMap.Root)
-	output := []string{rootName}
-
-	output = append(output, branchMap2StringRecurse(branchMap, rootName)...)
-
-	return strings.Join(output, "\n")
+	return &branchMapDoc{
+		Version: FormatVersion,
+		Root:    rootName,
+		Tree:    branchMap2DocRecurse(branchMap, rootName),
+	}
 }
 
-func string2BranchMap(repo *git.Repository, input []string) *models.BranchMap {
-	// First line is the name of the root branch.
-	root, _ := repo.LookupBranch(input[0], git.BranchLocal)
+func doc2BranchMap(repo *git.Repository, doc *branchMapDoc) *models.BranchMap {
+	root, _ := repo.LookupBranch(doc.Root, git.BranchLocal)
 
 	// Create a lookup table from branch name to its *git.Branch.
-	branchNames := extractBranchNames(input[1:])
+	branchNames := extractBranchNames(doc.Tree)
 	nameMap := namesToBranches(repo, branchNames)
 
 	return &models.BranchMap{
 		Root:     root,
-		Children: populateChildrenMap(input[1:], nameMap),
+		Children: populateChildrenMap(doc.Tree, nameMap),
 	}
 }
 
-// Return all the branches used in the parent-children string representation.
-func extractBranchNames(childrenLines []string) []string {
+// Return all the branches used in the parent-children representation.
+func extractBranchNames(tree []branchMapNode) []string {
 	nameSet := map[string]struct{}{}
-	for _, line := range childrenLines {
-		names := strings.Split(strings.TrimSpace(line), " ")
-		for _, name := range names {
+	for _, node := range tree {
+		nameSet[node.Branch] = struct{}{}
+		for _, name := range node.Children {
 			nameSet[name] = struct{}{}
 		}
 	}
@@ -119,23 +162,17 @@ func namesToBranches(repo *git.Repository, names []string) map[string]*git.Branc
 
 // Populate a mapping from each branch to its children branches.
 //
-// Receives the lines in the string representation of the children map. Also
+// Receives the parent-children representation of the children map. Also
 // receives a lookup table from branch name to its *git.Branch.
-func populateChildrenMap(childrenLines []string, nameMap map[string]*git.Branch) map[*git.Branch]models.BranchList {
+func populateChildrenMap(tree []branchMapNode, nameMap map[string]*git.Branch) map[*git.Branch]models.BranchList {
 	childrenMap := initChildrenMap(nameMap)
 
-	for _, line := range childrenLines {
-		names := strings.Split(strings.TrimSpace(line), " ")
-
-		// First name is the parent branch. Following names are its children.
-		parent := names[0]
-		children := names[1:]
-
-		parentBranch := nameMap[parent]
+	for _, node := range tree {
+		parentBranch := nameMap[node.Branch]
 		childrenMap[parentBranch] = models.BranchList{}
 
 		// Add each child branch under its parent.
-		for _, child := range children {
+		for _, child := range node.Children {
 			childBranch := nameMap[child]
 			childrenMap[parentBranch] = append(childrenMap[parentBranch], childBranch)
 		}
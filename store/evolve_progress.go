@@ -0,0 +1,146 @@
+package store
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/utils"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// The progress file of an interrupted `git-tree evolve`:
+//
+//	{
+//	  "version": 2,
+//	  "head": "<branch>",
+//	  "pending": {"commit": "<oid>", "onto": "<oid>", "branch": "<temp-branch>"},
+//	  "temps": ["<temp-branch>", ...],
+//	  "rebased": [{"commit": "<oid>", "onto": "<oid>", "rebased": "<oid>"}, ...],
+//	  "in-memory": true
+//	}
+//
+// `in-memory` is omitted unless commits are rebased in memory.
+type evolveProgressDoc struct {
+	Version  int                `json:"version"`
+	Head     string             `json:"head"`
+	Pending  evolvePendingDoc   `json:"pending"`
+	Temps    []string           `json:"temps"`
+	Rebased  []evolveRebasedDoc `json:"rebased"`
+	InMemory bool               `json:"in-memory,omitempty"`
+}
+
+type evolvePendingDoc struct {
+	Commit string `json:"commit"`
+	Onto   string `json:"onto"`
+	Branch string `json:"branch"`
+}
+
+type evolveRebasedDoc struct {
+	Commit  string `json:"commit"`
+	Onto    string `json:"onto"`
+	Rebased string `json:"rebased"`
+}
+
+// Read the progress file of an interrupted `git-tree evolve`.
+func ReadEvolveProgress(filepath string) *models.EvolveProgress {
+	progress := &models.EvolveProgress{Rebased: map[models.EvolveStep]git.Oid{}}
+
+	doc, _, _ := readEvolveProgressDoc(filepath)
+	progress.HeadBranch = doc.Head
+	if doc.Pending.Commit != "" {
+		progress.Pending = evolveStepFromStrings(doc.Pending.Commit, doc.Pending.Onto)
+		progress.PendingBranch = doc.Pending.Branch
+	}
+	progress.TempBranches = doc.Temps
+	progress.InMemory = doc.InMemory
+	for _, rebased := range doc.Rebased {
+		step := evolveStepFromStrings(rebased.Commit, rebased.Onto)
+		rebasedOid, _ := git.NewOid(rebased.Rebased)
+		progress.Rebased[step] = *rebasedOid
+	}
+	return progress
+}
+
+// Returns true if the progress file was in the line-based format.
+func readEvolveProgressDoc(filepath string) (*evolveProgressDoc, bool, error) {
+	doc := &evolveProgressDoc{Version: FormatVersion, Rebased: []evolveRebasedDoc{}}
+	legacy, err := decodeFile(filepath, utils.ReadFile(filepath), doc, func(lines []string) {
+		*doc = legacyLines2EvolveProgressDoc(lines)
+	})
+	return doc, legacy, err
+}
+
+// In the line-based format, each line starts with the kind of record it holds:
+//
+//	head <branch>
+//	pending <commit> <onto> <temp-branch>
+//	temp <temp-branch>
+//	rebased <commit> <onto> <rebased-commit>
+func legacyLines2EvolveProgressDoc(lines []string) evolveProgressDoc {
+	doc := evolveProgressDoc{Version: FormatVersion, Rebased: []evolveRebasedDoc{}}
+	for _, line := range lines {
+		lineParts := strings.Fields(line)
+
+		switch lineParts[0] {
+		case "head":
+			if len(lineParts) > 1 {
+				doc.Head = lineParts[1]
+			}
+		case "pending":
+			doc.Pending = evolvePendingDoc{Commit: lineParts[1], Onto: lineParts[2], Branch: lineParts[3]}
+		case "temp":
+			doc.Temps = append(doc.Temps, lineParts[1])
+		case "rebased":
+			doc.Rebased = append(doc.Rebased, evolveRebasedDoc{
+				Commit:  lineParts[1],
+				Onto:    lineParts[2],
+				Rebased: lineParts[3],
+			})
+		}
+	}
+	return doc
+}
+
+func evolveStepFromStrings(commit string, onto string) models.EvolveStep {
+	commitOid, _ := git.NewOid(commit)
+	ontoOid, _ := git.NewOid(onto)
+	return models.EvolveStep{Commit: *commitOid, Onto: *ontoOid}
+}
+
+// Write the progress file of an interrupted `git-tree evolve`.
+func WriteEvolveProgress(progress *models.EvolveProgress, filepath string) error {
+	return writeLocked(filepath, encodeFile(evolveProgress2Doc(progress)))
+}
+
+// Rebased steps are listed in sorted order for consistency.
+func evolveProgress2Doc(progress *models.EvolveProgress) *evolveProgressDoc {
+	doc := &evolveProgressDoc{
+		Version: FormatVersion,
+		Head:    progress.HeadBranch,
+		Pending: evolvePendingDoc{
+			Commit: progress.Pending.Commit.String(),
+			Onto:   progress.Pending.Onto.String(),
+			Branch: progress.PendingBranch,
+		},
+		Temps:    progress.TempBranches,
+		Rebased:  []evolveRebasedDoc{},
+		InMemory: progress.InMemory,
+	}
+
+	for step, oid := range progress.Rebased {
+		doc.Rebased = append(doc.Rebased, evolveRebasedDoc{
+			Commit:  step.Commit.String(),
+			Onto:    step.Onto.String(),
+			Rebased: oid.String(),
+		})
+	}
+	sort.Slice(doc.Rebased, func(i, j int) bool {
+		a, b := doc.Rebased[i], doc.Rebased[j]
+		if a.Commit != b.Commit {
+			return a.Commit < b.Commit
+		}
+		return a.Onto < b.Onto
+	})
+	return doc
+}
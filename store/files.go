@@ -1,6 +1,10 @@
 package store
 
-import "path/filepath"
+import (
+	"path/filepath"
+
+	gitutil "github.com/acamadeo/git-tree/git"
+)
 
 type GitTreeFile int
 
@@ -12,6 +16,18 @@ const (
 	RebaseSource
 	RebaseDest
 	RebaseTemporaryBranches
+	RebaseRecreateMerges
+	RebaseHead
+	RebaseInMemory
+	RebaseRoot
+	SyncInProgress
+	SyncOnto
+	SyncHead
+	EvolveInProgress
+	DeleteInProgress
+	DeleteHead
+	OperationLog
+	StoreLock
 )
 
 var gitTreeFileNames = map[GitTreeFile]string{
@@ -22,22 +38,58 @@ var gitTreeFileNames = map[GitTreeFile]string{
 	RebaseSource:            "rebasing-source",
 	RebaseDest:              "rebasing-dest",
 	RebaseTemporaryBranches: "rebasing-temps",
+	RebaseRecreateMerges:    "rebasing-merges",
+	RebaseHead:              "rebasing-head",
+	RebaseInMemory:          "rebasing-in-memory",
+	RebaseRoot:              "rebasing-root",
+	SyncInProgress:          "syncing",
+	SyncOnto:                "syncing-onto",
+	SyncHead:                "syncing-head",
+	EvolveInProgress:        "evolving",
+	DeleteInProgress:        "deleting",
+	DeleteHead:              "deleting-head",
+	OperationLog:            "oplog",
+	StoreLock:               "lock",
+}
+
+// The files shared by all the worktrees of a repository. The other files track
+// operations in progress (or the commit being amended), which belong to the
+// worktree they were started in.
+var sharedGitTreeFiles = map[GitTreeFile]bool{
+	BranchMap:    true,
+	ObsoleteMap:  true,
+	OperationLog: true,
+	StoreLock:    true,
 }
 
 const GitTreeRootBranch = "git-tree-root"
 
 const GitTreeSubdir = "tree"
 
+// Returns the path of `fileType` for the repository whose git directory is
+// `gitPath`. Shared files are stored under the common git directory, so every
+// worktree sees the same tree.
 func GitTreeFilePath(gitPath string, fileType GitTreeFile) string {
 	fileName := gitTreeFileNames[fileType]
-	return filepath.Join(gitPath, GitTreeSubdir, fileName)
+	if sharedGitTreeFiles[fileType] {
+		return filepath.Join(GitTreeSubdirPath(gitPath), fileName)
+	}
+	return filepath.Join(WorktreeGitTreeSubdirPath(gitPath), fileName)
 }
 
 // -------------------------------------------------------------------------- \
 // Ease-of-use functions                                                      |
 // -------------------------------------------------------------------------- /
 
+// Returns the directory of the files shared by all worktrees.
 func GitTreeSubdirPath(gitPath string) string {
+	return filepath.Join(gitutil.CommonDir(gitPath), GitTreeSubdir)
+}
+
+// Returns the directory of the files that belong to the worktree whose git
+// directory is `gitPath`. It is the same as `GitTreeSubdirPath()` in the main
+// worktree.
+func WorktreeGitTreeSubdirPath(gitPath string) string {
 	return filepath.Join(gitPath, GitTreeSubdir)
 }
 
@@ -68,3 +120,51 @@ func RebasingDestPath(gitPath string) string {
 func RebasingTempsPath(gitPath string) string {
 	return GitTreeFilePath(gitPath, RebaseTemporaryBranches)
 }
+
+func RebasingMergesPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, RebaseRecreateMerges)
+}
+
+func RebasingHeadPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, RebaseHead)
+}
+
+func RebasingInMemoryPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, RebaseInMemory)
+}
+
+func RebasingRootPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, RebaseRoot)
+}
+
+func SyncingPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, SyncInProgress)
+}
+
+func SyncingOntoPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, SyncOnto)
+}
+
+func SyncingHeadPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, SyncHead)
+}
+
+func EvolvingPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, EvolveInProgress)
+}
+
+func DeletingPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, DeleteInProgress)
+}
+
+func DeletingHeadPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, DeleteHead)
+}
+
+func OplogPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, OperationLog)
+}
+
+func LockPath(gitPath string) string {
+	return GitTreeFilePath(gitPath, StoreLock)
+}
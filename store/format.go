@@ -0,0 +1,205 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/acamadeo/git-tree/utils"
+)
+
+// Version of the format of the files under `.git/tree`.
+//
+// Version 1 is the original line-based format, which had no version field.
+// Files in version 1 are still read, and are rewritten in the current format
+// by MigrateFormat.
+const FormatVersion = 2
+
+const legacyFormatVersion = 1
+
+// Returned when a file under `.git/tree` was written by a newer version of
+// git-tree than this one.
+type NewerFormatError struct {
+	Path    string
+	Version int
+}
+
+func (e *NewerFormatError) Error() string {
+	return fmt.Sprintf("%s uses format version %d, but this git-tree only supports up to version %d. Upgrade git-tree",
+		e.Path, e.Version, FormatVersion)
+}
+
+// Every versioned file is a JSON object with a `version` field.
+type formatHeader struct {
+	Version int `json:"version"`
+}
+
+// Returns the format version of the contents of file `path`.
+func formatVersion(path string, contents string) (int, error) {
+	if !strings.HasPrefix(strings.TrimSpace(contents), "{") {
+		return legacyFormatVersion, nil
+	}
+
+	header := formatHeader{}
+	if err := json.Unmarshal([]byte(contents), &header); err != nil {
+		return 0, fmt.Errorf("Could not parse %s: %s", path, err.Error())
+	}
+	if header.Version > FormatVersion {
+		return 0, &NewerFormatError{Path: path, Version: header.Version}
+	}
+	return header.Version, nil
+}
+
+// Decode the contents of file `path` into `doc`, using `parseLegacy` if the
+// file is in the line-based format.
+//
+// Returns true if the file was in the line-based format.
+func decodeFile(path string, contents string, doc any, parseLegacy func(lines []string)) (bool, error) {
+	version, err := formatVersion(path, contents)
+	if err != nil {
+		return false, err
+	}
+
+	if version == legacyFormatVersion {
+		lines := []string{}
+		for _, line := range strings.Split(contents, "\n") {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+		parseLegacy(lines)
+		return true, nil
+	}
+
+	if err := json.Unmarshal([]byte(contents), doc); err != nil {
+		return false, fmt.Errorf("Could not parse %s: %s", path, err.Error())
+	}
+	return false, nil
+}
+
+// Encode `doc` as indented JSON. Each object in an array goes on its own line,
+// so every branch or entry reads as one line.
+func encodeFile(doc any) string {
+	raw, _ := json.Marshal(doc)
+	return formatJSON(raw, "", true)
+}
+
+func formatJSON(raw json.RawMessage, indent string, top bool) string {
+	if len(raw) == 0 || (raw[0] != '{' && raw[0] != '[') {
+		return string(raw)
+	}
+
+	// Split the object or array into its members, keeping their order.
+	isObject := raw[0] == '{'
+	keys, values := []string{}, []json.RawMessage{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.Token()
+	for decoder.More() {
+		if isObject {
+			key, _ := decoder.Token()
+			quoted, _ := json.Marshal(key)
+			keys = append(keys, string(quoted)+": ")
+		} else {
+			keys = append(keys, "")
+		}
+		value := json.RawMessage{}
+		decoder.Decode(&value)
+		values = append(values, value)
+	}
+
+	openBracket, closeBracket := "[", "]"
+	if isObject {
+		openBracket, closeBracket = "{", "}"
+	}
+
+	members := []string{}
+	for i, value := range values {
+		members = append(members, keys[i]+formatJSON(value, "", false))
+	}
+	inline := openBracket + strings.Join(members, ", ") + closeBracket
+	arrayOfObjects := !isObject && len(values) > 0 && values[0][0] == '{'
+	if len(values) == 0 || (!top && !arrayOfObjects && !strings.Contains(inline, "\n")) {
+		return inline
+	}
+
+	memberIndent := indent + "  "
+	members = []string{}
+	for i, value := range values {
+		members = append(members, memberIndent+keys[i]+formatJSON(value, memberIndent, false))
+	}
+	return openBracket + "\n" + strings.Join(members, ",\n") + "\n" + indent + closeBracket
+}
+
+// The files that are stored in a versioned format, along with a function that
+// reads each one (migrating it from the line-based format if needed).
+var versionedFiles = map[GitTreeFile]func(path string) (any, bool, error){
+	BranchMap:               func(path string) (any, bool, error) { return readBranchMapDoc(path) },
+	ObsoleteMap:             func(path string) (any, bool, error) { return readObsolescenceMapDoc(path) },
+	RebaseTemporaryBranches: func(path string) (any, bool, error) { return readTempBranchesDoc(path) },
+	EvolveInProgress:        func(path string) (any, bool, error) { return readEvolveProgressDoc(path) },
+}
+
+// Rewrite any files under `.git/tree` that are in an older format in the
+// current format.
+//
+// Returns a NewerFormatError without changing anything if a file was written
+// by a newer version of git-tree.
+func MigrateFormat(gitPath string) error {
+	if !utils.DirExists(GitTreeSubdirPath(gitPath)) {
+		return nil
+	}
+
+	// Most commands find every file in the current format already, so only
+	// take the lock when there is something to migrate.
+	if migrate, err := needsMigration(gitPath); err != nil || !migrate {
+		return err
+	}
+
+	return withLock(BranchMapPath(gitPath), func() error {
+		migrated := map[string]any{}
+		for file, read := range versionedFiles {
+			path := GitTreeFilePath(gitPath, file)
+			if !utils.FileExists(path) {
+				continue
+			}
+
+			doc, legacy, err := read(path)
+			if err != nil {
+				return err
+			}
+			if legacy {
+				migrated[path] = doc
+			}
+		}
+
+		for path, doc := range migrated {
+			if err := utils.OverwriteFile(path, encodeFile(doc)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Returns true if any file under `.git/tree` is in the line-based format.
+//
+// Only reads the version of each file, without taking the lock.
+func needsMigration(gitPath string) (bool, error) {
+	migrate := false
+	for file := range versionedFiles {
+		path := GitTreeFilePath(gitPath, file)
+		if !utils.FileExists(path) {
+			continue
+		}
+
+		version, err := formatVersion(path, utils.ReadFile(path))
+		if err != nil {
+			return false, err
+		}
+		if version == legacyFormatVersion {
+			migrate = true
+		}
+	}
+	return migrate, nil
+}
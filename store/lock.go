@@ -0,0 +1,157 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/acamadeo/git-tree/utils"
+)
+
+// How long to wait for another process to release the lock.
+const lockTimeout = 5 * time.Second
+
+// How often to check whether the lock was released.
+const lockRetryInterval = 20 * time.Millisecond
+
+// Writes to the store take milliseconds. A lock held for longer than this was
+// left behind by a process that crashed.
+const staleLockAge = time.Minute
+
+// An exclusive lock on the files under `.git/tree`.
+//
+// The lock file holds the ID of the process that acquired it, so a lock left
+// behind by a crashed process can be detected and taken over.
+type Lock struct {
+	path string
+}
+
+// Acquire the lock guarding the store files of the repository whose git
+// directory is `gitPath`, waiting for another process to release it if needed.
+//
+// The lock is shared by all worktrees (see `LockPath()`), so it guards both the
+// shared files and the files of each worktree.
+func AcquireLock(gitPath string) (*Lock, error) {
+	path := LockPath(gitPath)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = file.WriteString(strconv.Itoa(os.Getpid()))
+			file.Close()
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return &Lock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if lockIsStale(path) {
+			removeStaleLock(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Could not acquire %s: another git-tree process is running. "+
+				"If it is not, remove the file", path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Release the lock.
+//
+// The lock is left alone if it is no longer held by this process, i.e. if it
+// was taken over as stale by another process.
+func (l *Lock) Release() error {
+	if strings.TrimSpace(utils.ReadFile(l.path)) != strconv.Itoa(os.Getpid()) {
+		return nil
+	}
+	return os.Remove(l.path)
+}
+
+// Remove the stale lock at `path`.
+//
+// Several waiting processes may find the same lock stale. Removing it directly
+// would let one of them remove the lock another one just acquired, so the lock
+// is first renamed to a name unique to this process, which only one of them
+// can do, and checked again once renamed.
+func removeStaleLock(path string) {
+	stalePath := fmt.Sprintf("%s.stale-%d", path, os.Getpid())
+	if err := os.Rename(path, stalePath); err != nil {
+		// Another process removed the lock first.
+		return
+	}
+	defer os.Remove(stalePath)
+
+	if !lockIsStale(stalePath) {
+		// A fresh lock replaced the stale one before it was renamed, so put it
+		// back unless yet another lock was acquired since.
+		os.Link(stalePath, path)
+	}
+}
+
+// Returns true if the process holding the lock at `path` no longer exists, or
+// if the lock is older than `staleLockAge`.
+func lockIsStale(path string) bool {
+	stat, err := os.Stat(path)
+	if err != nil {
+		// The lock was just released.
+		return false
+	}
+	if time.Since(stat.ModTime()) > staleLockAge {
+		return true
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(utils.ReadFile(path)))
+	if err != nil {
+		// The holder may not have written its ID yet.
+		return false
+	}
+	return !processExists(pid)
+}
+
+func processExists(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
+
+// Run `fn` while holding the lock guarding the store file `filename`.
+//
+// Store files live under `<git-dir>/tree` (see `GitTreeFilePath()`).
+func withLock(filename string, fn func() error) error {
+	gitPath := filepath.Dir(filepath.Dir(filename))
+	lock, err := AcquireLock(gitPath)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return fn()
+}
+
+// Overwrite `file` with `contents` while holding the lock.
+func WriteGitTreeFile(gitPath string, file GitTreeFile, contents string) error {
+	return writeLocked(GitTreeFilePath(gitPath, file), contents)
+}
+
+// Overwrite the store file `filename` with `contents` while holding the lock.
+func writeLocked(filename string, contents string) error {
+	return withLock(filename, func() error {
+		return utils.OverwriteFile(filename, contents)
+	})
+}
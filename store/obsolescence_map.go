@@ -2,7 +2,6 @@ package store
 
 import (
 	"errors"
-	"fmt"
 	"strings"
 
 	"github.com/acamadeo/git-tree/models"
@@ -22,54 +21,146 @@ var hookTypeStrings = map[models.HookType]string{
 	models.PostRewriteAmend:  "post-rewrite.amend",
 	models.PostRewriteRebase: "post-rewrite.rebase",
 	models.PostCommit:        "post-commit",
+	models.ReflogScan:        "reflog",
 }
 
-// Read obsolescence map file
-func ReadObsolescenceMap(repo *git.Repository, filepath string) *models.ObsolescenceMap {
-	contents := utils.ReadFile(filepath)
-	if contents == "" {
-		return &models.ObsolescenceMap{}
+// Returns the name of `actionType` as stored in the obsolescence map file.
+func ActionTypeName(actionType models.ActionType) string {
+	return actionTypeStrings[actionType]
+}
+
+// Returns the name of `hookType` as stored in the obsolescence map file.
+func HookTypeName(hookType models.HookType) string {
+	return hookTypeStrings[hookType]
+}
+
+// The obsolescence map file lists each action and its entries:
+//
+//	{
+//	  "version": 2,
+//	  "actions": [
+//	    {
+//	      "type": "amend",
+//	      "entries": [
+//	        {"commit": "<oid>", "obsoleter": "<oid>", "hook": "post-rewrite.amend"}
+//	      ]
+//	    }
+//	  ]
+//	}
+type obsolescenceMapDoc struct {
+	Version int                     `json:"version"`
+	Actions []obsolescenceActionDoc `json:"actions"`
+}
+
+type obsolescenceActionDoc struct {
+	Type    string                 `json:"type"`
+	Entries []obsolescenceEntryDoc `json:"entries"`
+}
+
+type obsolescenceEntryDoc struct {
+	Commit    string `json:"commit"`
+	Obsoleter string `json:"obsoleter"`
+	Hook      string `json:"hook"`
+}
+
+// Read obsolescence map file.
+//
+// Entries that refer to commits that no longer exist (e.g. removed by `git gc`
+// before they were kept by a hidden ref) have nil commits, and should be
+// skipped by callers. Returns a NewerFormatError if the file was written by a
+// newer version of git-tree.
+func ReadObsolescenceMap(repo *git.Repository, filepath string) (*models.ObsolescenceMap, error) {
+	doc, _, err := readObsolescenceMapDoc(filepath)
+	if err != nil {
+		return nil, err
 	}
+	return doc2ObsolescenceMap(repo, doc), nil
+}
+
+// Returns true if the obsolescence map file was in the line-based format.
+func readObsolescenceMapDoc(filepath string) (*obsolescenceMapDoc, bool, error) {
+	doc := &obsolescenceMapDoc{Version: FormatVersion, Actions: []obsolescenceActionDoc{}}
+	legacy, err := decodeFile(filepath, utils.ReadFile(filepath), doc, func(lines []string) {
+		*doc = legacyLines2ObsolescenceMapDoc(lines)
+	})
+	return doc, legacy, err
+}
+
+// In the line-based format, each action starts with a line `action <type>`,
+// followed by a line `<commit> <obsoleter> <hook>` for each entry.
+func legacyLines2ObsolescenceMapDoc(lines []string) obsolescenceMapDoc {
+	doc := obsolescenceMapDoc{Version: FormatVersion, Actions: []obsolescenceActionDoc{}}
+	for _, line := range lines {
+		lineParts := strings.Fields(line)
 
-	obsmap := models.ObsolescenceMap{}
-	for _, line := range strings.Split(contents, "\n") {
 		// Check for the start of a new action.
-		if strings.HasPrefix(line, "action") {
-			lineParts := strings.Fields(line)
-			obsmap.Actions = append(obsmap.Actions, models.ObsolescenceAction{
-				ActionType: actionTypeFromString(lineParts[1]),
+		if lineParts[0] == "action" {
+			doc.Actions = append(doc.Actions, obsolescenceActionDoc{
+				Type:    lineParts[1],
+				Entries: []obsolescenceEntryDoc{},
 			})
 			continue
 		}
 
 		// Line does not indicate the start of a new action. Append an entry to
 		// the latest action.
-		lastAction := obsmap.Actions[len(obsmap.Actions)-1]
-		lastAction.Entries = append(lastAction.Entries,
-			obsolescenceEntryFromLine(repo, line))
-		obsmap.Actions[len(obsmap.Actions)-1] = lastAction
+		lastAction := &doc.Actions[len(doc.Actions)-1]
+		lastAction.Entries = append(lastAction.Entries, obsolescenceEntryDoc{
+			Commit:    lineParts[0],
+			Obsoleter: lineParts[1],
+			Hook:      lineParts[2],
+		})
 	}
+	return doc
+}
 
+func doc2ObsolescenceMap(repo *git.Repository, doc *obsolescenceMapDoc) *models.ObsolescenceMap {
+	obsmap := models.ObsolescenceMap{}
+	for _, actionDoc := range doc.Actions {
+		action := models.ObsolescenceAction{ActionType: actionTypeFromString(actionDoc.Type)}
+		for _, entryDoc := range actionDoc.Entries {
+			action.Entries = append(action.Entries, models.ObsolescenceEntry{
+				Commit:    lookupCommitByHash(repo, entryDoc.Commit),
+				Obsoleter: lookupCommitByHash(repo, entryDoc.Obsoleter),
+				HookType:  hookTypeFromString(entryDoc.Hook),
+			})
+		}
+		obsmap.Actions = append(obsmap.Actions, action)
+	}
 	return &obsmap
 }
 
-func obsolescenceEntryFromLine(repo *git.Repository, line string) models.ObsolescenceEntry {
-	lineParts := strings.Fields(line)
-	oldHash := lineParts[0]
-	newHash := lineParts[1]
-	hookType := lineParts[2]
-
-	commitOid, _ := git.NewOid(oldHash)
-	commit, _ := repo.LookupCommit(commitOid)
-
-	obsoleterOid, _ := git.NewOid(newHash)
-	obsoleter, _ := repo.LookupCommit(obsoleterOid)
+func obsolescenceMap2Doc(obsmap *models.ObsolescenceMap) *obsolescenceMapDoc {
+	doc := &obsolescenceMapDoc{Version: FormatVersion, Actions: []obsolescenceActionDoc{}}
+	for _, action := range obsmap.Actions {
+		actionDoc := obsolescenceActionDoc{
+			Type:    actionTypeStrings[action.ActionType],
+			Entries: []obsolescenceEntryDoc{},
+		}
+		for _, entry := range action.Entries {
+			// Skip entries whose commits no longer exist.
+			if entry.Commit == nil || entry.Obsoleter == nil {
+				continue
+			}
+			actionDoc.Entries = append(actionDoc.Entries, obsolescenceEntryDoc{
+				Commit:    entry.Commit.Id().String(),
+				Obsoleter: entry.Obsoleter.Id().String(),
+				Hook:      hookTypeStrings[entry.HookType],
+			})
+		}
+		doc.Actions = append(doc.Actions, actionDoc)
+	}
+	return doc
+}
 
-	return models.ObsolescenceEntry{
-		Commit:    commit,
-		Obsoleter: obsoleter,
-		HookType:  hookTypeFromString(hookType),
+// Returns nil if the commit does not exist.
+func lookupCommitByHash(repo *git.Repository, hash string) *git.Commit {
+	oid, err := git.NewOid(hash)
+	if err != nil {
+		return nil
 	}
+	commit, _ := repo.LookupCommit(oid)
+	return commit
 }
 
 func actionTypeFromString(value string) models.ActionType {
@@ -91,61 +182,73 @@ func hookTypeFromString(value string) models.HookType {
 }
 
 // Write obsolescence map file
-func WriteObsolescenceMap(obsmap *models.ObsolescenceMap, filepath string) {
-	utils.OverwriteFile(filepath, obsolescenceMapString(obsmap))
+func WriteObsolescenceMap(obsmap *models.ObsolescenceMap, filepath string) error {
+	return writeLocked(filepath, encodeFile(obsolescenceMap2Doc(obsmap)))
 }
 
-func LastObsolescenceActionType(repo *git.Repository, filepath string) models.ActionType {
-	obsmap := ReadObsolescenceMap(repo, filepath)
-	return obsmap.Actions[len(obsmap.Actions)-1].ActionType
+func LastObsolescenceActionType(repo *git.Repository, filepath string) (models.ActionType, error) {
+	obsmap, err := ReadObsolescenceMap(repo, filepath)
+	if err != nil {
+		return 0, err
+	}
+	return obsmap.Actions[len(obsmap.Actions)-1].ActionType, nil
 }
 
-func SetLastObsolescenceActionType(repo *git.Repository, filepath string, actionType models.ActionType) {
-	obsmap := ReadObsolescenceMap(repo, filepath)
-	obsmap.Actions[len(obsmap.Actions)-1].ActionType = actionType
-	WriteObsolescenceMap(obsmap, filepath)
+// Change the type of the last ObsolescenceAction from `from` to `to`. Does
+// nothing if the last action is not of type `from`.
+func ReplaceLastObsolescenceActionType(repo *git.Repository, filepath string, from, to models.ActionType) error {
+	return UpdateObsolescenceMap(repo, filepath, func(obsmap *models.ObsolescenceMap) error {
+		if len(obsmap.Actions) < 1 || obsmap.Actions[len(obsmap.Actions)-1].ActionType != from {
+			return nil
+		}
+		obsmap.Actions[len(obsmap.Actions)-1].ActionType = to
+		return nil
+	})
 }
 
-func AppendObsolescenceAction(repo *git.Repository, filepath string, ActionType models.ActionType) {
-	obsmap := ReadObsolescenceMap(repo, filepath)
-
-	obsmap.Actions = append(obsmap.Actions, models.ObsolescenceAction{
-		ActionType: ActionType,
+func AppendObsolescenceAction(repo *git.Repository, filepath string, ActionType models.ActionType) error {
+	return UpdateObsolescenceMap(repo, filepath, func(obsmap *models.ObsolescenceMap) error {
+		obsmap.Actions = append(obsmap.Actions, models.ObsolescenceAction{
+			ActionType: ActionType,
+		})
+		return nil
 	})
-	WriteObsolescenceMap(obsmap, filepath)
 }
 
 // Append entries to obsolescence map file under the last ObsolescenceAction.
 func AppendEntriesToLastObsolescenceAction(repo *git.Repository, filepath string, entries ...models.ObsolescenceEntry) error {
-	obsmap := ReadObsolescenceMap(repo, filepath)
-	if len(obsmap.Actions) < 1 {
-		return errors.New("cannot append entry to obsolete map without actions")
-	}
-
-	lastAction := obsmap.Actions[len(obsmap.Actions)-1]
-	lastAction.Entries = append(lastAction.Entries, entries...)
-	obsmap.Actions[len(obsmap.Actions)-1] = lastAction
+	return UpdateObsolescenceMap(repo, filepath, func(obsmap *models.ObsolescenceMap) error {
+		if len(obsmap.Actions) < 1 {
+			return errors.New("cannot append entry to obsolete map without actions")
+		}
 
-	WriteObsolescenceMap(obsmap, filepath)
-	return nil
+		lastAction := obsmap.Actions[len(obsmap.Actions)-1]
+		lastAction.Entries = append(lastAction.Entries, entries...)
+		obsmap.Actions[len(obsmap.Actions)-1] = lastAction
+		return nil
+	})
 }
 
-func obsolescenceMapString(obsmap *models.ObsolescenceMap) string {
-	output := []string{}
-
-	for _, action := range obsmap.Actions {
-		actionHeader := fmt.Sprintf("action %s",
-			actionTypeStrings[action.ActionType])
-		output = append(output, actionHeader)
-
-		for _, entry := range action.Entries {
-			entryString := fmt.Sprintf("%s %s %s",
-				entry.Commit.Id().String(),
-				entry.Obsoleter.Id().String(),
-				hookTypeStrings[entry.HookType])
-			output = append(output, entryString)
+// Read, modify and write back the obsolescence map file while holding the
+// lock, so concurrent git-hooks don't overwrite each other's changes.
+//
+// Entries that refer to commits that no longer exist have nil commits, and are
+// dropped when the file is written. Also keeps the commits in the file
+// reachable through hidden refs (see `SyncObsolescenceRefs()`).
+func UpdateObsolescenceMap(repo *git.Repository, filepath string, modify func(*models.ObsolescenceMap) error) error {
+	return withLock(filepath, func() error {
+		doc, _, err := readObsolescenceMapDoc(filepath)
+		if err != nil {
+			return err
 		}
-	}
 
-	return strings.Join(output, "\n")
+		obsmap := doc2ObsolescenceMap(repo, doc)
+		if err := modify(obsmap); err != nil {
+			return err
+		}
+		if err := utils.OverwriteFile(filepath, encodeFile(obsolescenceMap2Doc(obsmap))); err != nil {
+			return err
+		}
+		return SyncObsolescenceRefs(repo, obsmap)
+	})
 }
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"github.com/acamadeo/git-tree/models"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// Obsolete commits are usually not reachable from any branch, so `git gc` may
+// delete them. Each commit in the obsolescence map is kept reachable through a
+// hidden ref under this prefix, named after the commit.
+const ObsolescenceRefPrefix = "refs/git-tree/obs/"
+
+const obsolescenceRefMessage = "[git-tree] keep obsolete commit"
+
+// Returns the name of the hidden ref that keeps commit `oid` reachable.
+func ObsolescenceRefName(oid *git.Oid) string {
+	return ObsolescenceRefPrefix + oid.String()
+}
+
+// Create a hidden ref for every commit in `obsmap`, and delete the hidden refs
+// of commits no longer in it.
+func SyncObsolescenceRefs(repo *git.Repository, obsmap *models.ObsolescenceMap) error {
+	wanted := map[string]*git.Oid{}
+	for _, action := range obsmap.Actions {
+		for _, entry := range action.Entries {
+			for _, commit := range []*git.Commit{entry.Commit, entry.Obsoleter} {
+				if commit != nil {
+					wanted[ObsolescenceRefName(commit.Id())] = commit.Id()
+				}
+			}
+		}
+	}
+
+	existing, err := obsolescenceRefNames(repo)
+	if err != nil {
+		return err
+	}
+
+	for name, oid := range wanted {
+		if existing[name] {
+			continue
+		}
+		ref, err := repo.References.Create(name, oid, true, obsolescenceRefMessage)
+		if err != nil {
+			return err
+		}
+		ref.Free()
+	}
+
+	for name := range existing {
+		if _, ok := wanted[name]; ok {
+			continue
+		}
+		if err := deleteReference(repo, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete all the hidden refs that keep obsolete commits reachable.
+func DeleteObsolescenceRefs(repo *git.Repository) error {
+	existing, err := obsolescenceRefNames(repo)
+	if err != nil {
+		return err
+	}
+
+	for name := range existing {
+		if err := deleteReference(repo, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func obsolescenceRefNames(repo *git.Repository) (map[string]bool, error) {
+	iterator, err := repo.NewReferenceIteratorGlob(ObsolescenceRefPrefix + "*")
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Free()
+
+	names := map[string]bool{}
+	nameIterator := iterator.Names()
+	for {
+		name, err := nameIterator.Next()
+		if git.IsErrorCode(err, git.ErrorCodeIterOver) {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names[name] = true
+	}
+}
+
+func deleteReference(repo *git.Repository, name string) error {
+	ref, err := repo.References.Lookup(name)
+	if err != nil {
+		return err
+	}
+	defer ref.Free()
+	return ref.Delete()
+}
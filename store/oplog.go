@@ -0,0 +1,166 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/acamadeo/git-tree/models"
+	"github.com/acamadeo/git-tree/utils"
+	git "github.com/libgit2/git2go/v34"
+)
+
+// The git-tree files captured in each snapshot of the operation log.
+var OplogSnapshotFiles = []GitTreeFile{BranchMap, ObsoleteMap}
+
+// Each entry of the operation log is stored in its own numbered directory:
+//
+//	oplog/
+//	  position        <- number of entries that are currently applied
+//	  pending/        <- entry of an operation interrupted by a merge conflict
+//	  1/
+//	    operation     <- command line of the operation
+//	    before/refs   <- HEAD and branch targets before the operation
+//	    before/<file> <- copy of each snapshot file before the operation
+//	    after/...     <- same as before/, after the operation
+
+// Directory of entry `index` (starting at 1) of the operation log.
+func OplogEntryPath(gitPath string, index int) string {
+	return filepath.Join(OplogPath(gitPath), strconv.Itoa(index))
+}
+
+func OplogPendingPath(gitPath string) string {
+	return filepath.Join(OplogPath(gitPath), "pending")
+}
+
+func oplogPositionPath(gitPath string) string {
+	return filepath.Join(OplogPath(gitPath), "position")
+}
+
+// Returns the number of entries in the operation log.
+func OplogLength(gitPath string) int {
+	length := 0
+	for utils.DirExists(OplogEntryPath(gitPath, length+1)) {
+		length++
+	}
+	return length
+}
+
+// Returns the number of entries of the operation log that are currently
+// applied. Entries past the position have been undone and can be redone.
+func ReadOplogPosition(gitPath string) int {
+	contents := utils.ReadFile(oplogPositionPath(gitPath))
+	if contents == "" {
+		return OplogLength(gitPath)
+	}
+	position, _ := strconv.Atoi(contents)
+	return position
+}
+
+func WriteOplogPosition(gitPath string, position int) error {
+	return utils.OverwriteFile(oplogPositionPath(gitPath), strconv.Itoa(position))
+}
+
+// Read an operation log entry stored in directory `dir`.
+//
+// Only the "before" snapshot is read if the entry is still pending.
+func ReadOplogEntry(dir string) *models.OplogEntry {
+	return &models.OplogEntry{
+		Operation: utils.ReadFile(filepath.Join(dir, "operation")),
+		Before:    readSnapshot(filepath.Join(dir, "before")),
+		After:     readSnapshot(filepath.Join(dir, "after")),
+	}
+}
+
+// Write an operation log entry to directory `dir`, replacing any existing
+// entry.
+func WriteOplogEntry(entry *models.OplogEntry, dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := utils.OverwriteFile(filepath.Join(dir, "operation"), entry.Operation); err != nil {
+		return err
+	}
+	if err := writeSnapshot(entry.Before, filepath.Join(dir, "before")); err != nil {
+		return err
+	}
+	if entry.After.Branches != nil {
+		return writeSnapshot(entry.After, filepath.Join(dir, "after"))
+	}
+	return nil
+}
+
+// The refs file of a snapshot holds HEAD and the target of each branch:
+//
+//	{
+//	  "version": 2,
+//	  "head": "<branch-or-commit>",
+//	  "branches": {"<name>": "<oid>", ...}
+//	}
+type snapshotRefsDoc struct {
+	Version  int               `json:"version"`
+	Head     string            `json:"head"`
+	Branches map[string]string `json:"branches"`
+}
+
+func readSnapshot(dir string) models.RepoSnapshot {
+	snapshot := models.RepoSnapshot{
+		Branches: map[string]git.Oid{},
+		Files:    map[string]string{},
+	}
+
+	refsPath := filepath.Join(dir, "refs")
+	doc := &snapshotRefsDoc{Branches: map[string]string{}}
+	decodeFile(refsPath, utils.ReadFile(refsPath), doc, func(lines []string) {
+		// In the line-based format, HEAD is listed and then each branch:
+		//
+		//	head <branch-or-commit>
+		//	branch <name> <oid>
+		for _, line := range lines {
+			lineParts := strings.Fields(line)
+			switch lineParts[0] {
+			case "head":
+				doc.Head = lineParts[1]
+			case "branch":
+				doc.Branches[lineParts[1]] = lineParts[2]
+			}
+		}
+	})
+
+	snapshot.Head = doc.Head
+	for name, hash := range doc.Branches {
+		oid, _ := git.NewOid(hash)
+		snapshot.Branches[name] = *oid
+	}
+
+	for _, file := range OplogSnapshotFiles {
+		name := gitTreeFileNames[file]
+		path := filepath.Join(dir, name)
+		if utils.FileExists(path) {
+			snapshot.Files[name] = utils.ReadFile(path)
+		}
+	}
+	return snapshot
+}
+
+func writeSnapshot(snapshot models.RepoSnapshot, dir string) error {
+	doc := &snapshotRefsDoc{
+		Version:  FormatVersion,
+		Head:     snapshot.Head,
+		Branches: map[string]string{},
+	}
+	for name, oid := range snapshot.Branches {
+		doc.Branches[name] = oid.String()
+	}
+	if err := utils.OverwriteFile(filepath.Join(dir, "refs"), encodeFile(doc)); err != nil {
+		return err
+	}
+
+	for name, contents := range snapshot.Files {
+		if err := utils.OverwriteFile(filepath.Join(dir, name), contents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
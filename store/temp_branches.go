@@ -1,9 +1,6 @@
 package store
 
 import (
-	"bufio"
-	"fmt"
-	"os"
 	"sort"
 	"strings"
 
@@ -13,39 +10,57 @@ import (
 	git "github.com/libgit2/git2go/v34"
 )
 
+// The temporary branches file lists each temporary branch and the branch it
+// replaced:
+//
+//	{
+//	  "version": 2,
+//	  "branches": [
+//	    {"temp": "rebase-treecko", "original": "treecko"}
+//	  ]
+//	}
+type tempBranchesDoc struct {
+	Version  int             `json:"version"`
+	Branches []tempBranchDoc `json:"branches"`
+}
+
+type tempBranchDoc struct {
+	Temp     string `json:"temp"`
+	Original string `json:"original"`
+}
+
 // Read temporary branches file.
 //
 // It is expected that the file exists.
 func ReadTemporaryBranches(repo *git.Repository, filepath string) models.TempBranchMap {
-	readFile, _ := os.Open(filepath)
-	defer readFile.Close()
+	doc, _, _ := readTempBranchesDoc(filepath)
 
-	fileScanner := bufio.NewScanner(readFile)
-	fileScanner.Split(bufio.ScanLines)
+	output := models.TempBranchMap{}
+	for _, entry := range doc.Branches {
+		tempBranch, _ := repo.LookupBranch(entry.Temp, git.BranchLocal)
+		origBranch, _ := repo.LookupBranch(entry.Original, git.BranchLocal)
 
-	lines := []string{}
-	for fileScanner.Scan() {
-		lines = append(lines, fileScanner.Text())
+		output[tempBranch] = origBranch
 	}
-
-	return string2TempBranchMap(repo, lines)
+	return output
 }
 
 // Write temporary branches file.
-func WriteTemporaryBranches(tempMap models.TempBranchMap, filepath string) {
-	utils.OverwriteFile(filepath, tempBranchMap2String(tempMap))
+func WriteTemporaryBranches(tempMap models.TempBranchMap, filepath string) error {
+	return writeLocked(filepath, encodeFile(tempBranchMap2Doc(tempMap)))
 }
 
-func string2TempBranchMap(repo *git.Repository, input []string) models.TempBranchMap {
-	output := models.TempBranchMap{}
-	for _, line := range input {
-		parts := strings.Fields(line)
-		tempBranch, _ := repo.LookupBranch(parts[0], git.BranchLocal)
-		origBranch, _ := repo.LookupBranch(parts[1], git.BranchLocal)
-
-		output[tempBranch] = origBranch
-	}
-	return output
+// Returns true if the temporary branches file was in the line-based format.
+func readTempBranchesDoc(filepath string) (*tempBranchesDoc, bool, error) {
+	doc := &tempBranchesDoc{Version: FormatVersion, Branches: []tempBranchDoc{}}
+	legacy, err := decodeFile(filepath, utils.ReadFile(filepath), doc, func(lines []string) {
+		// In the line-based format, each line is `<temp-branch> <original-branch>`.
+		for _, line := range lines {
+			parts := strings.Fields(line)
+			doc.Branches = append(doc.Branches, tempBranchDoc{Temp: parts[0], Original: parts[1]})
+		}
+	})
+	return doc, legacy, err
 }
 
 func sortedTempBranches(tempMap models.TempBranchMap) []*git.Branch {
@@ -60,16 +75,13 @@ func sortedTempBranches(tempMap models.TempBranchMap) []*git.Branch {
 }
 
 // Temporary branches are listed alphabetically for consistency.
-func tempBranchMap2String(tempMap models.TempBranchMap) string {
-	sortedTempBranches := sortedTempBranches(tempMap)
-
-	output := []string{}
-	for _, tempBranch := range sortedTempBranches {
-		origBranch := tempMap[tempBranch]
-
-		tempName := gitutil.BranchName(tempBranch)
-		origName := gitutil.BranchName(origBranch)
-		output = append(output, fmt.Sprintf("%s %s", tempName, origName))
+func tempBranchMap2Doc(tempMap models.TempBranchMap) *tempBranchesDoc {
+	doc := &tempBranchesDoc{Version: FormatVersion, Branches: []tempBranchDoc{}}
+	for _, tempBranch := range sortedTempBranches(tempMap) {
+		doc.Branches = append(doc.Branches, tempBranchDoc{
+			Temp:     gitutil.BranchName(tempBranch),
+			Original: gitutil.BranchName(tempMap[tempBranch]),
+		})
 	}
-	return strings.Join(output, "\n")
+	return doc
 }
@@ -2,6 +2,7 @@ package testutil
 
 import (
 	"os"
+	"path/filepath"
 	"time"
 
 	gitutil "github.com/acamadeo/git-tree/git"
@@ -43,6 +44,24 @@ func (t *TestRepository) CreateBranch(name string) *git.Branch {
 	return branch
 }
 
+// Add a linked worktree (like `git worktree add`) with branch `name` checked
+// out, and return it as a test repository.
+//
+// git2go does not expose worktrees, so the files that link the worktree to the
+// repository are written directly.
+func (t *TestRepository) AddWorktree(name string) TestRepository {
+	worktreeDir, _ := os.MkdirTemp("", "test-git-worktree")
+	gitDir := filepath.Join(t.Repo.Path(), "worktrees", name)
+	utils.OverwriteFile(filepath.Join(gitDir, "HEAD"), "ref: refs/heads/"+name)
+	utils.OverwriteFile(filepath.Join(gitDir, "commondir"), "../..")
+	utils.OverwriteFile(filepath.Join(gitDir, "gitdir"), filepath.Join(worktreeDir, ".git"))
+	utils.OverwriteFile(filepath.Join(worktreeDir, ".git"), "gitdir: "+gitDir)
+
+	repo, _ := git.OpenRepository(worktreeDir)
+	repo.CheckoutHead(&git.CheckoutOptions{Strategy: git.CheckoutForce})
+	return TestRepository{Repo: repo}
+}
+
 // Move HEAD to the specified branch. This assumes the specified branch exists.
 func (t *TestRepository) SwitchBranch(name string) {
 	gitutil.CheckoutBranchByName(t.Repo, name)
@@ -153,3 +172,19 @@ func (t *TestRepository) BranchWithCommit(name string) {
 	t.SwitchBranch(name)
 	t.WriteAndCommitFile(name, name, name)
 }
+
+// Merge branch `name` into HEAD, committing the merge with message `message`.
+//
+// It is assumed that the merge does not conflict.
+func (t *TestRepository) MergeBranch(name string, message string) {
+	headRef, _ := t.Repo.Head()
+	headCommit, _ := t.Repo.LookupCommit(headRef.Target())
+	branchCommit, _ := t.Repo.LookupCommit(t.LookupBranch(name).Target())
+
+	index, _ := t.Repo.MergeCommits(headCommit, branchCommit, nil)
+	treeOid, _ := index.WriteTreeTo(t.Repo)
+	tree, _ := t.Repo.LookupTree(treeOid)
+
+	t.Repo.CreateCommit("HEAD", signature, signature, message, tree, headCommit, branchCommit)
+	t.Repo.CheckoutHead(&git.CheckoutOptions{Strategy: git.CheckoutForce})
+}
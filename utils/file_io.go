@@ -9,19 +9,20 @@ import (
 
 // Replaces the contents of file `filename` with `contents`.
 //
-// Creates file `filename` if it did not exist before.
-func OverwriteFile(filename string, contents string) {
+// Creates file `filename` if it did not exist before. The contents are written
+// to a temporary file which then replaces `filename`, so readers never see a
+// partially written file.
+func OverwriteFile(filename string, contents string) error {
 	// Make the file's parent directory (no-op if directory already exists).
 	dir := filepath.Dir(filename)
-	os.MkdirAll(dir, os.ModePerm)
-	mode := fs.FileMode(0664)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
 
-	// Delete the file if it already exists.
-	existingFile, err := os.Open(filename)
-	if err == nil {
-		stat, _ := existingFile.Stat()
+	// Keep the mode of the existing file, if any.
+	mode := fs.FileMode(0664)
+	if stat, err := os.Stat(filename); err == nil {
 		mode = stat.Mode()
-		os.Remove(filename)
 	}
 
 	// Pad the contents with a final newline.
@@ -29,10 +30,28 @@ func OverwriteFile(filename string, contents string) {
 		contents = contents + "\n"
 	}
 
-	newFile, _ := os.Create(filename)
-	newFile.WriteString(contents)
-	newFile.Chmod(mode)
-	newFile.Close()
+	tempFile, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.WriteString(contents); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Chmod(mode); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), filename)
 }
 
 // Return contents of a file as a string.
@@ -44,15 +63,15 @@ func ReadFile(filename string) string {
 }
 
 // Add `contents` and a newline to the start of the file.
-func PrependToFile(filename string, contents string) {
+func PrependToFile(filename string, contents string) error {
 	existingContents := ReadFile(filename)
-	OverwriteFile(filename, contents+"\n"+existingContents)
+	return OverwriteFile(filename, contents+"\n"+existingContents)
 }
 
 // Add a newline and append `contents` to the file.
-func AppendToFile(filename string, contents string) {
+func AppendToFile(filename string, contents string) error {
 	existingContents := ReadFile(filename)
-	OverwriteFile(filename, existingContents+"\n"+contents)
+	return OverwriteFile(filename, existingContents+"\n"+contents)
 }
 
 // Returns true if the file exists.
@@ -84,7 +103,7 @@ func FileContainsLine(filename string, line string) bool {
 }
 
 // Removes any lines matching `line` in the file.
-func DeleteLineInFile(filename string, line string) {
+func DeleteLineInFile(filename string, line string) error {
 	lines := []string{}
 
 	contents := ReadFile(filename)
@@ -95,5 +114,5 @@ func DeleteLineInFile(filename string, line string) {
 		lines = append(lines, l)
 	}
 
-	OverwriteFile(filename, strings.Join(lines, "\n"))
+	return OverwriteFile(filename, strings.Join(lines, "\n"))
 }